@@ -111,3 +111,25 @@ func TestMongoConnection(mongoURI, dbName string) error {
 
 	return nil
 }
+
+// SupportsTransactions reports whether the deployment behind db is a replica set or a sharded
+// cluster. Standalone servers support neither multi-document transactions nor change streams.
+func SupportsTransactions(db *mongo.Database) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var result bson.M
+	err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&result)
+	if err != nil {
+		// Servers older than 4.4.2 only know the legacy command
+		if err := db.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result); err != nil {
+			return false, err
+		}
+	}
+
+	if _, ok := result["setName"]; ok {
+		return true, nil
+	}
+	msg, _ := result["msg"].(string)
+	return msg == "isdbgrid", nil
+}
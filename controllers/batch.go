@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxBatchRequests caps the number of sub-requests in a single batch
+const maxBatchRequests = 20
+
+// batchReferencePattern matches {{ref.path.to.value}} placeholders
+var batchReferencePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)*)\s*\}\}`)
+
+// batchRequestIDPattern restricts sub-request ids to names usable in placeholders
+var batchRequestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// batchForwardedHeaders are copied from the batch request to every sub-request
+var batchForwardedHeaders = []string{"X-API-Key", "Authorization", "Accept-Language", "User-Agent"}
+
+type BatchController struct {
+	engine http.Handler
+}
+
+// NewBatchController creates a controller that dispatches sub-requests through the given engine,
+// so they pass through the same middleware and handlers as regular requests
+func NewBatchController(engine http.Handler) *BatchController {
+	return &BatchController{engine: engine}
+}
+
+// batchDatabaseKey is the request context key of the user database shared by a batch
+type batchDatabaseKey struct{}
+
+// batchDatabase is the user database a batch opens once for all of its sub-requests
+type batchDatabase struct {
+	userID primitive.ObjectID
+	db     *mongo.Database
+}
+
+// Helper function to reuse the database opened by a batch. Sub-requests may authenticate with
+// another API key, so the database is only shared with the same user.
+func sharedUserDatabase(c *gin.Context, user models.User) (*mongo.Database, bool) {
+	shared, ok := c.Request.Context().Value(batchDatabaseKey{}).(*batchDatabase)
+	if !ok || shared.userID != user.ID {
+		return nil, false
+	}
+	return shared.db, true
+}
+
+// Helper function to check that a sub-request path points to an endpoint a batch can run.
+// Streaming endpoints keep the response open and exports flush it in chunks, both need a
+// connection the batch can't provide.
+func batchPathError(rawPath string) error {
+	parsed, err := url.Parse(rawPath)
+	if err != nil || !strings.HasPrefix(rawPath, "/api/") {
+		return errors.New("Path must point to a dynamic API endpoint under /api/")
+	}
+	endpoint := path.Clean(parsed.Path)
+	if endpoint == "/api/batch" {
+		return errors.New("Path must point to a dynamic API endpoint under /api/")
+	}
+
+	segments := strings.Split(strings.TrimPrefix(endpoint, "/api/"), "/")
+	if endpoint == "/api/ws" || (len(segments) == 2 && segments[1] == "stream") {
+		return errors.New("Streaming endpoints cannot be called in a batch")
+	}
+	if len(segments) == 2 && segments[1] == "export" {
+		return errors.New("Exports cannot be called in a batch")
+	}
+	return nil
+}
+
+// batchResponseWriter captures the response of a sub-request
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchResponseWriter() *batchResponseWriter {
+	return &batchResponseWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Helper function to look up a value inside a decoded JSON response
+func lookupBatchValue(value interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Helper function to resolve placeholders in a string. A string that is a single placeholder
+// is replaced by the referenced value itself, so numbers and objects keep their type.
+func resolveBatchString(value string, responses map[string]interface{}, escape bool) (interface{}, error) {
+	matches := batchReferencePattern.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+
+	resolve := func(match []int) (interface{}, error) {
+		ref := value[match[2]:match[3]]
+		body, ok := responses[ref]
+		if !ok {
+			return nil, errors.New("Referenced request is unknown or failed: " + ref)
+		}
+
+		var path []string
+		if match[4] != match[5] {
+			path = strings.Split(strings.TrimPrefix(value[match[4]:match[5]], "."), ".")
+		}
+		resolved, ok := lookupBatchValue(body, path)
+		if !ok {
+			return nil, errors.New("Unresolved batch reference: " + value[match[0]:match[1]])
+		}
+		return resolved, nil
+	}
+
+	if !escape && len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(value) {
+		return resolve(matches[0])
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range matches {
+		resolved, err := resolve(match)
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprint(resolved)
+		if escape {
+			text = url.PathEscape(text)
+		}
+		result.WriteString(value[last:match[0]])
+		result.WriteString(text)
+		last = match[1]
+	}
+	result.WriteString(value[last:])
+
+	return result.String(), nil
+}
+
+// Helper function to resolve placeholders anywhere inside a request body
+func resolveBatchBody(value interface{}, responses map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return resolveBatchString(v, responses, false)
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolvedItem, err := resolveBatchBody(item, responses)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = resolvedItem
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolvedItem, err := resolveBatchBody(item, responses)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = resolvedItem
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
+
+// @Summary Run batch request
+// @Description Run up to 20 dynamic API calls in one round trip. Sub-requests run in order through the regular middleware and count against the API quota individually, as does the batch call itself. They may reference earlier responses with {{id.field}} placeholders.
+// @Tags dynamic-api
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.BatchRequest true "Sub-requests"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Router /api/batch [post]
+func (bc *BatchController) RunBatch(c *gin.Context) {
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Requests) > maxBatchRequests {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A batch can contain at most %d requests", maxBatchRequests)})
+		return
+	}
+
+	// Validate all sub-requests up front so a malformed batch does not run partially
+	names := make(map[string]bool)
+	for i, subRequest := range req.Requests {
+		method := strings.ToUpper(subRequest.Method)
+		if method != http.MethodGet && method != http.MethodPost && method != http.MethodPut && method != http.MethodDelete {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Method must be one of: GET, POST, PUT, DELETE", "request": i})
+			return
+		}
+		if err := batchPathError(subRequest.Path); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "request": i})
+			return
+		}
+		if subRequest.ID != "" {
+			if _, err := strconv.Atoi(subRequest.ID); err == nil || !batchRequestIDPattern.MatchString(subRequest.ID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Request id must be a non-numeric name of letters, digits, '-' or '_'", "request": i})
+				return
+			}
+			if names[subRequest.ID] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate request id: " + subRequest.ID, "request": i})
+				return
+			}
+			names[subRequest.ID] = true
+		}
+	}
+
+	// Open the user's database once instead of once per sub-request
+	if apiUser, exists := c.Get("api_user"); exists {
+		user := apiUser.(models.User)
+		if user.MongoDBURI != "" && user.DatabaseName != "" {
+			if db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName); err == nil {
+				defer db.Client().Disconnect(context.TODO())
+				ctx := context.WithValue(c.Request.Context(), batchDatabaseKey{}, &batchDatabase{userID: user.ID, db: db})
+				c.Request = c.Request.WithContext(ctx)
+			}
+		}
+	}
+
+	// Decoded response bodies by index and by id, used to resolve references
+	responses := make(map[string]interface{})
+	results := make([]models.BatchSubResponse, 0, len(req.Requests))
+
+	for i, subRequest := range req.Requests {
+		result := models.BatchSubResponse{ID: subRequest.ID}
+
+		status, body, err := bc.dispatch(c, subRequest, responses)
+		if err != nil {
+			// The request depends on a response that is missing or failed
+			result.Status = http.StatusFailedDependency
+			result.Body = gin.H{"error": err.Error()}
+		} else {
+			result.Status = status
+			result.Body = body
+			if status < http.StatusBadRequest {
+				responses[strconv.Itoa(i)] = body
+				if subRequest.ID != "" {
+					responses[subRequest.ID] = body
+				}
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"responses": results})
+}
+
+// Helper function to run one sub-request through the engine and decode its response
+func (bc *BatchController) dispatch(c *gin.Context, subRequest models.BatchSubRequest, responses map[string]interface{}) (int, interface{}, error) {
+	path, err := resolveBatchString(subRequest.Path, responses, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	// References can complete a path to an endpoint that was refused up front
+	if err := batchPathError(path.(string)); err != nil {
+		return http.StatusBadRequest, gin.H{"error": err.Error()}, nil
+	}
+
+	var bodyReader io.Reader = http.NoBody
+	if subRequest.Body != nil {
+		body, err := resolveBatchBody(subRequest.Body, responses)
+		if err != nil {
+			return 0, nil, err
+		}
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		bodyReader = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(c.Request.Context(), strings.ToUpper(subRequest.Method), path.(string), bodyReader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, header := range batchForwardedHeaders {
+		if value := c.GetHeader(header); value != "" {
+			request.Header.Set(header, value)
+		}
+	}
+	for header, value := range subRequest.Headers {
+		request.Header.Set(header, value)
+	}
+	if subRequest.Body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.RemoteAddr = c.Request.RemoteAddr
+
+	writer := newBatchResponseWriter()
+	bc.engine.ServeHTTP(writer, request)
+
+	if writer.body.Len() == 0 {
+		return writer.status, nil, nil
+	}
+
+	// JSON responses are embedded as-is, anything else is returned as text
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(writer.body.Bytes()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return writer.status, writer.body.String(), nil
+	}
+	return writer.status, decoded, nil
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestBatchPathErrorRefusesNonDynamicEndpoints(t *testing.T) {
+	allowed := []string{"/api/posts", "/api/posts/64b000000000000000000000?populate=author", "/api/transactions"}
+	for _, path := range allowed {
+		if err := batchPathError(path); err != nil {
+			t.Errorf("%s was refused: %v", path, err)
+		}
+	}
+
+	refused := []string{"/auth/login", "/api/batch", "/api/batch/", "/api/./batch", "/api/posts/../batch?x=1", "api/posts", "/api/posts/export?format=csv", "/api/posts//export/", "/api/posts/stream", "/api/posts/./stream?near=1,2", "/api/ws", "/api/ws?api_key=x"}
+	for _, path := range refused {
+		if batchPathError(path) == nil {
+			t.Errorf("%s was accepted", path)
+		}
+	}
+}
+
+func TestResolveBatchStringKeepsTypesAndEscapesPaths(t *testing.T) {
+	responses := map[string]interface{}{
+		"order": map[string]interface{}{"id": "a/b", "items": []interface{}{map[string]interface{}{"qty": 3}}},
+	}
+
+	value, err := resolveBatchString("{{order.items.0.qty}}", responses, false)
+	if err != nil || !reflect.DeepEqual(value, 3) {
+		t.Errorf("single placeholder resolved to %v, %v, want 3", value, err)
+	}
+	value, err = resolveBatchString("/api/orders/{{order.id}}", responses, true)
+	if err != nil || value != "/api/orders/a%2Fb" {
+		t.Errorf("path placeholder resolved to %v, %v", value, err)
+	}
+	if _, err := resolveBatchString("{{missing.id}}", responses, false); err == nil {
+		t.Error("unknown reference was resolved")
+	}
+}
+
+func TestSharedUserDatabaseOnlyServesTheBatchUser(t *testing.T) {
+	userID := primitive.NewObjectID()
+	db := &mongo.Database{}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/posts", nil)
+	if _, ok := sharedUserDatabase(c, models.User{ID: userID}); ok {
+		t.Fatal("database shared outside a batch")
+	}
+
+	ctx := context.WithValue(c.Request.Context(), batchDatabaseKey{}, &batchDatabase{userID: userID, db: db})
+	c.Request = c.Request.WithContext(ctx)
+	if shared, ok := sharedUserDatabase(c, models.User{ID: userID}); !ok || shared != db {
+		t.Error("batch user did not get the shared database")
+	}
+	if _, ok := sharedUserDatabase(c, models.User{ID: primitive.NewObjectID()}); ok {
+		t.Error("database shared with another API key")
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/M-awais-rasool/SchemaCraft-go/config"
 	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -25,6 +26,17 @@ func NewDynamicAPIController() *DynamicAPIController {
 
 // Helper function to get user's database
 func (dc *DynamicAPIController) getUserDatabase(c *gin.Context) (*mongo.Database, error) {
+	if apiUser, exists := c.Get("api_user"); exists {
+		if db, ok := sharedUserDatabase(c, apiUser.(models.User)); ok {
+			return db, nil
+		}
+	}
+
+	return dc.connectUserDatabase(c)
+}
+
+// Helper function to open a new connection to user's database, the caller has to disconnect it
+func (dc *DynamicAPIController) connectUserDatabase(c *gin.Context) (*mongo.Database, error) {
 	apiUser, exists := c.Get("api_user")
 	if !exists {
 		return nil, errors.New("user not found in context")
@@ -46,10 +58,196 @@ func (dc *DynamicAPIController) getSchemaByCollection(userID primitive.ObjectID,
 	return &schema, err
 }
 
+// Helper function to validate a single field value and convert it to the form stored in MongoDB
+func (dc *DynamicAPIController) prepareFieldValue(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, field models.SchemaField, value interface{}) (interface{}, int, error) {
+	switch field.Type {
+	case "relation":
+		if field.Target == "" {
+			return value, http.StatusOK, nil
+		}
+
+		// Convert value to ObjectID for validation
+		var relationID primitive.ObjectID
+		switch v := value.(type) {
+		case string:
+			var err error
+			relationID, err = primitive.ObjectIDFromHex(v)
+			if err != nil {
+				return nil, http.StatusBadRequest, errors.New("Invalid relation ID for field: " + field.Name)
+			}
+		case primitive.ObjectID:
+			relationID = v
+		default:
+			return nil, http.StatusBadRequest, errors.New("Relation field must be a valid ObjectID: " + field.Name)
+		}
+
+		// Verify the referenced document exists
+		var count int64
+
+		// Check if the target collection is an authentication schema
+		var targetSchema models.Schema
+		targetSchemaFilter := bson.M{"user_id": userID, "collection_name": field.Target, "is_active": true}
+		err := config.DB.Collection("schemas").FindOne(context.TODO(), targetSchemaFilter).Decode(&targetSchema)
+
+		if err == nil && targetSchema.AuthConfig != nil && targetSchema.AuthConfig.Enabled {
+			// This is an authentication collection, check in the user collection
+			userCollection := targetSchema.AuthConfig.UserCollection
+			if userCollection == "" {
+				userCollection = field.Target + "_users"
+			}
+			count, err = db.Collection(userCollection).CountDocuments(ctx, bson.M{"_id": relationID})
+		} else {
+			// Regular data collection, check with user_id filter
+			count, err = db.Collection(field.Target).CountDocuments(ctx, bson.M{"_id": relationID, "user_id": userID})
+		}
+
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.New("Failed to validate relation for field: " + field.Name)
+		}
+		if count == 0 {
+			return nil, http.StatusBadRequest, errors.New("Referenced document not found for field: " + field.Name)
+		}
+
+		return relationID, http.StatusOK, nil
+	case "file":
+		return nil, http.StatusBadRequest, errors.New("File field " + field.Name + " must be uploaded through the /files/" + field.Name + " endpoint")
+	case "geopoint":
+		point, err := utils.ParseGeoPoint(value)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid location for field " + field.Name + ": " + err.Error())
+		}
+		return point, http.StatusOK, nil
+	}
+
+	return value, http.StatusOK, nil
+}
+
+// Helper function to validate request data against the schema.
+// For updates only the supplied fields are returned and required/default rules are skipped.
+// Localized fields are returned per locale ("field.locale") on updates so other translations are kept.
+func (dc *DynamicAPIController) buildDocumentData(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, requestData map[string]interface{}, isUpdate bool) (map[string]interface{}, int, error) {
+	docData := make(map[string]interface{})
+	for _, field := range schema.Fields {
+		if value, ok := requestData[field.Name]; ok {
+			if field.Localized {
+				translations, err := dc.prepareLocalizedValue(schema, field, value, isUpdate)
+				if err != nil {
+					return nil, http.StatusBadRequest, err
+				}
+				if isUpdate {
+					for locale, translation := range translations {
+						docData[field.Name+"."+locale] = translation
+					}
+				} else {
+					docData[field.Name] = translations
+				}
+				continue
+			}
+
+			prepared, status, err := dc.prepareFieldValue(ctx, db, userID, field, value)
+			if err != nil {
+				return nil, status, err
+			}
+			docData[field.Name] = prepared
+		} else if isUpdate {
+			continue
+		} else if field.Required {
+			return nil, http.StatusBadRequest, errors.New("Required field missing: " + field.Name)
+		} else if field.Default != nil {
+			docData[field.Name] = field.Default
+		}
+
+		// The expiry field has to be stored as a BSON date for the TTL index to remove the document
+		if schema.Expiry != nil && schema.Expiry.Field == field.Name {
+			if value, ok := docData[field.Name]; ok && value != nil {
+				expiresAt, err := utils.ParseDate(value)
+				if err != nil {
+					return nil, http.StatusBadRequest, errors.New("Invalid expiry date for field " + field.Name + ": " + err.Error())
+				}
+				docData[field.Name] = expiresAt
+			}
+		}
+	}
+
+	return docData, http.StatusOK, nil
+}
+
+// Helper function to validate the translations sent for a localized field
+func (dc *DynamicAPIController) prepareLocalizedValue(schema *models.Schema, field models.SchemaField, value interface{}, isUpdate bool) (map[string]interface{}, error) {
+	if schema.Localization == nil {
+		return nil, errors.New("Localization is not configured for field: " + field.Name)
+	}
+
+	translations, err := normalizeLocalizedValue(value, schema.Localization)
+	if err != nil {
+		return nil, errors.New("Invalid value for localized field " + field.Name + ": " + err.Error())
+	}
+
+	defaultLocale := schema.Localization.DefaultLocale
+	defaultValue, hasDefault := translations[defaultLocale]
+	if !isUpdate && !hasDefault {
+		return nil, errors.New("Localized field " + field.Name + " requires a value for the default locale: " + defaultLocale)
+	}
+	if hasDefault && defaultValue == "" {
+		return nil, errors.New("Localized field " + field.Name + " cannot have an empty value for the default locale: " + defaultLocale)
+	}
+
+	return translations, nil
+}
+
+// Helper function to get the locales requested through ?locale= or Accept-Language.
+// Nil means every translation is returned.
+func (dc *DynamicAPIController) requestedLocales(c *gin.Context) []string {
+	if locale := c.Query("locale"); locale != "" {
+		if locale == "*" || locale == "all" {
+			return nil
+		}
+		return utils.ParseAcceptLanguage(locale)
+	}
+	if header := c.GetHeader("Accept-Language"); header != "" {
+		return utils.ParseAcceptLanguage(header)
+	}
+	return nil
+}
+
+// Helper function to replace the locale maps of localized fields with a single translation
+func (dc *DynamicAPIController) localizeDocument(document map[string]interface{}, schema *models.Schema, requested []string) {
+	if len(requested) == 0 || schema.Localization == nil {
+		return
+	}
+
+	chain := utils.LocaleChain(requested, schema.Localization.Fallback, schema.Localization.DefaultLocale)
+	for _, field := range schema.Fields {
+		if !field.Localized {
+			continue
+		}
+
+		var translations map[string]interface{}
+		switch v := document[field.Name].(type) {
+		case bson.M:
+			translations = v
+		case map[string]interface{}:
+			translations = v
+		default:
+			continue
+		}
+
+		value, _, _ := utils.ResolveLocalizedValue(translations, chain)
+		document[field.Name] = value
+	}
+}
+
 // Helper function to create aggregation pipeline for populating relations
-func (dc *DynamicAPIController) createPopulationPipeline(userID primitive.ObjectID, schema *models.Schema, matchFilter bson.M) []bson.M {
-	pipeline := []bson.M{
-		{"$match": matchFilter},
+func (dc *DynamicAPIController) createPopulationPipeline(c *gin.Context, userID primitive.ObjectID, schema *models.Schema, matchFilter bson.M, publishedView bool) []bson.M {
+	return dc.appendPopulationStages(c, userID, schema, []bson.M{{"$match": matchFilter}}, publishedView)
+}
+
+// Helper function to append $lookup stages for relation fields to an existing pipeline.
+// In the published view documents expose their published snapshot as data. Related documents
+// of other tenants are left out.
+func (dc *DynamicAPIController) appendPopulationStages(c *gin.Context, userID primitive.ObjectID, schema *models.Schema, pipeline []bson.M, publishedView bool) []bson.M {
+	if publishedView {
+		pipeline = append(pipeline, bson.M{"$addFields": bson.M{"data": "$published_data"}})
 	}
 
 	// Add $lookup stages for relation fields
@@ -90,14 +288,131 @@ func (dc *DynamicAPIController) createPopulationPipeline(userID primitive.Object
 				},
 			}
 			pipeline = append(pipeline, unwindStage)
+
+			// Callers without a tenant see no related documents of tenant-scoped collections
+			if tenantScoped(c, &targetSchema) {
+				populated := "$populated_" + field.Name
+				var value interface{} = "$$REMOVE"
+				if tenant := requestTenant(c); tenant != "" {
+					value = bson.M{
+						"$cond": bson.A{
+							bson.M{"$eq": bson.A{populated + "." + utils.TenantKey(&targetSchema), tenant}},
+							populated,
+							"$$REMOVE",
+						},
+					}
+				}
+				pipeline = append(pipeline, bson.M{"$addFields": bson.M{"populated_" + field.Name: value}})
+			}
+
+			// Anonymous readers only get published versions of related documents
+			if publishedView && targetSchema.Publishable {
+				populated := "$populated_" + field.Name
+				pipeline = append(pipeline, bson.M{
+					"$addFields": bson.M{
+						"populated_" + field.Name: bson.M{
+							"$cond": bson.A{
+								bson.M{"$and": bson.A{
+									bson.M{"$ne": bson.A{bson.M{"$type": populated + ".published_data"}, "missing"}},
+									bson.M{"$lte": bson.A{populated + ".published_at", time.Now()}},
+								}},
+								bson.M{
+									"_id":        populated + "._id",
+									"data":       populated + ".published_data",
+									"created_at": populated + ".created_at",
+									"updated_at": populated + ".updated_at",
+								},
+								"$$REMOVE",
+							},
+						},
+					},
+				})
+			}
 		}
 	}
 
 	return pipeline
 }
 
-// Helper function to filter fields based on visibility and populate relations
-func (dc *DynamicAPIController) filterPublicFieldsWithRelations(data bson.M, schema *models.Schema, userID primitive.ObjectID) map[string]interface{} {
+// Helper function to find the geopoint field used by near/within queries
+func (dc *DynamicAPIController) getGeoField(schema *models.Schema, name string) (*models.SchemaField, error) {
+	for i, field := range schema.Fields {
+		if field.Type != "geopoint" {
+			continue
+		}
+		if name == "" || field.Name == name {
+			return &schema.Fields[i], nil
+		}
+	}
+
+	if name != "" {
+		return nil, errors.New("Geopoint field not found: " + name)
+	}
+	return nil, errors.New("Collection has no geopoint field")
+}
+
+// Helper function to apply the near/within query parameters to the list filters.
+// A $geoNear stage is returned when results have to be ordered by distance.
+func (dc *DynamicAPIController) applyGeoFilters(c *gin.Context, schema *models.Schema, dataKey string, matchFilter, countFilter bson.M) (bson.M, error) {
+	near := c.Query("near")
+	within := c.Query("within")
+	if near == "" && within == "" {
+		return nil, nil
+	}
+	if near != "" && within != "" {
+		return nil, errors.New("near and within cannot be combined")
+	}
+
+	field, err := dc.getGeoField(schema, c.Query("geo_field"))
+	if err != nil {
+		return nil, err
+	}
+	key := dataKey + "." + field.Name
+
+	if within != "" {
+		polygon, err := utils.ParseWithinPolygon(within)
+		if err != nil {
+			return nil, errors.New("Invalid within filter: " + err.Error())
+		}
+		condition := bson.M{"$geoWithin": bson.M{"$geometry": polygon}}
+		matchFilter[key] = condition
+		countFilter[key] = condition
+		return nil, nil
+	}
+
+	lat, lng, err := utils.ParseLatLng(near)
+	if err != nil {
+		return nil, errors.New("Invalid near filter: " + err.Error())
+	}
+	point := bson.M{"type": "Point", "coordinates": []float64{lng, lat}}
+
+	geoNear := bson.M{
+		"near":          point,
+		"key":           key,
+		"distanceField": "distance",
+		"spherical":     true,
+		"query":         matchFilter,
+	}
+
+	countFilter[key] = bson.M{"$exists": true}
+	if maxDistanceStr := c.Query("max_distance"); maxDistanceStr != "" {
+		maxDistance, err := strconv.ParseFloat(maxDistanceStr, 64)
+		if err != nil || maxDistance <= 0 {
+			return nil, errors.New("max_distance must be a positive number of meters")
+		}
+		geoNear["maxDistance"] = maxDistance
+		countFilter[key] = bson.M{
+			"$geoWithin": bson.M{
+				"$centerSphere": []interface{}{[]float64{lng, lat}, maxDistance / utils.EarthRadiusMeters},
+			},
+		}
+	}
+
+	return bson.M{"$geoNear": geoNear}, nil
+}
+
+// Helper function to filter fields based on the viewer's read access and populate relations
+func (dc *DynamicAPIController) filterPublicFieldsWithRelations(data bson.M, schema *models.Schema, userID primitive.ObjectID, viewer fieldViewer) map[string]interface{} {
 	result := make(map[string]interface{})
 
 	// Always include ID and timestamps
@@ -110,10 +425,34 @@ func (dc *DynamicAPIController) filterPublicFieldsWithRelations(data bson.M, sch
 	if updatedAt, ok := data["updated_at"]; ok {
 		result["updated_at"] = updatedAt
 	}
+	if createdBy, ok := data["created_by"]; ok {
+		result["created_by"] = createdBy
+	}
+	if updatedBy, ok := data["updated_by"]; ok {
+		result["updated_by"] = updatedBy
+	}
+	if distance, ok := data["distance"]; ok {
+		result["distance"] = distance
+	}
 
-	// Include public fields and populate relations
+	// Publishable documents report their editorial state
+	if schema.Publishable {
+		result["status"] = documentStatus(data)
+		if publishedAt, ok := data["published_at"]; ok {
+			result["published_at"] = publishedAt
+		}
+	}
+
+	// Documents with a fixed TTL report when they will expire
+	if schema.Expiry != nil && schema.Expiry.TTLSeconds > 0 {
+		if createdAt, err := utils.ParseDate(data["created_at"]); err == nil {
+			result["expires_at"] = createdAt.Add(time.Duration(schema.Expiry.TTLSeconds) * time.Second)
+		}
+	}
+
+	// Include readable fields and populate relations
 	for _, field := range schema.Fields {
-		if field.Visibility == "public" {
+		if viewer.canRead(&field, data) {
 			if field.Type == "relation" && field.Target != "" {
 				// Get populated relation data
 				if populatedData, ok := data["populated_"+field.Name]; ok {
@@ -135,11 +474,18 @@ func (dc *DynamicAPIController) filterPublicFieldsWithRelations(data bson.M, sch
 								}
 							}
 						} else {
-							// Regular data collection - data is in the "data" field
+							// Regular data collection - data is in the "data" field, limited to
+							// the fields of the target schema the viewer may read
+							readable := make(map[string]bool)
+							for _, targetField := range targetSchema.Fields {
+								readable[targetField.Name] = viewer.canRead(&targetField, populatedDoc)
+							}
 							if docData, ok := populatedDoc["data"]; ok {
 								if docDataMap, ok := docData.(bson.M); ok {
 									for key, value := range docDataMap {
-										relatedData[key] = value
+										if allowed, known := readable[key]; allowed || !known {
+											relatedData[key] = value
+										}
 									}
 								}
 							}
@@ -166,6 +512,14 @@ func (dc *DynamicAPIController) filterPublicFieldsWithRelations(data bson.M, sch
 						}
 					}
 				}
+			} else if field.Type == "file" {
+				// Return file metadata and a download URL instead of the stored reference
+				if dataMap, ok := data["data"].(bson.M); ok {
+					if value, ok := dataMap[field.Name]; ok && value != nil {
+						documentID, _ := data["_id"].(primitive.ObjectID)
+						result[field.Name] = dc.fileFieldResponse(schema.CollectionName, documentID, field.Name, value)
+					}
+				}
 			} else {
 				// Regular field
 				if dataMap, ok := data["data"].(bson.M); ok {
@@ -188,6 +542,7 @@ func (dc *DynamicAPIController) filterPublicFieldsWithRelations(data bson.M, sch
 // @Security ApiKeyAuth
 // @Param collection path string true "Collection name"
 // @Param data body object true "Document data"
+// @Param Idempotency-Key header string false "Unique key for safe retries"
 // @Success 201 "Created"
 // @Failure 400 "Bad Request"
 // @Failure 401 "Unauthorized"
@@ -230,77 +585,50 @@ func (dc *DynamicAPIController) CreateDocument(c *gin.Context) {
 		return
 	}
 
-	// Validate and prepare document data
-	docData := make(map[string]interface{})
-	for _, field := range schema.Fields {
-		if value, ok := requestData[field.Name]; ok {
-			// Validate relation fields
-			if field.Type == "relation" && field.Target != "" {
-				// Convert value to ObjectID for validation
-				var relationID primitive.ObjectID
-				switch v := value.(type) {
-				case string:
-					var err error
-					relationID, err = primitive.ObjectIDFromHex(v)
-					if err != nil {
-						c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relation ID for field: " + field.Name})
-						return
-					}
-				case primitive.ObjectID:
-					relationID = v
-				default:
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Relation field must be a valid ObjectID: " + field.Name})
-					return
-				}
+	// Fields with a write policy may only be set by callers passing it
+	if status, err := dc.checkFieldWrites(c, db, userID, schema, nil, requestData); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
-				// Verify the referenced document exists
-				var count int64
+	// Validate and prepare document data
+	docData, status, err := dc.buildDocumentData(context.TODO(), db, userID, schema, requestData, false)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := dc.applyTenant(context.TODO(), db, userID, schema, requestTenant(c), docData); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
-				// Check if the target collection is an authentication schema
-				var targetSchema models.Schema
-				targetSchemaFilter := bson.M{"user_id": userID, "collection_name": field.Target, "is_active": true}
-				err = config.DB.Collection("schemas").FindOne(context.TODO(), targetSchemaFilter).Decode(&targetSchema)
+	documentID := primitive.NewObjectID()
 
-				if err == nil && targetSchema.AuthConfig != nil && targetSchema.AuthConfig.Enabled {
-					// This is an authentication collection, check in the user collection
-					userCollection := targetSchema.AuthConfig.UserCollection
-					if userCollection == "" {
-						userCollection = field.Target + "_users"
-					}
-					count, err = db.Collection(userCollection).CountDocuments(context.TODO(), bson.M{"_id": relationID})
-				} else {
-					// Regular data collection, check with user_id filter
-					count, err = db.Collection(field.Target).CountDocuments(context.TODO(), bson.M{"_id": relationID, "user_id": userID})
-				}
-
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate relation for field: " + field.Name})
-					return
-				}
-				if count == 0 {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Referenced document not found for field: " + field.Name})
-					return
-				}
+	// Check the create rule before anything else sees the document
+	if !dc.checkWriteRule(c, db, userID, schema, models.AccessCreate, documentID, docData) {
+		return
+	}
 
-				docData[field.Name] = relationID
-			} else {
-				docData[field.Name] = value
-			}
-		} else if field.Required {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Required field missing: " + field.Name})
-			return
-		} else if field.Default != nil {
-			docData[field.Name] = field.Default
-		}
+	// Let triggers and the before-write hook approve or change the document
+	docData, ok := dc.runBeforeWrite(c, db, userID, schema, models.BeforeWriteCreate, documentID, requestData, docData)
+	if !ok {
+		return
 	}
 
-	// Add metadata
+	// Add metadata, documents created by dynamic auth users belong to them
 	document := models.DynamicData{
-		ID:        primitive.NewObjectID(),
+		ID:        documentID,
 		Data:      docData,
 		UserID:    userID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		CreatedBy: dynamicAuthUserID(c),
+		UpdatedBy: dynamicAuthUserID(c),
+	}
+
+	// Publishable documents start as drafts
+	if schema.Publishable {
+		document.Status = models.DocumentStatusDraft
 	}
 
 	// Insert document
@@ -310,6 +638,10 @@ func (dc *DynamicAPIController) CreateDocument(c *gin.Context) {
 		return
 	}
 
+	dc.recordHistory(c, db, schema, userID, document.ID, models.HistoryActionCreate, nil, document.Data)
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventInsert, document.ID, document.Data)
+	dc.runAfterTriggers(c, db, userID, schema, models.BeforeWriteCreate, document.ID, document.Data)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":    "Document created successfully",
 		"id":         document.ID.Hex(),
@@ -325,7 +657,14 @@ func (dc *DynamicAPIController) CreateDocument(c *gin.Context) {
 // @Param collection path string true "Collection name"
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Param near query string false "Sort by distance from a point (lat,lng)"
+// @Param max_distance query number false "Maximum distance in meters, used with near"
+// @Param within query string false "Bounding box (minLat,minLng,maxLat,maxLng) or polygon (lat,lng;lat,lng;...)"
+// @Param geo_field query string false "Geopoint field to query when the schema has several"
+// @Param locale query string false "Locale for localized fields (falls back to Accept-Language, 'all' returns every translation)"
+// @Param status query string false "Editors only: filter publishable documents by status (draft, published, scheduled)"
 // @Success 200 "Success"
+// @Failure 400 "Bad Request"
 // @Failure 401 "Unauthorized"
 // @Failure 404 "Not Found"
 // @Failure 500 "Internal Server Error"
@@ -367,13 +706,14 @@ func (dc *DynamicAPIController) GetDocuments(c *gin.Context) {
 	}
 	skip := (page - 1) * limit
 
-	// Create aggregation pipeline with population
-	matchFilter := bson.M{"user_id": userID}
-	pipeline := dc.createPopulationPipeline(userID, schema, matchFilter)
+	pipeline, countFilter, err := dc.buildListPipeline(c, db, userID, schema)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Add pagination stages
 	pipeline = append(pipeline,
-		bson.M{"$sort": bson.M{"created_at": -1}},
 		bson.M{"$skip": int64(skip)},
 		bson.M{"$limit": int64(limit)},
 	)
@@ -393,14 +733,16 @@ func (dc *DynamicAPIController) GetDocuments(c *gin.Context) {
 	}
 
 	// Filter public fields and populate relations
+	locales := dc.requestedLocales(c)
 	var publicDocuments []map[string]interface{}
 	for _, doc := range documents {
-		publicData := dc.filterPublicFieldsWithRelations(doc, schema, userID)
+		publicData := dc.filterPublicFieldsWithRelations(doc, schema, userID, fieldViewerFor(c))
+		dc.localizeDocument(publicData, schema, locales)
 		publicDocuments = append(publicDocuments, publicData)
 	}
 
 	// Get total count
-	total, err := db.Collection(collectionName).CountDocuments(context.TODO(), bson.M{"user_id": userID})
+	total, err := db.Collection(collectionName).CountDocuments(context.TODO(), countFilter)
 	if err != nil {
 		total = 0
 	}
@@ -423,6 +765,7 @@ func (dc *DynamicAPIController) GetDocuments(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param collection path string true "Collection name"
 // @Param id path string true "Document ID"
+// @Param locale query string false "Locale for localized fields (falls back to Accept-Language, 'all' returns every translation)"
 // @Success 200 "Success"
 // @Failure 401 "Unauthorized"
 // @Failure 404 "Not Found"
@@ -465,7 +808,22 @@ func (dc *DynamicAPIController) GetDocumentByID(c *gin.Context) {
 
 	// Create aggregation pipeline with population for single document
 	matchFilter := bson.M{"_id": documentID, "user_id": userID}
-	pipeline := dc.createPopulationPipeline(userID, schema, matchFilter)
+	publishedView, err := dc.applyPublishFilters(c, schema, matchFilter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dataKey := "data"
+	if publishedView {
+		dataKey = "published_data"
+	}
+	if err := dc.applyReadRule(c, db, schema, dataKey, matchFilter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply read rule: " + err.Error()})
+		return
+	}
+	dc.applyOwnerFilter(c, schema, models.AccessRead, matchFilter)
+	dc.applyTenantFilter(c, schema, matchFilter)
+	pipeline := dc.createPopulationPipeline(c, userID, schema, matchFilter, publishedView)
 
 	// Execute aggregation
 	cursor, err := db.Collection(collectionName).Aggregate(context.TODO(), pipeline)
@@ -487,7 +845,8 @@ func (dc *DynamicAPIController) GetDocumentByID(c *gin.Context) {
 	}
 
 	// Filter public fields and populate relations
-	publicData := dc.filterPublicFieldsWithRelations(documents[0], schema, userID)
+	publicData := dc.filterPublicFieldsWithRelations(documents[0], schema, userID, fieldViewerFor(c))
+	dc.localizeDocument(publicData, schema, dc.requestedLocales(c))
 
 	c.JSON(http.StatusOK, publicData)
 }
@@ -549,80 +908,68 @@ func (dc *DynamicAPIController) UpdateDocument(c *gin.Context) {
 		return
 	}
 
-	// Validate and prepare update data
-	updateData := make(map[string]interface{})
-	for _, field := range schema.Fields {
-		if value, ok := requestData[field.Name]; ok {
-			// Validate relation fields
-			if field.Type == "relation" && field.Target != "" {
-				// Convert value to ObjectID for validation
-				var relationID primitive.ObjectID
-				switch v := value.(type) {
-				case string:
-					var err error
-					relationID, err = primitive.ObjectIDFromHex(v)
-					if err != nil {
-						c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relation ID for field: " + field.Name})
-						return
-					}
-				case primitive.ObjectID:
-					relationID = v
-				default:
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Relation field must be a valid ObjectID: " + field.Name})
-					return
-				}
-
-				// Verify the referenced document exists
-				var count int64
+	if status, err := dc.checkFieldWrites(c, db, userID, schema, &documentID, requestData); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
-				// Check if the target collection is an authentication schema
-				var targetSchema models.Schema
-				targetSchemaFilter := bson.M{"user_id": userID, "collection_name": field.Target, "is_active": true}
-				err = config.DB.Collection("schemas").FindOne(context.TODO(), targetSchemaFilter).Decode(&targetSchema)
+	// Validate and prepare update data
+	fieldData, status, err := dc.buildDocumentData(context.TODO(), db, userID, schema, requestData, true)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := dc.applyTenant(context.TODO(), db, userID, schema, requestTenant(c), fieldData); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
-				if err == nil && targetSchema.AuthConfig != nil && targetSchema.AuthConfig.Enabled {
-					// This is an authentication collection, check in the user collection
-					userCollection := targetSchema.AuthConfig.UserCollection
-					if userCollection == "" {
-						userCollection = field.Target + "_users"
-					}
-					count, err = db.Collection(userCollection).CountDocuments(context.TODO(), bson.M{"_id": relationID})
-				} else {
-					// Regular data collection, check with user_id filter
-					count, err = db.Collection(field.Target).CountDocuments(context.TODO(), bson.M{"_id": relationID, "user_id": userID})
-				}
+	// Check the update rule against the stored document and the result of the update
+	if !dc.checkWriteRule(c, db, userID, schema, models.AccessUpdate, documentID, fieldData) {
+		return
+	}
 
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate relation for field: " + field.Name})
-					return
-				}
-				if count == 0 {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Referenced document not found for field: " + field.Name})
-					return
-				}
+	// Let triggers and the before-write hook approve or change the update
+	fieldData, ok := dc.runBeforeWrite(c, db, userID, schema, models.BeforeWriteUpdate, documentID, requestData, fieldData)
+	if !ok {
+		return
+	}
 
-				updateData["data."+field.Name] = relationID
-			} else {
-				updateData["data."+field.Name] = value
-			}
-		}
+	updateData := make(map[string]interface{})
+	for key, value := range fieldData {
+		updateData["data."+key] = value
 	}
 	updateData["updated_at"] = time.Now()
+	if authUserID := dynamicAuthUserID(c); authUserID != nil {
+		updateData["updated_by"] = *authUserID
+	}
 
 	// Update document
-	filter := bson.M{"_id": documentID, "user_id": userID}
+	filter := dc.documentFilter(c, schema, models.AccessUpdate, documentID, userID)
 	update := bson.M{"$set": updateData}
 
-	result, err := db.Collection(collectionName).UpdateOne(context.TODO(), filter, update)
+	var previous bson.M
+	err = db.Collection(collectionName).FindOneAndUpdate(context.TODO(), filter, update).Decode(&previous)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
+		}
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
-		return
+	// Record the change with the stored data before and after the update
+	if schema.History {
+		var updated bson.M
+		if err := db.Collection(collectionName).FindOne(context.TODO(), filter).Decode(&updated); err == nil {
+			previousData, _ := previous["data"].(bson.M)
+			updatedData, _ := updated["data"].(bson.M)
+			dc.recordHistory(c, db, schema, userID, documentID, models.HistoryActionUpdate, previousData, updatedData)
+		}
 	}
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventUpdate, documentID, nil)
+	dc.runAfterTriggers(c, db, userID, schema, models.BeforeWriteUpdate, documentID, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Document updated successfully",
@@ -659,6 +1006,13 @@ func (dc *DynamicAPIController) DeleteDocument(c *gin.Context) {
 		return
 	}
 
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
 	// Get user's database
 	db, err := dc.getUserDatabase(c)
 	if err != nil {
@@ -670,17 +1024,37 @@ func (dc *DynamicAPIController) DeleteDocument(c *gin.Context) {
 		return
 	}
 
+	// Check the delete rule against the stored document
+	if !dc.checkWriteRule(c, db, userID, schema, models.AccessDelete, documentID, nil) {
+		return
+	}
+
+	// Let triggers and the before-write hook approve the delete
+	if _, ok := dc.runBeforeWrite(c, db, userID, schema, models.BeforeWriteDelete, documentID, nil, nil); !ok {
+		return
+	}
+
 	// Delete document
-	filter := bson.M{"_id": documentID, "user_id": userID}
-	result, err := db.Collection(collectionName).DeleteOne(context.TODO(), filter)
+	filter := dc.documentFilter(c, schema, models.AccessDelete, documentID, userID)
+	var deletedDocument bson.M
+	err = db.Collection(collectionName).FindOneAndDelete(context.TODO(), filter).Decode(&deletedDocument)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+		}
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
-		return
+	deletedData, _ := deletedDocument["data"].(bson.M)
+	dc.recordHistory(c, db, schema, userID, documentID, models.HistoryActionDelete, deletedData, nil)
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventDelete, documentID, deletedData)
+	dc.runAfterTriggers(c, db, userID, schema, models.BeforeWriteDelete, documentID, deletedData)
+
+	// Remove files attached to the document
+	if schemaHasFieldType(*schema, "file") {
+		dc.deleteDocumentFiles(db, schema, deletedDocument, nil)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
@@ -13,6 +13,7 @@ import (
 
 	"github.com/M-awais-rasool/SchemaCraft-go/config"
 	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -46,7 +47,7 @@ func (dac *DynamicAuthController) getAuthConfig(userID primitive.ObjectID, colle
 }
 
 // Helper function to generate JWT token for dynamic auth
-func (dac *DynamicAuthController) generateDynamicJWT(userID, schemaUserID, schemaID primitive.ObjectID, collection string, jwtSecret string, expirationHours int) (string, time.Time, error) {
+func (dac *DynamicAuthController) generateDynamicJWT(userID, schemaUserID, schemaID primitive.ObjectID, collection string, roles []string, tenant string, jwtSecret string, expirationHours int) (string, time.Time, error) {
 	expiresAt := time.Now().Add(time.Duration(expirationHours) * time.Hour)
 
 	claims := &models.DynamicAuthClaims{
@@ -54,6 +55,8 @@ func (dac *DynamicAuthController) generateDynamicJWT(userID, schemaUserID, schem
 		SchemaUserID: schemaUserID,
 		Collection:   collection,
 		SchemaID:     schemaID,
+		Roles:        roles,
+		Tenant:       tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -96,9 +99,34 @@ func (dac *DynamicAuthController) getUserDatabase(c *gin.Context) (*mongo.Databa
 		return nil, errors.New("MongoDB connection not configured")
 	}
 
+	if db, ok := sharedUserDatabase(c, user); ok {
+		return db, nil
+	}
+
 	return config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
 }
 
+// Helper function to read the roles stored on an auth user
+func authUserRoles(userData bson.M) []string {
+	stored, _ := userData[models.AuthRolesField].(bson.A)
+	roles := make([]string, 0, len(stored))
+	for _, role := range stored {
+		if name, ok := role.(string); ok {
+			roles = append(roles, name)
+		}
+	}
+	return roles
+}
+
+// Helper function to read the tenant stored on an auth user, empty when the user has none
+func authUserTenant(userData bson.M, authConfig *models.AuthConfig) string {
+	if authConfig.TenantField == "" {
+		return ""
+	}
+	tenant, _ := userData[authConfig.TenantField].(string)
+	return tenant
+}
+
 // Helper function to filter response fields
 func (dac *DynamicAuthController) filterResponseFields(userData map[string]interface{}, responseFields []string) map[string]interface{} {
 	if len(responseFields) == 0 {
@@ -121,6 +149,19 @@ func (dac *DynamicAuthController) filterResponseFields(userData map[string]inter
 	return result
 }
 
+// Helper function to build the signup webhook payload. The configured password field is removed
+// whatever its name, as are fields that look like passwords.
+func signupWebhookData(userData map[string]interface{}, passwordField string) map[string]interface{} {
+	result := make(map[string]interface{}, len(userData))
+	for key, value := range userData {
+		if key == passwordField || strings.Contains(strings.ToLower(key), "password") {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
 // @Summary Dynamic API Signup
 // @Description Sign up for a dynamic API with custom schema
 // @Tags dynamic-auth
@@ -129,6 +170,7 @@ func (dac *DynamicAuthController) filterResponseFields(userData map[string]inter
 // @Security ApiKeyAuth
 // @Param collection path string true "Collection name"
 // @Param signup body models.DynamicAuthSignupRequest true "Signup data"
+// @Param Idempotency-Key header string false "Unique key for safe retries"
 // @Success 201 "Created"
 // @Failure 400 "Bad Request"
 // @Failure 401 "Unauthorized"
@@ -240,6 +282,20 @@ func (dac *DynamicAuthController) Signup(c *gin.Context) {
 		}
 	}
 
+	// Users can't pick their own roles, new users get the default role
+	var roles []string
+	if authConfig.DefaultRole != "" {
+		roles = []string{authConfig.DefaultRole}
+	}
+	userDoc[models.AuthRolesField] = roles
+	req.Data[models.AuthRolesField] = roles
+
+	// Tenants are assigned by the API owner as well
+	if authConfig.TenantField != "" {
+		delete(userDoc, authConfig.TenantField)
+		delete(req.Data, authConfig.TenantField)
+	}
+
 	// Insert user
 	result, err := db.Collection(userCollection).InsertOne(context.TODO(), userDoc)
 	if err != nil {
@@ -250,6 +306,9 @@ func (dac *DynamicAuthController) Signup(c *gin.Context) {
 	// Get the inserted user ID
 	schemaUserID := result.InsertedID.(primitive.ObjectID)
 
+	// Notify signup webhooks without the password
+	triggerWebhooks(db, userID, collection, models.WebhookEventSignup, schemaUserID, signupWebhookData(req.Data, passwordField))
+
 	// Generate JWT token
 	jwtSecret := authConfig.JWTSecret
 	if jwtSecret == "" {
@@ -268,7 +327,7 @@ func (dac *DynamicAuthController) Signup(c *gin.Context) {
 		tokenExpiration = 24 // Default 24 hours
 	}
 
-	token, expiresAt, err := dac.generateDynamicJWT(userID, schemaUserID, schema.ID, collection, jwtSecret, tokenExpiration)
+	token, expiresAt, err := dac.generateDynamicJWT(userID, schemaUserID, schema.ID, collection, roles, "", jwtSecret, tokenExpiration)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -402,7 +461,7 @@ func (dac *DynamicAuthController) Login(c *gin.Context) {
 		tokenExpiration = 24 // Default 24 hours
 	}
 
-	token, expiresAt, err := dac.generateDynamicJWT(userID, schemaUserID, schema.ID, collection, jwtSecret, tokenExpiration)
+	token, expiresAt, err := dac.generateDynamicJWT(userID, schemaUserID, schema.ID, collection, authUserRoles(userData), authUserTenant(userData, authConfig), jwtSecret, tokenExpiration)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -480,6 +539,8 @@ func (dac *DynamicAuthController) ValidateToken(c *gin.Context) {
 		"valid":      true,
 		"user_id":    claims.SchemaUserID.Hex(),
 		"collection": claims.Collection,
+		"roles":      claims.Roles,
+		"tenant":     claims.Tenant,
 		"expires_at": claims.ExpiresAt,
 	})
 }
@@ -601,3 +662,205 @@ func (dac *DynamicAuthController) GetAllUsers(c *gin.Context) {
 		"total_pages": totalPages,
 	})
 }
+
+// Helper function to load the authentication schema managed through an owner endpoint. After
+// an error response has been written the second result is false.
+func (dac *DynamicAuthController) ownerAuthSchema(c *gin.Context, userID interface{}) (*models.Schema, bool) {
+	schemaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return nil, false
+	}
+
+	var schema models.Schema
+	filter := bson.M{"_id": schemaID, "user_id": userID, "is_active": true}
+	err = config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&schema)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find schema"})
+		}
+		return nil, false
+	}
+
+	if schema.AuthConfig == nil || !schema.AuthConfig.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Authentication not configured for this schema"})
+		return nil, false
+	}
+	return &schema, true
+}
+
+// Helper function to update a user of an authentication schema in the owner's database. After
+// an error response has been written it returns false.
+func (dac *DynamicAuthController) updateAuthUser(c *gin.Context, userID interface{}, schema *models.Schema, schemaUserID primitive.ObjectID, fields bson.M) bool {
+	var user models.User
+	if err := config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
+		return false
+	}
+	if user.MongoDBURI == "" || user.DatabaseName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		return false
+	}
+
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		return false
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	userCollection := schema.AuthConfig.UserCollection
+	if userCollection == "" {
+		userCollection = schema.CollectionName + "_users"
+	}
+
+	fields["updated_at"] = time.Now()
+	result, err := db.Collection(userCollection).UpdateOne(context.TODO(), bson.M{"_id": schemaUserID}, bson.M{"$set": fields})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return false
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return false
+	}
+	return true
+}
+
+// @Summary Set auth user roles
+// @Description Replace the roles of a user of an authentication schema. Only the API owner can assign roles. Roles are carried in tokens, so users receive new roles the next time they log in.
+// @Tags dynamic-auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param user_id path string true "Auth user ID"
+// @Param request body models.SetAuthUserRolesRequest true "Roles to assign"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/users/{user_id}/roles [put]
+func (dac *DynamicAuthController) SetUserRoles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaUserID, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.SetAuthUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schema, ok := dac.ownerAuthSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	// Roles are validated against the schema's list when it has one, duplicates are dropped
+	roles := make([]string, 0, len(req.Roles))
+	seen := make(map[string]bool)
+	for _, role := range req.Roles {
+		if err := utils.ValidateRoleName(role); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(schema.AuthConfig.Roles) > 0 && !utils.HasAnyRole([]string{role}, schema.AuthConfig.Roles) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role '" + role + "' is not defined for this schema"})
+			return
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	if !dac.updateAuthUser(c, userID, schema, schemaUserID, bson.M{models.AuthRolesField: roles}) {
+		return
+	}
+
+	go LogActivityWithContext(c, userID.(primitive.ObjectID), models.ActivityTypeSecurity, "Updated roles in \""+schema.CollectionName+"\"", "Roles assigned to an auth user", "auth_user", schemaUserID.Hex(), map[string]any{
+		"roles": roles,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Roles updated successfully",
+		"id":      schemaUserID.Hex(),
+		"roles":   roles,
+	})
+}
+
+// @Summary Set auth user tenant
+// @Description Move a user of an authentication schema to a tenant. Only the API owner can assign tenants. Tenants are carried in tokens, so the change applies the next time the user logs in.
+// @Tags dynamic-auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param user_id path string true "Auth user ID"
+// @Param request body models.SetAuthUserTenantRequest true "Tenant to assign"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/users/{user_id}/tenant [put]
+func (dac *DynamicAuthController) SetUserTenant(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaUserID, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.SetAuthUserTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schema, ok := dac.ownerAuthSchema(c, userID)
+	if !ok {
+		return
+	}
+	if schema.AuthConfig.TenantField == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tenants are not configured for this schema, set auth_config.tenant_field first"})
+		return
+	}
+
+	tenant := strings.TrimSpace(req.Tenant)
+	if len(tenant) > utils.MaxTenantLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tenant must be at most %d characters", utils.MaxTenantLength)})
+		return
+	}
+
+	if !dac.updateAuthUser(c, userID, schema, schemaUserID, bson.M{schema.AuthConfig.TenantField: tenant}) {
+		return
+	}
+
+	go LogActivityWithContext(c, userID.(primitive.ObjectID), models.ActivityTypeSecurity, "Updated tenant in \""+schema.CollectionName+"\"", "Tenant assigned to an auth user", "auth_user", schemaUserID.Hex(), map[string]any{
+		"tenant": tenant,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tenant updated successfully",
+		"id":      schemaUserID.Hex(),
+		"tenant":  tenant,
+	})
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import "testing"
+
+func TestSignupWebhookDataRemovesPasswordField(t *testing.T) {
+	data := map[string]interface{}{
+		"email":        "ada@example.com",
+		"pin":          "1234",
+		"old_password": "hunter2",
+	}
+
+	payload := signupWebhookData(data, "pin")
+
+	if _, ok := payload["pin"]; ok {
+		t.Fatalf("payload contains the password field: %v", payload)
+	}
+	if _, ok := payload["old_password"]; ok {
+		t.Fatalf("payload contains a password-like field: %v", payload)
+	}
+	if payload["email"] != "ada@example.com" {
+		t.Fatalf("payload lost the email: %v", payload)
+	}
+	if data["pin"] != "1234" {
+		t.Fatal("signup data was modified")
+	}
+}
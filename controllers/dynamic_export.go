@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportFlushInterval is the number of documents written between flushes to the client
+const exportFlushInterval = 100
+
+// exportContentTypes lists the supported export formats
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"json":   "application/json; charset=utf-8",
+}
+
+// documentExporter writes documents in one export format
+type documentExporter interface {
+	Begin() error
+	Write(document map[string]interface{}) error
+	End() error
+}
+
+// jsonArrayExporter writes documents as a single JSON array
+type jsonArrayExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonArrayExporter) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonArrayExporter) Write(document map[string]interface{}) error {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(encoded)
+	return err
+}
+
+func (e *jsonArrayExporter) End() error {
+	_, err := io.WriteString(e.w, "]")
+	return err
+}
+
+// ndjsonExporter writes one JSON document per line
+type ndjsonExporter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExporter) Begin() error {
+	return nil
+}
+
+func (e *ndjsonExporter) Write(document map[string]interface{}) error {
+	return e.encoder.Encode(document)
+}
+
+func (e *ndjsonExporter) End() error {
+	return nil
+}
+
+// csvExporter writes one row per document with a fixed set of columns
+type csvExporter struct {
+	writer    *csv.Writer
+	columns   []string
+	relations map[string]bool
+}
+
+func (e *csvExporter) Begin() error {
+	return e.writer.Write(e.columns)
+}
+
+func (e *csvExporter) Write(document map[string]interface{}) error {
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		value, err := csvExportValue(document[column], e.relations[column])
+		if err != nil {
+			return err
+		}
+		record[i] = value
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvExporter) End() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// Helper function to list the CSV columns in schema order, limited to the selected fields the
+// viewer may read
+func exportColumns(schema *models.Schema, fields map[string]bool, viewer fieldViewer) []string {
+	columns := []string{"id"}
+	for _, field := range schema.Fields {
+		if viewer.mayReadSome(&field) && (fields == nil || fields[field.Name]) {
+			columns = append(columns, field.Name)
+		}
+	}
+
+	meta := []string{"created_at", "updated_at"}
+	if schema.Publishable {
+		meta = append(meta, "status", "published_at")
+	}
+	if schema.Expiry != nil && schema.Expiry.TTLSeconds > 0 {
+		meta = append(meta, "expires_at")
+	}
+	for _, column := range meta {
+		if fields == nil || fields[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// Helper function to convert a document value to a CSV cell. Populated relations are written
+// as their ID and other structured values as JSON, the same form the CSV import accepts.
+func csvExportValue(value interface{}, relation bool) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case primitive.ObjectID:
+		return v.Hex(), nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339), nil
+	case primitive.DateTime:
+		return v.Time().UTC().Format(time.RFC3339), nil
+	case map[string]interface{}:
+		if id, ok := v["id"]; relation && ok {
+			return csvExportValue(id, false)
+		}
+	case bson.M:
+		if id, ok := v["id"]; relation && ok {
+			return csvExportValue(id, false)
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// @Summary Export documents
+// @Description Stream all matching documents of a collection as CSV, NDJSON or a JSON array. Accepts the same publish, geo and locale parameters as the list endpoint and an optional field selection.
+// @Tags dynamic-api
+// @Produce plain
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param format query string false "Export format: csv, ndjson or json (default: ndjson)"
+// @Param fields query string false "Comma separated list of fields to export"
+// @Param locale query string false "Locale for localized fields (falls back to Accept-Language, 'all' returns every translation)"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/export [get]
+func (dc *DynamicAPIController) ExportDocuments(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+
+	format := c.DefaultQuery("format", "ndjson")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be one of: csv, ndjson, json"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	pipeline, _, err := dc.buildListPipeline(c, db, userID, schema)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fields, err := dc.requestedFields(c, schema)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Stop reading from MongoDB as soon as the client goes away
+	ctx := c.Request.Context()
+	cursor, err := db.Collection(collectionName).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	viewer := fieldViewerFor(c)
+	var exporter documentExporter
+	switch format {
+	case "csv":
+		relations := make(map[string]bool)
+		for _, field := range schema.Fields {
+			if field.Type == "relation" {
+				relations[field.Name] = true
+			}
+		}
+		exporter = &csvExporter{writer: csv.NewWriter(c.Writer), columns: exportColumns(schema, fields, viewer), relations: relations}
+	case "json":
+		exporter = &jsonArrayExporter{w: c.Writer}
+	default:
+		exporter = &ndjsonExporter{encoder: json.NewEncoder(c.Writer)}
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", collectionName+"."+format))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// The status is already sent, so errors past this point can only end the stream early
+	if err := exporter.Begin(); err != nil {
+		return
+	}
+
+	locales := dc.requestedLocales(c)
+	count := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			fmt.Printf("Warning: Failed to decode exported document in %s: %v\n", collectionName, err)
+			return
+		}
+
+		publicData := dc.filterPublicFieldsWithRelations(doc, schema, userID, viewer)
+		dc.localizeDocument(publicData, schema, locales)
+		selectDocumentFields(publicData, fields)
+
+		if err := exporter.Write(publicData); err != nil {
+			return
+		}
+
+		count++
+		if count%exportFlushInterval == 0 {
+			if csvWriter, ok := exporter.(*csvExporter); ok {
+				csvWriter.writer.Flush()
+			}
+			c.Writer.Flush()
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		fmt.Printf("Warning: Export of %s stopped after %d documents: %v\n", collectionName, count, err)
+		return
+	}
+
+	if err := exporter.End(); err != nil {
+		return
+	}
+	c.Writer.Flush()
+}
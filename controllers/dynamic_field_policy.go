@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fieldViewer is the caller that field policies are checked against
+type fieldViewer struct {
+	userID *primitive.ObjectID // Dynamic auth user, nil without a token
+	roles  []string
+	server bool // Server-side code such as functions passes every policy but private
+}
+
+// Helper function to build the field viewer of a request
+func fieldViewerFor(c *gin.Context) fieldViewer {
+	return fieldViewer{
+		userID: dynamicAuthUserID(c),
+		roles:  c.GetStringSlice("dynamic_auth_roles"),
+		server: rulesBypassed(c),
+	}
+}
+
+// Helper function to check a policy for a document. Without a document owner policies fail,
+// so they can't be used where values of several documents are compared.
+func (v fieldViewer) allows(policy string, document bson.M) bool {
+	if v.server {
+		return policy != models.FieldPolicyPrivate
+	}
+	owner := false
+	if v.userID != nil && document != nil {
+		createdBy, ok := document["created_by"].(primitive.ObjectID)
+		owner = ok && createdBy == *v.userID
+	}
+	return utils.FieldPolicyAllows(policy, v.userID != nil, v.roles, owner)
+}
+
+// Helper function to check whether the viewer may read a field of a stored document
+func (v fieldViewer) canRead(field *models.SchemaField, document bson.M) bool {
+	return v.allows(utils.FieldReadPolicy(field), document)
+}
+
+// Helper function to check whether a field can appear in responses to the viewer, owner
+// fields may show up on the viewer's own documents
+func (v fieldViewer) mayReadSome(field *models.SchemaField) bool {
+	policy := utils.FieldReadPolicy(field)
+	if policy == models.FieldPolicyOwner {
+		return v.server || v.userID != nil
+	}
+	return v.allows(policy, nil)
+}
+
+// Helper function to list the schema fields a payload sets. Keys like "title.en" set a single
+// locale of a localized field.
+func writtenFields(schema *models.Schema, payload map[string]interface{}) []*models.SchemaField {
+	written := make(map[string]bool, len(payload))
+	for key := range payload {
+		name, _, _ := strings.Cut(key, ".")
+		written[name] = true
+	}
+
+	var fields []*models.SchemaField
+	for i := range schema.Fields {
+		if written[schema.Fields[i].Name] {
+			fields = append(fields, &schema.Fields[i])
+		}
+	}
+	return fields
+}
+
+// Helper function to check the write policies of a new document's fields. The caller becomes
+// the owner, so owner fields only need a token.
+func (v fieldViewer) checkCreateFields(schema *models.Schema, payload map[string]interface{}) error {
+	for _, field := range writtenFields(schema, payload) {
+		policy := utils.FieldWritePolicy(field)
+		if policy == models.FieldPolicyOwner && v.userID != nil {
+			continue
+		}
+		if !v.allows(policy, nil) {
+			return errors.New("Field '" + field.Name + "' cannot be written")
+		}
+	}
+	return nil
+}
+
+// Helper function to check the write policies of the fields a revert changes. The document
+// carries the owner, stored values are compared as JSON.
+func (v fieldViewer) checkChangedFields(schema *models.Schema, before, after map[string]interface{}, document bson.M) error {
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		if reflect.DeepEqual(utils.RuleValue(before[field.Name]), utils.RuleValue(after[field.Name])) {
+			continue
+		}
+		if !v.allows(utils.FieldWritePolicy(field), document) {
+			return errors.New("Field '" + field.Name + "' cannot be written")
+		}
+	}
+	return nil
+}
+
+// Helper function to check the write policies of the fields set by a payload. Creates (nil
+// document ID) make the caller the owner, updates compare with the stored document. On failure
+// it returns the HTTP status to respond with.
+func (dc *DynamicAPIController) checkFieldWrites(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, documentID *primitive.ObjectID, payload map[string]interface{}) (int, error) {
+	viewer := fieldViewerFor(c)
+	if documentID == nil {
+		if err := viewer.checkCreateFields(schema, payload); err != nil {
+			return http.StatusForbidden, err
+		}
+		return http.StatusOK, nil
+	}
+
+	var document bson.M
+	for _, field := range writtenFields(schema, payload) {
+		policy := utils.FieldWritePolicy(field)
+
+		// The owner is only loaded when a written field needs it
+		if policy == models.FieldPolicyOwner && viewer.userID != nil && document == nil {
+			document = bson.M{}
+			filter := bson.M{"_id": *documentID, "user_id": userID}
+			findOptions := options.FindOne().SetProjection(bson.M{"created_by": 1})
+			err := db.Collection(schema.CollectionName).FindOne(context.TODO(), filter, findOptions).Decode(&document)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return http.StatusInternalServerError, errors.New("Database error")
+			}
+		}
+
+		if !viewer.allows(policy, document) {
+			return http.StatusForbidden, errors.New("Field '" + field.Name + "' cannot be written")
+		}
+	}
+	return http.StatusOK, nil
+}
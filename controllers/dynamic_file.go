@@ -0,0 +1,728 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultMaxFileSize applies to file fields without a max_size
+	defaultMaxFileSize int64 = 10 << 20
+	// maxFileFieldSize is the largest max_size a file field may declare
+	maxFileFieldSize int64 = 100 << 20
+)
+
+// Helper function to open the GridFS bucket holding a collection's files
+func (dc *DynamicAPIController) getFileBucket(db *mongo.Database, collectionName string) (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(db, options.GridFSBucket().SetName(collectionName+"_files"))
+}
+
+// Helper function to find a file field in the schema
+func (dc *DynamicAPIController) getFileField(schema *models.Schema, name string) (*models.SchemaField, error) {
+	for i, field := range schema.Fields {
+		if field.Name == name {
+			if field.Type != "file" {
+				return nil, errors.New("Field is not a file field: " + name)
+			}
+			return &schema.Fields[i], nil
+		}
+	}
+	return nil, errors.New("Field not found: " + name)
+}
+
+// Helper function to decode stored file metadata
+func (dc *DynamicAPIController) decodeFileMetadata(value interface{}) (*models.FileMetadata, error) {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata models.FileMetadata
+	if err := bson.Unmarshal(raw, &metadata); err != nil {
+		return nil, err
+	}
+	if metadata.FileID.IsZero() {
+		return nil, errors.New("file reference missing")
+	}
+	return &metadata, nil
+}
+
+// Helper function to build the API representation of a stored file
+func (dc *DynamicAPIController) fileFieldResponse(collectionName string, documentID primitive.ObjectID, fieldName string, value interface{}) interface{} {
+	metadata, err := dc.decodeFileMetadata(value)
+	if err != nil {
+		return nil
+	}
+
+	return gin.H{
+		"id":           metadata.FileID.Hex(),
+		"filename":     metadata.Filename,
+		"content_type": metadata.ContentType,
+		"size":         metadata.Size,
+		"uploaded_at":  metadata.UploadedAt,
+		"url":          "/api/" + collectionName + "/" + documentID.Hex() + "/files/" + fieldName,
+	}
+}
+
+// Helper function to check a MIME type against a field's allowed types
+func fileTypeAllowed(contentType string, allowedTypes []string) bool {
+	if len(allowedTypes) == 0 {
+		return true
+	}
+
+	for _, allowedType := range allowedTypes {
+		allowedType = strings.ToLower(strings.TrimSpace(allowedType))
+		if allowedType == contentType || allowedType == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(allowedType, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowedType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to collect the GridFS file IDs referenced by document data
+func (dc *DynamicAPIController) referencedFileIDs(schema *models.Schema, dataMap bson.M) map[primitive.ObjectID]bool {
+	fileIDs := make(map[primitive.ObjectID]bool)
+	for _, field := range schema.Fields {
+		if field.Type != "file" {
+			continue
+		}
+		if value, ok := dataMap[field.Name]; ok && value != nil {
+			if metadata, err := dc.decodeFileMetadata(value); err == nil {
+				fileIDs[metadata.FileID] = true
+			}
+		}
+	}
+	return fileIDs
+}
+
+// Helper function to delete the GridFS files referenced by a document's draft and published
+// data, except the ones listed in keep. Files still referenced by history snapshots are kept
+// so older versions can be restored.
+func (dc *DynamicAPIController) deleteDocumentFiles(db *mongo.Database, schema *models.Schema, document bson.M, keep map[primitive.ObjectID]bool) {
+	fileIDs := make(map[primitive.ObjectID]bool)
+	for _, key := range []string{"data", "published_data"} {
+		if dataMap, ok := document[key].(bson.M); ok {
+			for fileID := range dc.referencedFileIDs(schema, dataMap) {
+				if !keep[fileID] {
+					fileIDs[fileID] = true
+				}
+			}
+		}
+	}
+	for fileID := range dc.historyFileIDs(db, schema, fileIDs) {
+		delete(fileIDs, fileID)
+	}
+	if len(fileIDs) == 0 {
+		return
+	}
+
+	bucket, err := dc.getFileBucket(db, schema.CollectionName)
+	if err != nil {
+		return
+	}
+
+	for fileID := range fileIDs {
+		if err := bucket.Delete(fileID); err != nil && err != gridfs.ErrFileNotFound {
+			fmt.Printf("Warning: Failed to delete file %s: %v\n", fileID.Hex(), err)
+		}
+	}
+}
+
+// @Summary Upload file to document field
+// @Description Upload a file for a file field of a document. Replaces any existing file. Protected like document updates.
+// @Tags dynamic-api
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param field path string true "File field name"
+// @Param file formData file true "File to upload"
+// @Success 201 "Created"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 413 "Request Entity Too Large"
+// @Failure 415 "Unsupported Media Type"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/files/{field} [post]
+func (dc *DynamicAPIController) UploadFile(c *gin.Context) {
+	collectionName := c.Param("collection")
+	fieldName := c.Param("field")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	field, err := dc.getFileField(schema, fieldName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Uploads and removals change the document without calling the before-write hook or trigger functions
+	if err := beforeWriteHookBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := beforeTriggerBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxSize := field.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultMaxFileSize
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	// Make sure the document exists before accepting the upload
+	filter := dc.documentFilter(c, schema, models.AccessUpdate, documentID, userID)
+	var document bson.M
+	err = db.Collection(collectionName).FindOne(context.TODO(), filter).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	if status, err := dc.checkFieldWrites(c, db, userID, schema, &documentID, map[string]interface{}{field.Name: nil}); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Uploads change the document, so they follow the update rule
+	if allowed := dc.writeRule(c, db, schema, models.AccessUpdate); allowed != nil {
+		data, _ := document["data"].(bson.M)
+		if !allowed(documentID, data, data) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied by update rule"})
+			return
+		}
+	}
+
+	// Leave some room for the multipart envelope
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+(1<<20))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the maximum size of %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multipart form field 'file' is required"})
+		return
+	}
+
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the maximum size of %d bytes", maxSize)})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	// Detect the content type from the file itself, falling back to the declared type
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	if contentType == "application/octet-stream" || strings.HasPrefix(contentType, "text/plain") {
+		if declared, _, err := mime.ParseMediaType(fileHeader.Header.Get("Content-Type")); err == nil && declared != "" {
+			contentType = declared
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	contentType = strings.ToLower(contentType)
+
+	if !fileTypeAllowed(contentType, field.AllowedTypes) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":         "File type " + contentType + " is not allowed for field: " + field.Name,
+			"allowed_types": field.AllowedTypes,
+		})
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	bucket, err := dc.getFileBucket(db, collectionName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file storage"})
+		return
+	}
+
+	uploadOptions := options.GridFSUpload().SetMetadata(bson.M{
+		"document_id":  documentID,
+		"field":        field.Name,
+		"content_type": contentType,
+		"user_id":      userID,
+	})
+	fileID, err := bucket.UploadFromStream(fileHeader.Filename, file, uploadOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	metadata := models.FileMetadata{
+		FileID:      fileID,
+		Filename:    fileHeader.Filename,
+		ContentType: contentType,
+		Size:        fileHeader.Size,
+		UploadedAt:  time.Now(),
+	}
+
+	fields := bson.M{
+		"data." + field.Name: metadata,
+		"updated_at":         time.Now(),
+	}
+	if authUserID := dynamicAuthUserID(c); authUserID != nil {
+		fields["updated_by"] = *authUserID
+	}
+	update := bson.M{"$set": fields}
+	if _, err := db.Collection(collectionName).UpdateOne(context.TODO(), filter, update); err != nil {
+		bucket.Delete(fileID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach file to document"})
+		return
+	}
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventUpdate, documentID, nil)
+
+	// Remove the file that was replaced unless the published version still serves it
+	fieldSchema := &models.Schema{CollectionName: collectionName, Fields: []models.SchemaField{*field}}
+	publishedData, _ := document["published_data"].(bson.M)
+	dc.deleteDocumentFiles(db, fieldSchema, bson.M{"data": document["data"]}, dc.referencedFileIDs(fieldSchema, publishedData))
+
+	c.JSON(http.StatusCreated, dc.fileFieldResponse(collectionName, documentID, field.Name, metadata))
+}
+
+// @Summary Download file from document field
+// @Description Download the file stored in a file field. Supports single byte ranges via the Range header.
+// @Tags dynamic-api
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param field path string true "File field name"
+// @Param download query bool false "Send as attachment instead of inline"
+// @Success 200 "Success"
+// @Success 206 "Partial Content"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 416 "Requested Range Not Satisfiable"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/files/{field} [get]
+func (dc *DynamicAPIController) DownloadFile(c *gin.Context) {
+	collectionName := c.Param("collection")
+	fieldName := c.Param("field")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	field, err := dc.getFileField(schema, fieldName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer := fieldViewerFor(c)
+	if !viewer.mayReadSome(field) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	// Readers without draft access download the published version of the file
+	filter := bson.M{"_id": documentID, "user_id": userID}
+	publishedView, err := dc.applyPublishFilters(c, schema, filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dataKey := "data"
+	if publishedView {
+		dataKey = "published_data"
+	}
+	if err := dc.applyReadRule(c, db, schema, dataKey, filter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply read rule: " + err.Error()})
+		return
+	}
+	dc.applyOwnerFilter(c, schema, models.AccessRead, filter)
+	dc.applyTenantFilter(c, schema, filter)
+
+	var document bson.M
+	err = db.Collection(collectionName).FindOne(context.TODO(), filter).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	dataMap, _ := document[dataKey].(bson.M)
+	value, ok := dataMap[field.Name]
+	if !ok || value == nil || !viewer.canRead(field, document) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	metadata, err := dc.decodeFileMetadata(value)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	bucket, err := dc.getFileBucket(db, collectionName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file storage"})
+		return
+	}
+
+	stream, err := bucket.OpenDownloadStream(metadata.FileID)
+	if err != nil {
+		if err == gridfs.ErrFileNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		}
+		return
+	}
+	defer stream.Close()
+
+	size := stream.GetFile().Length
+	start, end := int64(0), size-1
+	status := http.StatusOK
+
+	if rangeHeader := c.GetHeader("Range"); rangeHeader != "" && size > 0 {
+		var ok bool
+		start, end, ok = parseByteRange(rangeHeader, size)
+		if !ok {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+			c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": "Invalid range"})
+			return
+		}
+		status = http.StatusPartialContent
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+
+	if start > 0 {
+		if _, err := stream.Skip(start); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+	}
+
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+
+	length := end - start + 1
+	if size == 0 {
+		length = 0
+	}
+
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Content-Type", metadata.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(length, 10))
+	c.Header("Content-Disposition", contentDisposition(disposition, metadata.Filename))
+	c.Header("ETag", "\""+metadata.FileID.Hex()+"\"")
+	c.Status(status)
+
+	if c.Request.Method == http.MethodHead || length == 0 {
+		return
+	}
+
+	if _, err := io.CopyN(c.Writer, stream, length); err != nil {
+		fmt.Printf("Warning: File download interrupted for %s: %v\n", metadata.FileID.Hex(), err)
+	}
+}
+
+// @Summary Delete file from document field
+// @Description Delete the file stored in a file field. Protected like document updates.
+// @Tags dynamic-api
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param field path string true "File field name"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/files/{field} [delete]
+func (dc *DynamicAPIController) DeleteFile(c *gin.Context) {
+	collectionName := c.Param("collection")
+	fieldName := c.Param("field")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	field, err := dc.getFileField(schema, fieldName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Uploads and removals change the document without calling the before-write hook or trigger functions
+	if err := beforeWriteHookBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := beforeTriggerBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	if status, err := dc.checkFieldWrites(c, db, userID, schema, &documentID, map[string]interface{}{field.Name: nil}); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if !dc.checkWriteRule(c, db, userID, schema, models.AccessUpdate, documentID, map[string]interface{}{field.Name: nil}) {
+		return
+	}
+
+	// Detach the file and get the previous document in one step
+	filter := dc.documentFilter(c, schema, models.AccessUpdate, documentID, userID)
+	fields := bson.M{"updated_at": time.Now()}
+	if authUserID := dynamicAuthUserID(c); authUserID != nil {
+		fields["updated_by"] = *authUserID
+	}
+	update := bson.M{
+		"$unset": bson.M{"data." + field.Name: ""},
+		"$set":   fields,
+	}
+
+	var previous bson.M
+	err = db.Collection(collectionName).FindOneAndUpdate(context.TODO(), filter, update).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
+		}
+		return
+	}
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventUpdate, documentID, nil)
+
+	dataMap, _ := previous["data"].(bson.M)
+	value, ok := dataMap[field.Name]
+	if !ok || value == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	fieldSchema := &models.Schema{CollectionName: collectionName, Fields: []models.SchemaField{*field}}
+	publishedData, _ := previous["published_data"].(bson.M)
+	dc.deleteDocumentFiles(db, fieldSchema, bson.M{"data": previous["data"]}, dc.referencedFileIDs(fieldSchema, publishedData))
+
+	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+}
+
+// Helper function to parse a single "bytes=start-end" range against the file size
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, false
+	}
+
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	if strings.Contains(spec, ",") {
+		// Multiple ranges are not supported
+		return 0, 0, false
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	var start, end int64
+	var err error
+
+	if parts[0] == "" {
+		// Suffix range, e.g. bytes=-500 for the last 500 bytes
+		suffix, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, size - 1, true
+	}
+
+	start, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	if parts[1] == "" {
+		end = size - 1
+	} else {
+		end, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, true
+}
+
+// Helper function to build a Content-Disposition header value. Names that are not plain ASCII
+// get an RFC 5987 filename* parameter next to a quoted ASCII fallback for older clients.
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	for _, r := range filename {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			fallback.WriteByte('_')
+			continue
+		}
+		fallback.WriteRune(r)
+	}
+
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if fallback.String() == filename {
+		return value
+	}
+
+	var encoded strings.Builder
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+	return value + "; filename*=UTF-8''" + encoded.String()
+}
+
+// Helper function to check whether a byte may appear unencoded in an RFC 5987 value
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import "testing"
+
+func TestContentDispositionEncodesNonASCIINames(t *testing.T) {
+	cases := map[string]string{
+		"report.pdf":   `attachment; filename="report.pdf"`,
+		`say "hi".txt`: `attachment; filename="say _hi_.txt"; filename*=UTF-8''say%20%22hi%22.txt`,
+		"résumé.pdf":   `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`,
+		"数据.csv":       `attachment; filename="__.csv"; filename*=UTF-8''%E6%95%B0%E6%8D%AE.csv`,
+	}
+	for filename, want := range cases {
+		if got := contentDisposition("attachment", filename); got != want {
+			t.Errorf("contentDisposition(%q) = %s, want %s", filename, got, want)
+		}
+	}
+}
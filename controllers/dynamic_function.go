@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// functionMaxDatabaseCalls limits the db calls a single function run can make
+	functionMaxDatabaseCalls = 50
+	// functionMaxFindLimit caps the documents returned by db.find
+	functionMaxFindLimit = 100
+)
+
+// Before and after triggers of each write operation
+var functionTriggers = map[string][2]string{
+	models.BeforeWriteCreate: {models.FunctionTriggerBeforeCreate, models.FunctionTriggerAfterCreate},
+	models.BeforeWriteUpdate: {models.FunctionTriggerBeforeUpdate, models.FunctionTriggerAfterUpdate},
+	models.BeforeWriteDelete: {models.FunctionTriggerBeforeDelete, models.FunctionTriggerAfterDelete},
+}
+
+// functionAPI is the db object functions use to read and write the user's collections.
+// Reads and writes go through the same validation, visibility, history and change notifications
+// as the API, but writes made by functions don't run triggers or hooks again.
+type functionAPI struct {
+	dc     *DynamicAPIController
+	c      *gin.Context
+	ctx    context.Context
+	db     *mongo.Database
+	userID primitive.ObjectID
+	calls  int
+}
+
+// Helper function to convert values to their JSON form, so IDs become strings and numbers
+// exported from JavaScript look like numbers decoded from a request body
+func functionJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Helper function to count a database call and look up a data collection
+func (api *functionAPI) schema(collectionName string) (*models.Schema, error) {
+	api.calls++
+	if api.calls > functionMaxDatabaseCalls {
+		return nil, fmt.Errorf("Function exceeded %d database calls", functionMaxDatabaseCalls)
+	}
+	if err := api.ctx.Err(); err != nil {
+		return nil, utils.ErrFunctionTimeout
+	}
+
+	schema, err := api.dc.getSchemaByCollection(api.userID, collectionName)
+	if err != nil {
+		return nil, errors.New("Schema not found for collection: " + collectionName)
+	}
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		return nil, errors.New("Authentication collections cannot be used from functions")
+	}
+	return schema, nil
+}
+
+// Helper function to refuse writes that would skip the collection's before-write hook or before
+// trigger functions, which writes from functions don't run
+func (api *functionAPI) writable(schema *models.Schema, operation string) error {
+	if err := beforeWriteHookBypassError(schema, operation); err != nil {
+		return err
+	}
+	_, err := beforeTriggerBypassError(schema, operation)
+	return err
+}
+
+// Helper function to check a payload against the field write policies of the request's caller
+// and build the data to store, stamped with the caller's tenant. Updates pass the ID of the
+// changed document.
+func (api *functionAPI) prepareWrite(schema *models.Schema, documentID *primitive.ObjectID, data map[string]interface{}) (map[string]interface{}, error) {
+	if _, err := api.dc.checkFieldWrites(api.c, api.db, api.userID, schema, documentID, data); err != nil {
+		return nil, err
+	}
+	docData, _, err := api.dc.buildDocumentData(api.ctx, api.db, api.userID, schema, data, documentID != nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := api.dc.applyTenant(api.ctx, api.db, api.userID, schema, requestTenant(api.c), docData); err != nil {
+		return nil, err
+	}
+	return docData, nil
+}
+
+// Helper function to build the filter for a document the function changes. Reads see every
+// tenant, writes are limited to the caller's tenant.
+func (api *functionAPI) documentFilter(schema *models.Schema, documentID primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": documentID, "user_id": api.userID}
+	api.dc.applyTenantFilter(api.c, schema, filter)
+	return filter
+}
+
+// Helper function to create a request context with its own query string for the read helpers
+func (api *functionAPI) query(rawQuery string) *gin.Context {
+	ctx := api.c.Copy()
+	ctx.Request = api.c.Request.Clone(api.ctx)
+	ctx.Request.URL.RawQuery = rawQuery
+	ctx.Set("dynamic_rules_bypass", true)
+	return ctx
+}
+
+// Helper function to read a document the way the API returns it
+func (api *functionAPI) load(schema *models.Schema, documentID primitive.ObjectID) (interface{}, error) {
+	document, err := api.dc.loadStreamDocument(api.query(""), api.db, api.userID, schema, documentID)
+	if err != nil {
+		return nil, errors.New("Failed to load document")
+	}
+	if document == nil {
+		return nil, nil
+	}
+	return functionJSONValue(document)
+}
+
+// get returns a document by ID, or null
+func (api *functionAPI) get(collectionName, id string) (interface{}, error) {
+	schema, err := api.schema(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	documentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("Invalid document ID")
+	}
+	return api.load(schema, documentID)
+}
+
+// find lists documents. The query takes the list endpoint parameters, e.g.
+// {"fields": "name,price", "limit": 20, "page": 1}
+func (api *functionAPI) find(collectionName string, query map[string]interface{}) ([]interface{}, error) {
+	schema, err := api.schema(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, fmt.Sprint(value))
+	}
+	ctx := api.query(values.Encode())
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > functionMaxFindLimit {
+		limit = functionMaxFindLimit
+	}
+
+	pipeline, _, err := api.dc.buildListPipeline(ctx, api.db, api.userID, schema)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := api.dc.requestedFields(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	pipeline = append(pipeline,
+		bson.M{"$skip": int64((page - 1) * limit)},
+		bson.M{"$limit": int64(limit)},
+	)
+
+	cursor, err := api.db.Collection(schema.CollectionName).Aggregate(api.ctx, pipeline)
+	if err != nil {
+		return nil, errors.New("Failed to fetch documents")
+	}
+	defer cursor.Close(context.TODO())
+
+	var documents []bson.M
+	if err := cursor.All(api.ctx, &documents); err != nil {
+		return nil, errors.New("Failed to decode documents")
+	}
+
+	locales := api.dc.requestedLocales(ctx)
+	results := make([]interface{}, 0, len(documents))
+	for _, document := range documents {
+		publicData := api.dc.filterPublicFieldsWithRelations(document, schema, api.userID, fieldViewerFor(ctx))
+		api.dc.localizeDocument(publicData, schema, locales)
+		selectDocumentFields(publicData, fields)
+		result, err := functionJSONValue(publicData)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// create validates and inserts a document, returning it as the API would
+func (api *functionAPI) create(collectionName string, data map[string]interface{}) (interface{}, error) {
+	schema, err := api.schema(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.writable(schema, models.BeforeWriteCreate); err != nil {
+		return nil, err
+	}
+
+	docData, err := api.prepareWrite(schema, nil, data)
+	if err != nil {
+		return nil, err
+	}
+
+	document := models.DynamicData{
+		ID:        primitive.NewObjectID(),
+		Data:      docData,
+		UserID:    api.userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		CreatedBy: dynamicAuthUserID(api.c),
+		UpdatedBy: dynamicAuthUserID(api.c),
+	}
+	if schema.Publishable {
+		document.Status = models.DocumentStatusDraft
+	}
+
+	if _, err := api.db.Collection(schema.CollectionName).InsertOne(api.ctx, document); err != nil {
+		return nil, errors.New("Failed to create document")
+	}
+
+	api.dc.recordHistory(api.c, api.db, schema, api.userID, document.ID, models.HistoryActionCreate, nil, document.Data)
+	api.dc.notifyDocumentChange(api.db, api.userID, schema.CollectionName, utils.DocumentEventInsert, document.ID, document.Data)
+	return api.load(schema, document.ID)
+}
+
+// update validates and applies a partial update, returning the updated document
+func (api *functionAPI) update(collectionName, id string, data map[string]interface{}) (interface{}, error) {
+	schema, err := api.schema(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.writable(schema, models.BeforeWriteUpdate); err != nil {
+		return nil, err
+	}
+	documentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("Invalid document ID")
+	}
+
+	fieldData, err := api.prepareWrite(schema, &documentID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	updateData := bson.M{"updated_at": time.Now()}
+	if authUserID := dynamicAuthUserID(api.c); authUserID != nil {
+		updateData["updated_by"] = *authUserID
+	}
+	for key, value := range fieldData {
+		updateData["data."+key] = value
+	}
+
+	filter := api.documentFilter(schema, documentID)
+	var previous bson.M
+	err = api.db.Collection(schema.CollectionName).FindOneAndUpdate(api.ctx, filter, bson.M{"$set": updateData}).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("Document not found")
+		}
+		return nil, errors.New("Failed to update document")
+	}
+
+	var updated bson.M
+	if err := api.db.Collection(schema.CollectionName).FindOne(api.ctx, filter).Decode(&updated); err != nil {
+		return nil, errors.New("Failed to load updated document")
+	}
+	previousData, _ := previous["data"].(bson.M)
+	updatedData, _ := updated["data"].(bson.M)
+	api.dc.recordHistory(api.c, api.db, schema, api.userID, documentID, models.HistoryActionUpdate, previousData, updatedData)
+	api.dc.notifyDocumentChange(api.db, api.userID, schema.CollectionName, utils.DocumentEventUpdate, documentID, updatedData)
+	return api.load(schema, documentID)
+}
+
+// delete removes a document and its files, returning whether it existed
+func (api *functionAPI) delete(collectionName, id string) (bool, error) {
+	schema, err := api.schema(collectionName)
+	if err != nil {
+		return false, err
+	}
+	if err := api.writable(schema, models.BeforeWriteDelete); err != nil {
+		return false, err
+	}
+	documentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("Invalid document ID")
+	}
+
+	var deleted bson.M
+	err = api.db.Collection(schema.CollectionName).FindOneAndDelete(api.ctx, api.documentFilter(schema, documentID)).Decode(&deleted)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, errors.New("Failed to delete document")
+	}
+
+	deletedData, _ := deleted["data"].(bson.M)
+	api.dc.recordHistory(api.c, api.db, schema, api.userID, documentID, models.HistoryActionDelete, deletedData, nil)
+	if schemaHasFieldType(*schema, "file") {
+		api.dc.deleteDocumentFiles(api.db, schema, deleted, nil)
+	}
+	api.dc.notifyDocumentChange(api.db, api.userID, schema.CollectionName, utils.DocumentEventDelete, documentID, deletedData)
+	return true, nil
+}
+
+// Helper function to run a function with the db API bound to the request
+func (dc *DynamicAPIController) runFunction(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, function *models.SchemaFunction, event map[string]interface{}) (interface{}, []string, error) {
+	timeout := function.TimeoutMs
+	if timeout <= 0 {
+		timeout = utils.DefaultFunctionTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+	defer cancel()
+
+	eventValue, err := functionJSONValue(event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	api := &functionAPI{dc: dc, c: c, ctx: ctx, db: db, userID: userID}
+	result, logs, err := utils.RunFunction(ctx, function.Name, function.Code, timeout, eventValue, map[string]interface{}{
+		"get":    api.get,
+		"find":   api.find,
+		"create": api.create,
+		"update": api.update,
+		"delete": api.delete,
+	})
+
+	// Remember the outcome so failing triggers show up in the dashboard
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"last_run_at": now}, "$unset": bson.M{"last_error": ""}}
+	if err != nil {
+		update = bson.M{"$set": bson.M{"last_run_at": now, "last_error": err.Error()}}
+	}
+	config.DB.Collection("functions").UpdateOne(context.TODO(), bson.M{"_id": function.ID}, update)
+
+	if err != nil {
+		return nil, logs, err
+	}
+	if result == nil {
+		return nil, logs, nil
+	}
+	result, err = functionJSONValue(result)
+	return result, logs, err
+}
+
+// Helper function to find the active functions of a schema for a trigger, oldest first
+func findSchemaFunctions(schemaID primitive.ObjectID, trigger string) ([]models.SchemaFunction, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := config.DB.Collection("functions").Find(context.TODO(), bson.M{"schema_id": schemaID, "trigger": trigger, "active": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var functions []models.SchemaFunction
+	if err := cursor.All(context.TODO(), &functions); err != nil {
+		return nil, err
+	}
+	return functions, nil
+}
+
+// Helper function to build the event passed to trigger functions
+func functionTriggerEvent(schema *models.Schema, trigger string, documentID primitive.ObjectID, data, current map[string]interface{}) map[string]interface{} {
+	event := map[string]interface{}{
+		"trigger":    trigger,
+		"collection": schema.CollectionName,
+		"id":         documentID.Hex(),
+	}
+	if data != nil {
+		event["data"] = data
+	}
+	if current != nil {
+		event["current"] = current
+	}
+	return event
+}
+
+// Helper function to run the after trigger of a write in the background. Deleted documents are
+// passed as data. Errors are recorded on the function and don't affect the response.
+func (dc *DynamicAPIController) runAfterTriggers(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, operation string, documentID primitive.ObjectID, data map[string]interface{}) {
+	trigger := functionTriggers[operation][1]
+	ctx := c.Copy()
+
+	go func() {
+		functions, err := findSchemaFunctions(schema.ID, trigger)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load %s functions for %s: %v\n", trigger, schema.CollectionName, err)
+			return
+		}
+		if len(functions) == 0 {
+			return
+		}
+
+		// Updates only know the changed fields, triggers get the stored document
+		if data == nil && operation != models.BeforeWriteDelete {
+			var document bson.M
+			if err := db.Collection(schema.CollectionName).FindOne(context.TODO(), bson.M{"_id": documentID}).Decode(&document); err == nil {
+				data, _ = document["data"].(bson.M)
+			}
+		}
+
+		for i := range functions {
+			event := functionTriggerEvent(schema, trigger, documentID, data, nil)
+			if _, _, err := dc.runFunction(ctx, db, userID, &functions[i], event); err != nil {
+				fmt.Printf("Warning: Function %s on %s failed: %v\n", functions[i].Name, schema.CollectionName, err)
+			}
+		}
+	}()
+}
+
+// Helper function to answer a failed function run
+func functionErrorResponse(c *gin.Context, function *models.SchemaFunction, err error) {
+	var thrown *utils.FunctionError
+	switch {
+	case errors.As(err, &thrown):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": thrown.Message, "function": function.Name})
+	case errors.Is(err, utils.ErrFunctionTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Function " + function.Name + " exceeded its time limit"})
+	case errors.Is(err, utils.ErrFunctionBusy):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Function " + function.Name + " failed: " + err.Error()})
+	}
+}
+
+// @Summary Call collection function
+// @Description Run a custom endpoint function of the collection. The function receives the method, query parameters and JSON body as event and its return value is sent as the response. Errors thrown by the function are returned with status 422.
+// @Tags dynamic-api
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param name path string true "Function name"
+// @Param body body object false "Input passed to the function as event.body"
+// @Success 200 "Function result"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 422 "Error thrown by the function"
+// @Failure 504 "Function exceeded its time limit"
+// @Router /api/{collection}/fn/{name} [post]
+func (dc *DynamicAPIController) CallFunction(c *gin.Context) {
+	collectionName := c.Param("collection")
+	name := c.Param("name")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	var function models.SchemaFunction
+	filter := bson.M{"schema_id": schema.ID, "name": name, "trigger": models.FunctionTriggerEndpoint, "active": true}
+	if err := config.DB.Collection("functions").FindOne(context.TODO(), filter).Decode(&function); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Function not found: " + name})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find function"})
+		}
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	query := make(map[string]interface{})
+	for key, values := range c.Request.URL.Query() {
+		query[key] = values[0]
+	}
+	event := map[string]interface{}{
+		"method":     c.Request.Method,
+		"collection": collectionName,
+		"query":      query,
+	}
+	if c.Request.ContentLength != 0 && c.Request.Method != http.MethodGet {
+		var body interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		event["body"] = body
+	}
+	if authUserID, exists := c.Get("dynamic_auth_user_id"); exists {
+		if id, ok := authUserID.(primitive.ObjectID); ok {
+			event["user_id"] = id.Hex()
+		}
+	}
+
+	result, _, err := dc.runFunction(c, db, userID, &function, event)
+	if err != nil {
+		functionErrorResponse(c, &function, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFunctionWritesCheckFieldPoliciesOfCaller(t *testing.T) {
+	schema := &models.Schema{
+		CollectionName: "orders",
+		Fields: []models.SchemaField{
+			{Name: "note", Type: "string"},
+			{Name: "discount", Type: "number", WritePolicy: models.FieldPolicyServerOnly},
+		},
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	api := &functionAPI{dc: &DynamicAPIController{}, c: c}
+	if _, err := api.prepareWrite(schema, nil, map[string]interface{}{"discount": 50}); err == nil {
+		t.Error("function wrote a server-only field on behalf of an anonymous caller")
+	}
+}
+
+func TestFunctionWritesStampAndScopeCallerTenant(t *testing.T) {
+	schema := &models.Schema{
+		CollectionName: "invoices",
+		TenantField:    "org",
+		Fields: []models.SchemaField{
+			{Name: "org", Type: "string"},
+			{Name: "note", Type: "string"},
+		},
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("dynamic_auth_tenant", "acme")
+	api := &functionAPI{dc: &DynamicAPIController{}, c: c, ctx: context.Background()}
+
+	data, err := api.prepareWrite(schema, nil, map[string]interface{}{"note": "paid", "org": "globex"})
+	if err != nil {
+		t.Fatalf("create was refused: %v", err)
+	}
+	if data["org"] != "acme" {
+		t.Errorf("created document belongs to %v, want the caller's tenant acme", data["org"])
+	}
+
+	documentID := primitive.NewObjectID()
+	filter := api.documentFilter(schema, documentID)
+	if conditions, _ := filter["$and"].(bson.A); len(conditions) != 1 {
+		t.Errorf("update and delete filter %v is not limited to the caller's tenant", filter)
+	}
+
+	anonymous, _ := gin.CreateTestContext(httptest.NewRecorder())
+	api.c = anonymous
+	if _, err := api.prepareWrite(schema, nil, map[string]interface{}{"note": "paid"}); err == nil {
+		t.Error("create without a tenant was allowed on a tenant-scoped collection")
+	}
+}
@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// historyRecordAttempts bounds retries when concurrent writers race for the same version
+const historyRecordAttempts = 3
+
+// Helper function to name the collection holding a collection's change history
+func historyCollectionName(collectionName string) string {
+	return collectionName + "__history"
+}
+
+// Helper function to identify who is making a change
+func (dc *DynamicAPIController) historyActor(c *gin.Context) models.HistoryActor {
+	if authUserID, exists := c.Get("dynamic_auth_user_id"); exists {
+		if id, ok := authUserID.(primitive.ObjectID); ok {
+			return models.HistoryActor{Type: models.HistoryActorUser, ID: id.Hex()}
+		}
+	}
+
+	actor := models.HistoryActor{Type: models.HistoryActorAPIKey}
+	if apiUser, exists := c.Get("api_user"); exists {
+		if user, ok := apiUser.(models.User); ok {
+			actor.ID = user.ID.Hex()
+			actor.Email = user.Email
+		}
+	}
+	return actor
+}
+
+// Helper function to list the fields that differ between two versions of document data
+func diffDocumentData(before, after map[string]interface{}) []models.FieldChange {
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	changes := []models.FieldChange{}
+	for _, field := range names {
+		oldValue, newValue := before[field], after[field]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, models.FieldChange{Field: field, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	return changes
+}
+
+// Helper function to store a history entry for a write. The snapshot holds the data after the
+// change, or the removed data for deletes. Failures are logged and do not fail the write.
+// It returns the recorded version, or 0 when nothing was recorded.
+func (dc *DynamicAPIController) recordHistory(c *gin.Context, db *mongo.Database, schema *models.Schema, userID, documentID primitive.ObjectID, action models.HistoryAction, before, after map[string]interface{}) int {
+	if !schema.History {
+		return 0
+	}
+
+	snapshot := after
+	if action == models.HistoryActionDelete {
+		snapshot = before
+	}
+
+	entry := models.DocumentHistory{
+		DocumentID: documentID,
+		Action:     action,
+		Snapshot:   snapshot,
+		Diff:       diffDocumentData(before, after),
+		Actor:      dc.historyActor(c),
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+	}
+
+	collection := db.Collection(historyCollectionName(schema.CollectionName))
+	var err error
+	for attempt := 0; attempt < historyRecordAttempts; attempt++ {
+		var latest models.DocumentHistory
+		findOptions := options.FindOne().SetSort(bson.M{"version": -1}).SetProjection(bson.M{"version": 1})
+		latestErr := collection.FindOne(context.TODO(), bson.M{"document_id": documentID}, findOptions).Decode(&latest)
+		if latestErr != nil && latestErr != mongo.ErrNoDocuments {
+			err = latestErr
+			break
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.Version = latest.Version + 1
+		if _, err = collection.InsertOne(context.TODO(), entry); err == nil {
+			return entry.Version
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+
+	fmt.Printf("Warning: Failed to record history for %s/%s: %v\n", schema.CollectionName, documentID.Hex(), err)
+	return 0
+}
+
+// Helper function to find which of the given GridFS files are still referenced by history snapshots
+func (dc *DynamicAPIController) historyFileIDs(db *mongo.Database, schema *models.Schema, fileIDs map[primitive.ObjectID]bool) map[primitive.ObjectID]bool {
+	referenced := make(map[primitive.ObjectID]bool)
+	if !schema.History || len(fileIDs) == 0 {
+		return referenced
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(fileIDs))
+	for fileID := range fileIDs {
+		ids = append(ids, fileID)
+	}
+
+	collection := db.Collection(historyCollectionName(schema.CollectionName))
+	for _, field := range schema.Fields {
+		if field.Type != "file" {
+			continue
+		}
+
+		key := "snapshot." + field.Name + ".file_id"
+		values, err := collection.Distinct(context.TODO(), key, bson.M{key: bson.M{"$in": ids}})
+		if err != nil {
+			// Keep every file when history cannot be checked rather than break a version
+			fmt.Printf("Warning: Failed to check history file references for %s: %v\n", schema.CollectionName, err)
+			return fileIDs
+		}
+		for _, value := range values {
+			if fileID, ok := value.(primitive.ObjectID); ok {
+				referenced[fileID] = true
+			}
+		}
+	}
+	return referenced
+}
+
+// Helper function to decode a history entry with nested documents as maps, so snapshots and
+// diffs serialize to plain JSON objects
+func decodeHistoryEntry(raw bson.Raw) (models.DocumentHistory, error) {
+	var entry models.DocumentHistory
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return entry, err
+	}
+	decoder.DefaultDocumentM()
+	err = decoder.Decode(&entry)
+	return entry, err
+}
+
+// Helper function to hide the fields the viewer may not read from a history entry. The
+// document carries the owner that owner fields are checked against.
+func (dc *DynamicAPIController) publicHistoryEntry(schema *models.Schema, entry models.DocumentHistory, viewer fieldViewer, document bson.M) models.DocumentHistory {
+	public := make(map[string]bool)
+	for _, field := range schema.Fields {
+		if viewer.canRead(&field, document) {
+			public[field.Name] = true
+		}
+	}
+
+	snapshot := make(map[string]interface{})
+	for key, value := range entry.Snapshot {
+		if public[key] {
+			snapshot[key] = value
+		}
+	}
+	entry.Snapshot = snapshot
+
+	diff := []models.FieldChange{}
+	for _, change := range entry.Diff {
+		if public[change.Field] {
+			diff = append(diff, change)
+		}
+	}
+	entry.Diff = diff
+
+	return entry
+}
+
+// @Summary Get document history
+// @Description Get the change history of a document, newest version first
+// @Tags dynamic-api
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/history [get]
+func (dc *DynamicAPIController) GetDocumentHistory(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	if !schema.History {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "History is not enabled for collection: " + collectionName})
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	skip := (page - 1) * limit
+
+	// With a read rule, owner-only reads or tenants the history is only shown for documents the
+	// caller can read
+	if accessRule(schema, models.AccessRead) != nil || ownerOnly(c, schema, models.AccessRead) || tenantScoped(c, schema) {
+		documentFilter := dc.documentFilter(c, schema, models.AccessRead, documentID, userID)
+		if err := dc.applyReadRule(c, db, schema, "data", documentFilter); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply read rule: " + err.Error()})
+			return
+		}
+		if count, err := db.Collection(collectionName).CountDocuments(context.TODO(), documentFilter); err != nil || count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No history found for document"})
+			return
+		}
+	}
+
+	filter := bson.M{"document_id": documentID, "user_id": userID}
+	findOptions := options.Find().
+		SetSort(bson.M{"version": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	collection := db.Collection(historyCollectionName(collectionName))
+	cursor, err := collection.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var entries []models.DocumentHistory
+	for cursor.Next(context.TODO()) {
+		entry, err := decodeHistoryEntry(cursor.Current)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode history"})
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	total, err := collection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		total = 0
+	}
+
+	if total == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No history found for document"})
+		return
+	}
+
+	// Owner fields are shown to the creator of the document
+	viewer := fieldViewerFor(c)
+	var document bson.M
+	for _, field := range schema.Fields {
+		if utils.FieldReadPolicy(&field) == models.FieldPolicyOwner {
+			findOptions := options.FindOne().SetProjection(bson.M{"created_by": 1})
+			db.Collection(collectionName).FindOne(context.TODO(), bson.M{"_id": documentID, "user_id": userID}, findOptions).Decode(&document)
+			break
+		}
+	}
+
+	publicEntries := make([]models.DocumentHistory, 0, len(entries))
+	for _, entry := range entries {
+		publicEntries = append(publicEntries, dc.publicHistoryEntry(schema, entry, viewer, document))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": publicEntries,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// @Summary Revert document to a version
+// @Description Restore the data of a document from a history version. Deleted documents are recreated. File fields are restored to the files of that version. Protected like document updates.
+// @Tags dynamic-api
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param version path int true "History version"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/history/{version}/revert [post]
+func (dc *DynamicAPIController) RevertDocument(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	if !schema.History {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "History is not enabled for collection: " + collectionName})
+		return
+	}
+
+	// Reverts recreate or overwrite the document without calling the before-write hook or trigger functions
+	for _, operation := range []string{models.BeforeWriteCreate, models.BeforeWriteUpdate} {
+		if err := beforeWriteHookBypassError(schema, operation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if status, err := beforeTriggerBypassError(schema, operation); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	historyCollection := db.Collection(historyCollectionName(collectionName))
+	raw, err := historyCollection.FindOne(context.TODO(), bson.M{"document_id": documentID, "user_id": userID, "version": version}).DecodeBytes()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "History version not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	entry, err := decodeHistoryEntry(raw)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode history"})
+		return
+	}
+
+	// Files referenced by history are kept in GridFS, so file fields are restored with the rest
+	restored := make(map[string]interface{})
+	for key, value := range entry.Snapshot {
+		restored[key] = value
+	}
+
+	collection := db.Collection(collectionName)
+	filter := bson.M{"_id": documentID, "user_id": userID}
+
+	var current bson.M
+	err = collection.FindOne(context.TODO(), filter).Decode(&current)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	currentData, _ := current["data"].(bson.M)
+	if current != nil && (!dc.ownsDocument(c, schema, models.AccessUpdate, current) || !dc.sameTenant(c, schema, currentData)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	now := time.Now()
+	var before map[string]interface{}
+
+	if err == mongo.ErrNoDocuments {
+		// Restoring a deleted document creates it again
+		if allowed := dc.writeRule(c, db, schema, models.AccessCreate); allowed != nil && !allowed(documentID, nil, restored) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied by create rule"})
+			return
+		}
+
+		// The document was deleted, recreate it with its original creation date and owner when known
+		createdAt := now
+		createdBy := dynamicAuthUserID(c)
+		var first models.DocumentHistory
+		if err := historyCollection.FindOne(context.TODO(), bson.M{"document_id": documentID, "version": 1}).Decode(&first); err == nil {
+			createdAt = first.CreatedAt
+			createdBy = nil
+			if id, err := primitive.ObjectIDFromHex(first.Actor.ID); err == nil && first.Actor.Type == models.HistoryActorUser {
+				createdBy = &id
+			}
+		}
+
+		// Owner-only collections only let owners bring their documents back
+		owner := bson.M{}
+		if createdBy != nil {
+			owner["created_by"] = *createdBy
+		}
+		if !dc.ownsDocument(c, schema, models.AccessUpdate, owner) || !dc.sameTenant(c, schema, restored) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		if err := fieldViewerFor(c).checkChangedFields(schema, nil, restored, owner); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		document := models.DynamicData{
+			ID:        documentID,
+			Data:      restored,
+			UserID:    userID,
+			CreatedAt: createdAt,
+			UpdatedAt: now,
+			CreatedBy: createdBy,
+			UpdatedBy: dynamicAuthUserID(c),
+		}
+		if schema.Publishable {
+			document.Status = models.DocumentStatusDraft
+		}
+
+		if _, err := collection.InsertOne(context.TODO(), document); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore document"})
+			return
+		}
+	} else {
+		before = map[string]interface{}(currentData)
+
+		if err := fieldViewerFor(c).checkChangedFields(schema, currentData, restored, current); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		if allowed := dc.writeRule(c, db, schema, models.AccessUpdate); allowed != nil && !allowed(documentID, currentData, restored) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied by update rule"})
+			return
+		}
+
+		fields := bson.M{"data": restored, "updated_at": now}
+		if authUserID := dynamicAuthUserID(c); authUserID != nil {
+			fields["updated_by"] = *authUserID
+		}
+		update := bson.M{"$set": fields}
+		if _, err := collection.UpdateOne(context.TODO(), filter, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert document"})
+			return
+		}
+
+		// Remove replaced files unless the published version still serves them
+		keep := dc.referencedFileIDs(schema, bson.M(restored))
+		if publishedData, ok := current["published_data"].(bson.M); ok {
+			for fileID := range dc.referencedFileIDs(schema, publishedData) {
+				keep[fileID] = true
+			}
+		}
+		dc.deleteDocumentFiles(db, schema, bson.M{"data": currentData}, keep)
+	}
+
+	newVersion := dc.recordHistory(c, db, schema, userID, documentID, models.HistoryActionRevert, before, restored)
+	if current == nil {
+		dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventInsert, documentID, restored)
+	} else {
+		dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventUpdate, documentID, restored)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       fmt.Sprintf("Document reverted to version %d", version),
+		"reverted_from": version,
+		"version":       newVersion,
+		"updated_at":    now,
+	})
+}
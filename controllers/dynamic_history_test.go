@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+func TestDiffDocumentDataListsChangedFields(t *testing.T) {
+	before := map[string]interface{}{"title": "Draft", "views": 1, "tags": []interface{}{"a"}}
+	after := map[string]interface{}{"title": "Final", "views": 1, "summary": "New"}
+
+	want := []models.FieldChange{
+		{Field: "summary", OldValue: nil, NewValue: "New"},
+		{Field: "tags", OldValue: []interface{}{"a"}, NewValue: nil},
+		{Field: "title", OldValue: "Draft", NewValue: "Final"},
+	}
+	if got := diffDocumentData(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffDocumentData = %v, want %v", got, want)
+	}
+}
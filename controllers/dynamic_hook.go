@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Helper function to let the schema's before triggers and before-write hook approve a write.
+// Both see the request payload and may return a replacement, which is validated and checked
+// against field write policies and the write rule like a request.
+// It returns the prepared data to write; after an error response has been written the second
+// result is false.
+func (dc *DynamicAPIController) runBeforeWrite(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, operation string, documentID primitive.ObjectID, payload, prepared map[string]interface{}) (map[string]interface{}, bool) {
+	trigger := functionTriggers[operation][0]
+	functions, err := findSchemaFunctions(schema.ID, trigger)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load functions"})
+		return nil, false
+	}
+
+	hook := schema.BeforeWrite
+	hookRuns := utils.BeforeWriteHookRunsFor(hook, operation)
+	if len(functions) == 0 && !hookRuns {
+		return prepared, true
+	}
+
+	var current map[string]interface{}
+	if operation != models.BeforeWriteCreate {
+		var found bool
+		if current, found = dc.loadDocumentData(c, db, schema.CollectionName, dc.documentFilter(c, schema, operation, documentID, userID)); !found {
+			return nil, false
+		}
+	}
+
+	// Replacements go through the same field write policies and write rule as the request
+	var target *primitive.ObjectID
+	ruleOperation := models.AccessCreate
+	if operation == models.BeforeWriteUpdate {
+		target = &documentID
+		ruleOperation = models.AccessUpdate
+	}
+
+	// Helper to validate a replacement payload
+	replace := func(replacement map[string]interface{}, source string) bool {
+		if status, err := dc.checkFieldWrites(c, db, userID, schema, target, replacement); err != nil {
+			c.JSON(status, gin.H{"error": source + " returned invalid data: " + err.Error()})
+			return false
+		}
+		data, status, err := dc.buildDocumentData(context.TODO(), db, userID, schema, replacement, operation == models.BeforeWriteUpdate)
+		if err != nil {
+			c.JSON(status, gin.H{"error": source + " returned invalid data: " + err.Error()})
+			return false
+		}
+		// Replacements can't move the document to another tenant
+		if status, err := dc.applyTenant(context.TODO(), db, userID, schema, requestTenant(c), data); err != nil {
+			c.JSON(status, gin.H{"error": source + " returned invalid data: " + err.Error()})
+			return false
+		}
+		if !dc.checkWriteRule(c, db, userID, schema, ruleOperation, documentID, data) {
+			return false
+		}
+		payload, prepared = replacement, data
+		return true
+	}
+
+	for i := range functions {
+		event := functionTriggerEvent(schema, trigger, documentID, payload, current)
+		result, _, err := dc.runFunction(c, db, userID, &functions[i], event)
+		if err != nil {
+			functionErrorResponse(c, &functions[i], err)
+			return nil, false
+		}
+		if replacement, ok := result.(map[string]interface{}); ok && operation != models.BeforeWriteDelete {
+			if !replace(replacement, "Function "+functions[i].Name) {
+				return nil, false
+			}
+		}
+	}
+
+	if !hookRuns {
+		return prepared, true
+	}
+
+	response, err := utils.CallBeforeWriteHook(c.Request.Context(), hook, operation, schema.CollectionName, documentID, payload, current)
+	if err != nil {
+		if hook.FailOpen {
+			fmt.Printf("Warning: Before-write hook for %s failed, continuing: %v\n", schema.CollectionName, err)
+			return prepared, true
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Before-write hook failed: " + err.Error()})
+		return nil, false
+	}
+
+	if response.Allow != nil && !*response.Allow {
+		message := response.Message
+		if message == "" {
+			message = "Write rejected by before-write hook"
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": message})
+		return nil, false
+	}
+
+	if response.Data != nil && operation != models.BeforeWriteDelete {
+		if !replace(response.Data, "Before-write hook") {
+			return nil, false
+		}
+	}
+	return prepared, true
+}
+
+// Helper function to refuse writes through endpoints that cannot call the schema's before-write
+// hook, so the hook can't be bypassed. Such writes have to use the document endpoints.
+func beforeWriteHookBypassError(schema *models.Schema, operation string) error {
+	if !utils.BeforeWriteHookRunsFor(schema.BeforeWrite, operation) {
+		return nil
+	}
+	return fmt.Errorf("Collection %s has a before-write hook for %s, use the document endpoints instead", schema.CollectionName, operation)
+}
+
+// Helper function to refuse writes through endpoints that cannot run the schema's before trigger
+// functions, so they can't be bypassed either
+func beforeTriggerBypassError(schema *models.Schema, operation string) (int, error) {
+	trigger := functionTriggers[operation][0]
+	functions, err := findSchemaFunctions(schema.ID, trigger)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Failed to load %s functions", trigger)
+	}
+	if len(functions) > 0 {
+		return http.StatusBadRequest, fmt.Errorf("Collection %s has %s functions, use the document endpoints instead", schema.CollectionName, trigger)
+	}
+	return http.StatusOK, nil
+}
+
+// Helper function to load the stored data of a document for hooks, triggers and rules. After an error
+// response has been written the second result is false.
+func (dc *DynamicAPIController) loadDocumentData(c *gin.Context, db *mongo.Database, collectionName string, filter bson.M) (map[string]interface{}, bool) {
+	var document bson.M
+	err := db.Collection(collectionName).FindOne(context.TODO(), filter).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	data, _ := document["data"].(bson.M)
+	return data, true
+}
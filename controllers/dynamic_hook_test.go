@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+func hookedSchema(operations ...string) *models.Schema {
+	return &models.Schema{
+		CollectionName: "orders",
+		BeforeWrite:    &models.BeforeWriteHook{URL: "https://hooks.example.com/orders", Operations: operations},
+	}
+}
+
+func TestTransactionRefusesCollectionsWithBeforeWriteHook(t *testing.T) {
+	schema := hookedSchema()
+	for _, action := range []string{models.TransactionActionCreate, models.TransactionActionUpdate, models.TransactionActionDelete} {
+		if err := transactionSchemaError(schema, action); err == nil {
+			t.Errorf("%s was allowed on a collection with a before-write hook", action)
+		}
+	}
+}
+
+func TestTransactionAllowsActionsTheHookIgnores(t *testing.T) {
+	schema := hookedSchema(models.BeforeWriteDelete)
+	if err := transactionSchemaError(schema, models.TransactionActionCreate); err != nil {
+		t.Errorf("create was refused although the hook only runs on deletes: %v", err)
+	}
+	if err := transactionSchemaError(schema, models.TransactionActionDelete); err == nil {
+		t.Error("delete was allowed although the hook runs on deletes")
+	}
+	if err := transactionSchemaError(&models.Schema{CollectionName: "orders"}, models.TransactionActionUpdate); err != nil {
+		t.Errorf("update was refused on a collection without hook: %v", err)
+	}
+}
+
+func TestImportRefusesCollectionsWithCreateHook(t *testing.T) {
+	if err := importSchemaError(hookedSchema(models.BeforeWriteCreate)); err == nil {
+		t.Error("import was allowed on a collection with a before-create hook")
+	}
+	if err := importSchemaError(hookedSchema(models.BeforeWriteUpdate)); err != nil {
+		t.Errorf("import was refused although the hook doesn't run on creates: %v", err)
+	}
+	if err := importSchemaError(&models.Schema{CollectionName: "orders"}); err != nil {
+		t.Errorf("import was refused on a collection without hook: %v", err)
+	}
+}
+
+func TestFunctionWritesRefuseCollectionsWithBeforeWriteHook(t *testing.T) {
+	api := &functionAPI{}
+	for _, operation := range []string{models.BeforeWriteCreate, models.BeforeWriteUpdate, models.BeforeWriteDelete} {
+		if err := api.writable(hookedSchema(operation), operation); err == nil {
+			t.Errorf("function %s was allowed on a collection with a before-write hook", operation)
+		}
+	}
+}
@@ -0,0 +1,764 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxImportSize is the largest file accepted for import
+	maxImportSize int64 = 50 << 20
+	// importSyncMaxSize is the largest file imported within the request, bigger files run as a job
+	importSyncMaxSize int64 = 1 << 20
+	// importBatchSize is the number of documents written per InsertMany
+	importBatchSize = 500
+	// maxImportErrors caps the row errors kept in a report
+	maxImportErrors = 1000
+)
+
+// importRowError marks a problem with a single row, the import continues with the next one
+type importRowError struct {
+	err error
+}
+
+func (e *importRowError) Error() string {
+	return e.err.Error()
+}
+
+// importRowReader yields the rows of an import file. Next returns io.EOF after the last row,
+// an *importRowError for a bad row and any other error when the file cannot be read further.
+type importRowReader interface {
+	Next() (map[string]interface{}, error)
+}
+
+// countingReader tracks how much of the input has been consumed for progress reporting
+type countingReader struct {
+	reader io.Reader
+	count  atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+// csvImportReader maps CSV columns to schema fields and converts cells to the field types
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns []*models.SchemaField
+}
+
+// Helper function to read the CSV header and resolve the column mapping
+func newCSVImportReader(r io.Reader, schema *models.Schema, mapping map[string]string) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("CSV file is empty")
+		}
+		return nil, errors.New("Failed to read CSV header: " + err.Error())
+	}
+
+	fieldsByName := make(map[string]*models.SchemaField)
+	for i := range schema.Fields {
+		fieldsByName[strings.ToLower(schema.Fields[i].Name)] = &schema.Fields[i]
+	}
+
+	for column, fieldName := range mapping {
+		if _, ok := fieldsByName[strings.ToLower(fieldName)]; !ok {
+			return nil, fmt.Errorf("Mapping for column %q targets unknown field: %s", column, fieldName)
+		}
+	}
+
+	columns := make([]*models.SchemaField, len(header))
+	mapped := 0
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+
+		fieldName := column
+		if mapping != nil {
+			target, ok := mapping[column]
+			if !ok {
+				continue
+			}
+			fieldName = target
+		}
+
+		if field, ok := fieldsByName[strings.ToLower(fieldName)]; ok {
+			columns[i] = field
+			mapped++
+		}
+	}
+
+	if mapped == 0 {
+		return nil, errors.New("No CSV column matches a schema field, provide a mapping")
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) Next() (map[string]interface{}, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &importRowError{err: err}
+		}
+		return nil, err
+	}
+
+	if len(record) != len(r.columns) {
+		return nil, &importRowError{err: fmt.Errorf("expected %d columns, got %d", len(r.columns), len(record))}
+	}
+
+	row := make(map[string]interface{})
+	for i, cell := range record {
+		field := r.columns[i]
+		if field == nil || cell == "" {
+			// Empty cells are treated as missing so required and default rules apply
+			continue
+		}
+
+		value, err := csvFieldValue(*field, cell)
+		if err != nil {
+			return nil, &importRowError{err: err}
+		}
+		row[field.Name] = value
+	}
+	return row, nil
+}
+
+// Helper function to convert a CSV cell to the value type of its field
+func csvFieldValue(field models.SchemaField, cell string) (interface{}, error) {
+	switch field.Type {
+	case "number":
+		number, err := strconv.ParseFloat(strings.TrimSpace(cell), 64)
+		if err != nil {
+			return nil, errors.New("Field " + field.Name + " must be a number")
+		}
+		return number, nil
+	case "boolean":
+		boolean, err := strconv.ParseBool(strings.TrimSpace(cell))
+		if err != nil {
+			return nil, errors.New("Field " + field.Name + " must be true or false")
+		}
+		return boolean, nil
+	case "object", "array":
+		var value interface{}
+		if err := json.Unmarshal([]byte(cell), &value); err != nil {
+			return nil, errors.New("Field " + field.Name + " must contain valid JSON")
+		}
+		return value, nil
+	}
+	return cell, nil
+}
+
+// jsonArrayImportReader streams the objects of a JSON array
+type jsonArrayImportReader struct {
+	decoder *json.Decoder
+	started bool
+}
+
+func (r *jsonArrayImportReader) Next() (map[string]interface{}, error) {
+	if !r.started {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, errors.New("Invalid JSON: " + err.Error())
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("JSON import must be an array of objects")
+		}
+		r.started = true
+	}
+
+	if !r.decoder.More() {
+		return nil, io.EOF
+	}
+
+	var value interface{}
+	if err := r.decoder.Decode(&value); err != nil {
+		return nil, errors.New("Invalid JSON: " + err.Error())
+	}
+
+	row, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, &importRowError{err: errors.New("row must be a JSON object")}
+	}
+	return row, nil
+}
+
+// ndjsonImportReader reads one JSON object per line
+type ndjsonImportReader struct {
+	reader *bufio.Reader
+}
+
+func (r *ndjsonImportReader) Next() (map[string]interface{}, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+
+		var row map[string]interface{}
+		if jsonErr := json.Unmarshal(line, &row); jsonErr != nil {
+			return nil, &importRowError{err: errors.New("invalid JSON object: " + jsonErr.Error())}
+		}
+		return row, nil
+	}
+}
+
+// Helper function to check that documents may be imported into a collection. Imports don't call
+// before-write hooks, so collections using one for creates are refused.
+func importSchemaError(schema *models.Schema) error {
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		return errors.New("Users of authentication collections must sign up individually")
+	}
+	return beforeWriteHookBypassError(schema, models.BeforeWriteCreate)
+}
+
+// Helper function to check that JSON values match their field types
+func validateImportFieldTypes(schema *models.Schema, row map[string]interface{}) error {
+	for _, field := range schema.Fields {
+		value, ok := row[field.Name]
+		if !ok || value == nil {
+			continue
+		}
+
+		valid := true
+		switch field.Type {
+		case "string":
+			_, isString := value.(string)
+			_, isMap := value.(map[string]interface{})
+			valid = isString || (field.Localized && isMap)
+		case "number":
+			_, valid = value.(float64)
+		case "boolean":
+			_, valid = value.(bool)
+		case "object":
+			_, valid = value.(map[string]interface{})
+		case "array":
+			_, valid = value.([]interface{})
+		case "date":
+			_, err := utils.ParseDate(value)
+			valid = err == nil
+		}
+
+		if !valid {
+			return fmt.Errorf("Field %s must be of type %s", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// importResult collects the outcome of an import
+type importResult struct {
+	processed int
+	imported  int
+	failed    int
+	errors    []models.ImportRowError
+	truncated bool
+}
+
+func (r *importResult) addError(row int, err error) {
+	r.failed++
+	if len(r.errors) >= maxImportErrors {
+		r.truncated = true
+		return
+	}
+	r.errors = append(r.errors, models.ImportRowError{Row: row, Error: err.Error()})
+}
+
+// Helper function to validate rows and insert them in batches. Rows are checked against the
+// field write policies of the viewer and the create rule when there is one, and stamped with
+// the tenant of tenant-scoped collections. The progress callback is called after every batch.
+func (dc *DynamicAPIController) runImport(db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, reader importRowReader, viewer fieldViewer, tenant string, createRule writeRuleCheck, progress func(*importResult)) (*importResult, error) {
+	result := &importResult{errors: []models.ImportRowError{}}
+	collection := db.Collection(schema.CollectionName)
+
+	var batch []interface{}
+	var batchRows []int
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		_, err := collection.InsertMany(context.TODO(), batch, options.InsertMany().SetOrdered(false))
+		failedIndexes := make(map[int]bool)
+		if err != nil {
+			var bulkErr mongo.BulkWriteException
+			if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+				return err
+			}
+			for _, writeErr := range bulkErr.WriteErrors {
+				failedIndexes[writeErr.Index] = true
+				result.addError(batchRows[writeErr.Index], errors.New(writeErr.Message))
+			}
+		}
+
+		result.imported += len(batch) - len(failedIndexes)
+		for i, document := range batch {
+			if !failedIndexes[i] {
+				inserted := document.(models.DynamicData)
+				dc.notifyDocumentChange(db, userID, schema.CollectionName, utils.DocumentEventInsert, inserted.ID, inserted.Data)
+			}
+		}
+		batch = batch[:0]
+		batchRows = batchRows[:0]
+
+		if progress != nil {
+			progress(result)
+		}
+		return nil
+	}
+
+	for row := 1; ; row++ {
+		data, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var rowErr *importRowError
+		if errors.As(err, &rowErr) {
+			result.processed++
+			result.addError(row, rowErr.err)
+			continue
+		}
+		if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				return result, flushErr
+			}
+			return result, err
+		}
+
+		result.processed++
+
+		if err := validateImportFieldTypes(schema, data); err != nil {
+			result.addError(row, err)
+			continue
+		}
+
+		if err := viewer.checkCreateFields(schema, data); err != nil {
+			result.addError(row, err)
+			continue
+		}
+
+		docData, _, err := dc.buildDocumentData(context.TODO(), db, userID, schema, data, false)
+		if err != nil {
+			result.addError(row, err)
+			continue
+		}
+		if _, err := dc.applyTenant(context.TODO(), db, userID, schema, tenant, docData); err != nil {
+			result.addError(row, err)
+			continue
+		}
+
+		documentID := primitive.NewObjectID()
+		if createRule != nil && !createRule(documentID, nil, docData) {
+			result.addError(row, errors.New("Access denied by create rule"))
+			continue
+		}
+
+		now := time.Now()
+		document := models.DynamicData{
+			ID:        documentID,
+			Data:      docData,
+			UserID:    userID,
+			CreatedAt: now,
+			UpdatedAt: now,
+			CreatedBy: viewer.userID,
+			UpdatedBy: viewer.userID,
+		}
+		if schema.Publishable {
+			document.Status = models.DocumentStatusDraft
+		}
+
+		batch = append(batch, document)
+		batchRows = append(batchRows, row)
+
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, flush()
+}
+
+// Helper function to pick the import format from the query, file name or content type
+func detectImportFormat(format, filename, contentType string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".csv":
+			format = "csv"
+		case ".json":
+			format = "json"
+		case ".ndjson", ".jsonl":
+			format = "ndjson"
+		}
+	}
+
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch mediaType {
+		case "text/csv", "application/csv":
+			format = "csv"
+		case "application/json":
+			format = "json"
+		case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+			format = "ndjson"
+		}
+	}
+
+	switch format {
+	case "csv", "json", "ndjson":
+		return format, nil
+	case "":
+		return "", errors.New("Could not detect the import format, pass format=csv, json or ndjson")
+	}
+	return "", errors.New("Format must be one of: csv, json, ndjson")
+}
+
+// Helper function to create the row reader for a format
+func newImportRowReader(format string, r io.Reader, schema *models.Schema, mapping map[string]string) (importRowReader, error) {
+	switch format {
+	case "csv":
+		return newCSVImportReader(r, schema, mapping)
+	case "json":
+		return &jsonArrayImportReader{decoder: json.NewDecoder(r)}, nil
+	default:
+		return &ndjsonImportReader{reader: bufio.NewReader(r)}, nil
+	}
+}
+
+// @Summary Import documents
+// @Description Import a CSV, JSON array or NDJSON file into a collection. Rows are validated against the schema and failures are reported per row. Files over 1 MB, or requests with async=true, run as a background job.
+// @Tags dynamic-api
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param file formData file false "File to import, the raw request body is used when omitted"
+// @Param format query string false "csv, json or ndjson (detected from the file name or content type)"
+// @Param mapping query string false "CSV column mapping as JSON, e.g. {\"Full Name\":\"name\"}"
+// @Param async query bool false "Run as a background job"
+// @Success 200 "Import finished"
+// @Success 202 "Import job started"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 413 "Request Entity Too Large"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/import [post]
+func (dc *DynamicAPIController) ImportDocuments(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	if err := importSchemaError(schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := beforeTriggerBypassError(schema, models.BeforeWriteCreate); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Read the upload, either as multipart file or as raw body
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+(1<<20))
+
+	var content []byte
+	filename := ""
+	contentType := c.ContentType()
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import file exceeds the maximum size of %d bytes", maxImportSize)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Multipart form field 'file' is required"})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		content, err = io.ReadAll(io.LimitReader(file, maxImportSize+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		filename = fileHeader.Filename
+		contentType = fileHeader.Header.Get("Content-Type")
+	} else {
+		content, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import file exceeds the maximum size of %d bytes", maxImportSize)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+	}
+
+	if int64(len(content)) > maxImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import file exceeds the maximum size of %d bytes", maxImportSize)})
+		return
+	}
+	if len(content) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import file is empty"})
+		return
+	}
+
+	format, err := detectImportFormat(strings.ToLower(c.DefaultQuery("format", c.PostForm("format"))), filename, contentType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var mapping map[string]string
+	if rawMapping := c.DefaultQuery("mapping", c.PostForm("mapping")); rawMapping != "" {
+		if format != "csv" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A column mapping is only supported for CSV imports"})
+			return
+		}
+		if err := json.Unmarshal([]byte(rawMapping), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mapping must be a JSON object of column names to field names"})
+			return
+		}
+	}
+
+	// Check the header before accepting the file so mapping mistakes are reported right away
+	input := &countingReader{reader: bytes.NewReader(content)}
+	reader, err := newImportRowReader(format, input, schema, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user's database. Background jobs outlive the request, so the import owns its connection.
+	db, err := dc.connectUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	createRule := dc.writeRule(c, db, schema, models.AccessCreate)
+
+	if c.Query("async") != "true" && int64(len(content)) <= importSyncMaxSize {
+		defer db.Client().Disconnect(context.TODO())
+
+		result, err := dc.runImport(db, userID, schema, reader, fieldViewerFor(c), requestTenant(c), createRule, nil)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     "Import stopped: " + err.Error(),
+				"processed": result.processed,
+				"imported":  result.imported,
+				"failed":    result.failed,
+				"errors":    result.errors,
+			})
+			return
+		}
+
+		go LogActivityWithContext(c, userID, models.ActivityTypeCreate, fmt.Sprintf("Imported %d documents into \"%s\"", result.imported, collectionName), "Bulk import finished", "collection", collectionName, map[string]any{
+			"format":   format,
+			"imported": result.imported,
+			"failed":   result.failed,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Import finished",
+			"processed":        result.processed,
+			"imported":         result.imported,
+			"failed":           result.failed,
+			"errors":           result.errors,
+			"errors_truncated": result.truncated,
+		})
+		return
+	}
+
+	// Large imports run in the background and report progress through the job
+	now := time.Now()
+	job := models.ImportJob{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		Collection: collectionName,
+		Format:     format,
+		Status:     models.ImportStatusPending,
+		TotalBytes: int64(len(content)),
+		Errors:     []models.ImportRowError{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err := config.DB.Collection("import_jobs").InsertOne(context.TODO(), job); err != nil {
+		db.Client().Disconnect(context.TODO())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import job"})
+		return
+	}
+
+	go dc.runImportJob(db, userID, schema, job.ID, reader, input, fieldViewerFor(c), requestTenant(c), createRule)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Import started",
+		"job_id":     job.ID.Hex(),
+		"status":     job.Status,
+		"status_url": "/api/" + collectionName + "/import/" + job.ID.Hex(),
+	})
+}
+
+// Helper function to run an import job and keep its progress up to date
+func (dc *DynamicAPIController) runImportJob(db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, jobID primitive.ObjectID, reader importRowReader, input *countingReader, viewer fieldViewer, tenant string, createRule writeRuleCheck) {
+	defer db.Client().Disconnect(context.TODO())
+
+	jobs := config.DB.Collection("import_jobs")
+	updateJob := func(fields bson.M) {
+		fields["updated_at"] = time.Now()
+		if _, err := jobs.UpdateOne(context.TODO(), bson.M{"_id": jobID}, bson.M{"$set": fields}); err != nil {
+			fmt.Printf("Warning: Failed to update import job %s: %v\n", jobID.Hex(), err)
+		}
+	}
+	resultFields := func(result *importResult) bson.M {
+		return bson.M{
+			"processed_bytes":  input.count.Load(),
+			"processed_rows":   result.processed,
+			"imported_rows":    result.imported,
+			"failed_rows":      result.failed,
+			"errors":           result.errors,
+			"errors_truncated": result.truncated,
+		}
+	}
+
+	updateJob(bson.M{"status": models.ImportStatusRunning})
+
+	result, err := dc.runImport(db, userID, schema, reader, viewer, tenant, createRule, func(progress *importResult) {
+		updateJob(resultFields(progress))
+	})
+
+	fields := resultFields(result)
+	completedAt := time.Now()
+	fields["completed_at"] = completedAt
+	if err != nil {
+		fields["status"] = models.ImportStatusFailed
+		fields["error"] = err.Error()
+	} else {
+		fields["status"] = models.ImportStatusCompleted
+	}
+	updateJob(fields)
+
+	LogActivity(userID, models.ActivityTypeCreate, fmt.Sprintf("Imported %d documents into \"%s\"", result.imported, schema.CollectionName), "Bulk import job finished", "collection", schema.CollectionName, map[string]any{
+		"job_id":   jobID.Hex(),
+		"imported": result.imported,
+		"failed":   result.failed,
+	})
+}
+
+// @Summary Get import job
+// @Description Get the progress and row errors of an asynchronous import
+// @Tags dynamic-api
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param job_id path string true "Import job ID"
+// @Success 200 {object} models.ImportJob
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Router /api/{collection}/import/{job_id} [get]
+func (dc *DynamicAPIController) GetImportJob(c *gin.Context) {
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	jobID, err := primitive.ObjectIDFromHex(c.Param("job_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	var job models.ImportJob
+	filter := bson.M{"_id": jobID, "user_id": apiUserID.(primitive.ObjectID), "collection": c.Param("collection")}
+	if err := config.DB.Collection("import_jobs").FindOne(context.TODO(), filter).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	progress := 0.0
+	if job.TotalBytes > 0 {
+		progress = float64(job.ProcessedBytes) / float64(job.TotalBytes) * 100
+	}
+	if job.Status == models.ImportStatusCompleted {
+		progress = 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":      job,
+		"progress": progress,
+	})
+}
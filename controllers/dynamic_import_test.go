@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+func TestDetectImportFormatPrefersQueryThenFileThenContentType(t *testing.T) {
+	cases := []struct {
+		format, filename, contentType string
+		want                          string
+	}{
+		{"ndjson", "people.csv", "text/csv", "ndjson"},
+		{"", "people.JSONL", "text/csv", "ndjson"},
+		{"", "upload", "text/csv; charset=utf-8", "csv"},
+		{"", "", "application/json", "json"},
+	}
+	for _, tc := range cases {
+		if got, err := detectImportFormat(tc.format, tc.filename, tc.contentType); err != nil || got != tc.want {
+			t.Errorf("detectImportFormat(%q, %q, %q) = %q, %v, want %q", tc.format, tc.filename, tc.contentType, got, err, tc.want)
+		}
+	}
+
+	if _, err := detectImportFormat("", "data.bin", "application/octet-stream"); err == nil {
+		t.Error("unknown format was accepted")
+	}
+}
+
+func TestCSVImportReaderMapsColumnsAndConvertsValues(t *testing.T) {
+	schema := &models.Schema{Fields: []models.SchemaField{
+		{Name: "name", Type: "string"},
+		{Name: "age", Type: "number"},
+		{Name: "active", Type: "boolean"},
+	}}
+	input := "Full Name,age,active,ignored\nAda,36,true,x\nBob,,false,y\nEve,old,true,z\n"
+
+	reader, err := newCSVImportReader(strings.NewReader(input), schema, map[string]string{"Full Name": "name", "age": "age", "active": "active"})
+	if err != nil {
+		t.Fatalf("CSV header was refused: %v", err)
+	}
+
+	row, err := reader.Next()
+	if err != nil || !reflect.DeepEqual(row, map[string]interface{}{"name": "Ada", "age": 36.0, "active": true}) {
+		t.Errorf("first row = %v, %v", row, err)
+	}
+	row, err = reader.Next()
+	if err != nil || !reflect.DeepEqual(row, map[string]interface{}{"name": "Bob", "active": false}) {
+		t.Errorf("empty cells were not skipped: %v, %v", row, err)
+	}
+	var rowErr *importRowError
+	if _, err = reader.Next(); !errors.As(err, &rowErr) {
+		t.Errorf("invalid number returned %v, want a row error", err)
+	}
+	if _, err = reader.Next(); err != io.EOF {
+		t.Errorf("reader did not stop at the end: %v", err)
+	}
+}
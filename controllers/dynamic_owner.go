@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Helper function to get the dynamic auth user making a request, nil for API key requests
+func dynamicAuthUserID(c *gin.Context) *primitive.ObjectID {
+	authUserID, exists := c.Get("dynamic_auth_user_id")
+	if !exists {
+		return nil
+	}
+	id, ok := authUserID.(primitive.ObjectID)
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// Helper function to check whether an operation is limited to the caller's own documents.
+// Server-side code such as functions is not limited, like with access rules.
+func ownerOnly(c *gin.Context, schema *models.Schema, operation string) bool {
+	return !rulesBypassed(c) && utils.DynamicEndpointOwnerOnly(schema, operation)
+}
+
+// Helper function to restrict filters to documents created by the caller when the schema
+// limits the operation to owners
+func (dc *DynamicAPIController) applyOwnerFilter(c *gin.Context, schema *models.Schema, operation string, filters ...bson.M) {
+	if !ownerOnly(c, schema, operation) {
+		return
+	}
+
+	var condition interface{} = bson.M{"$in": bson.A{}}
+	if owner := dynamicAuthUserID(c); owner != nil {
+		condition = *owner
+	}
+	for _, filter := range filters {
+		filter["created_by"] = condition
+	}
+}
+
+// Helper function to build the filter for a single document the caller may use for an operation
+func (dc *DynamicAPIController) documentFilter(c *gin.Context, schema *models.Schema, operation string, documentID, userID primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": documentID, "user_id": userID}
+	dc.applyOwnerFilter(c, schema, operation, filter)
+	dc.applyTenantFilter(c, schema, filter)
+	return filter
+}
+
+// Helper function to check whether the caller may use a loaded document for an operation
+func (dc *DynamicAPIController) ownsDocument(c *gin.Context, schema *models.Schema, operation string, document bson.M) bool {
+	if !ownerOnly(c, schema, operation) {
+		return true
+	}
+	owner := dynamicAuthUserID(c)
+	createdBy, ok := document["created_by"].(primitive.ObjectID)
+	return owner != nil && ok && createdBy == *owner
+}
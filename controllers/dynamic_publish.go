@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Helper function to check whether the caller may see drafts. Only requests that passed
+// dynamic authentication on a protected endpoint count as editors, tokens that were only
+// read for access rules don't.
+func (dc *DynamicAPIController) canViewDrafts(c *gin.Context) bool {
+	_, authenticated := c.Get("dynamic_auth_user_id")
+	return authenticated && !c.GetBool("dynamic_auth_optional")
+}
+
+// Helper function to restrict list and detail filters to what the caller may see.
+// It returns true when the published snapshot has to be served instead of the draft.
+func (dc *DynamicAPIController) applyPublishFilters(c *gin.Context, schema *models.Schema, filters ...bson.M) (bool, error) {
+	if !schema.Publishable {
+		return false, nil
+	}
+
+	now := time.Now()
+	var condition bson.M
+
+	if dc.canViewDrafts(c) {
+		// Editors see everything and may narrow the list down by status
+		switch c.Query("status") {
+		case "":
+			return false, nil
+		case models.DocumentStatusDraft:
+			condition = bson.M{"published_data": bson.M{"$exists": false}}
+		case models.DocumentStatusPublished:
+			condition = bson.M{"published_data": bson.M{"$exists": true}, "published_at": bson.M{"$lte": now}}
+		case models.DocumentStatusScheduled:
+			condition = bson.M{"published_data": bson.M{"$exists": true}, "published_at": bson.M{"$gt": now}}
+		default:
+			return false, errors.New("status must be one of: draft, published, scheduled")
+		}
+	} else {
+		condition = bson.M{"published_data": bson.M{"$exists": true}, "published_at": bson.M{"$lte": now}}
+	}
+
+	for _, filter := range filters {
+		for key, value := range condition {
+			filter[key] = value
+		}
+	}
+
+	return !dc.canViewDrafts(c), nil
+}
+
+// Helper function to compute the editorial status of a stored document
+func documentStatus(document bson.M) string {
+	if _, ok := document["published_data"]; !ok {
+		return models.DocumentStatusDraft
+	}
+
+	publishedAt, ok := document["published_at"].(primitive.DateTime)
+	if ok && publishedAt.Time().After(time.Now()) {
+		return models.DocumentStatusScheduled
+	}
+	return models.DocumentStatusPublished
+}
+
+// @Summary Publish document
+// @Description Publish the current draft of a document. Pass publish_at to schedule the publication. Protected like document updates.
+// @Tags dynamic-api
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Param request body models.PublishRequest false "Publication options"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/publish [post]
+func (dc *DynamicAPIController) PublishDocument(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	if !schema.Publishable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Collection is not publishable: " + collectionName})
+		return
+	}
+
+	// Publishing changes the document without calling the before-write hook or trigger functions
+	if err := beforeWriteHookBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := beforeTriggerBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The body is optional, an empty request publishes immediately
+	var req models.PublishRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	publishAt := time.Now()
+	if req.PublishAt != nil {
+		publishAt = *req.PublishAt
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	// Publishing changes the document, so it follows the update rule
+	if !dc.checkWriteRule(c, db, userID, schema, models.AccessUpdate, documentID, nil) {
+		return
+	}
+
+	// Copy the draft into the published snapshot in a single update
+	filter := dc.documentFilter(c, schema, models.AccessUpdate, documentID, userID)
+	update := []bson.M{
+		{"$set": bson.M{
+			"published_data": "$data",
+			"published_at":   publishAt,
+			"status":         models.DocumentStatusPublished,
+		}},
+	}
+
+	var previous bson.M
+	err = db.Collection(collectionName).FindOneAndUpdate(context.TODO(), filter, update).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish document"})
+		}
+		return
+	}
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventUpdate, documentID, nil)
+
+	// Files only referenced by the replaced snapshot are no longer reachable
+	if schemaHasFieldType(*schema, "file") {
+		dataMap, _ := previous["data"].(bson.M)
+		dc.deleteDocumentFiles(db, schema, bson.M{"published_data": previous["published_data"]}, dc.referencedFileIDs(schema, dataMap))
+	}
+
+	status := models.DocumentStatusPublished
+	if publishAt.After(time.Now()) {
+		status = models.DocumentStatusScheduled
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Document published successfully",
+		"status":       status,
+		"published_at": publishAt,
+	})
+}
+
+// @Summary Unpublish document
+// @Description Withdraw the published version of a document. The draft is kept. Protected like document updates.
+// @Tags dynamic-api
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Document ID"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/{id}/unpublish [post]
+func (dc *DynamicAPIController) UnpublishDocument(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+	documentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	if !schema.Publishable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Collection is not publishable: " + collectionName})
+		return
+	}
+
+	// Publishing changes the document without calling the before-write hook or trigger functions
+	if err := beforeWriteHookBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := beforeTriggerBypassError(schema, models.BeforeWriteUpdate); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	if !dc.checkWriteRule(c, db, userID, schema, models.AccessUpdate, documentID, nil) {
+		return
+	}
+
+	filter := dc.documentFilter(c, schema, models.AccessUpdate, documentID, userID)
+	update := bson.M{
+		"$unset": bson.M{"published_data": "", "published_at": ""},
+		"$set":   bson.M{"status": models.DocumentStatusDraft},
+	}
+
+	var previous bson.M
+	err = db.Collection(collectionName).FindOneAndUpdate(context.TODO(), filter, update).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish document"})
+		}
+		return
+	}
+	dc.notifyDocumentChange(db, userID, collectionName, utils.DocumentEventUpdate, documentID, nil)
+
+	if schemaHasFieldType(*schema, "file") {
+		dataMap, _ := previous["data"].(bson.M)
+		dc.deleteDocumentFiles(db, schema, bson.M{"published_data": previous["published_data"]}, dc.referencedFileIDs(schema, dataMap))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Document unpublished successfully",
+		"status":  models.DocumentStatusDraft,
+	})
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// documentMetaFields are response keys that can be selected besides schema fields
+var documentMetaFields = map[string]bool{
+	"id":           true,
+	"created_at":   true,
+	"updated_at":   true,
+	"created_by":   true,
+	"updated_by":   true,
+	"status":       true,
+	"published_at": true,
+	"expires_at":   true,
+	"distance":     true,
+}
+
+// Helper function to find a field the caller may read, for field selection with fields=
+func readableSchemaField(c *gin.Context, schema *models.Schema, name string) *models.SchemaField {
+	viewer := fieldViewerFor(c)
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		if field.Name == name && viewer.mayReadSome(field) {
+			return field
+		}
+	}
+	return nil
+}
+
+// Helper function to read the fields=a,b query parameter. A nil map means all fields.
+func (dc *DynamicAPIController) requestedFields(c *gin.Context, schema *models.Schema) (map[string]bool, error) {
+	fieldsParam := strings.TrimSpace(c.Query("fields"))
+	if fieldsParam == "" {
+		return nil, nil
+	}
+
+	fields := map[string]bool{"id": true}
+	for _, name := range strings.Split(fieldsParam, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !documentMetaFields[name] && readableSchemaField(c, schema, name) == nil {
+			return nil, errors.New("Unknown field: " + name)
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// Helper function to drop the keys that were not requested with fields=
+func selectDocumentFields(document map[string]interface{}, fields map[string]bool) {
+	if fields == nil {
+		return
+	}
+	for key := range document {
+		if !fields[key] {
+			delete(document, key)
+		}
+	}
+}
+
+// Helper function to build the list pipeline shared by GetDocuments and ExportDocuments.
+// It applies the publish, read rule and geo filters and returns the count filter alongside.
+func (dc *DynamicAPIController) buildListPipeline(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema) ([]bson.M, bson.M, error) {
+	// Limit drafts to editors
+	matchFilter := bson.M{"user_id": userID}
+	countFilter := bson.M{"user_id": userID}
+	publishedView, err := dc.applyPublishFilters(c, schema, matchFilter, countFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey := "data"
+	if publishedView {
+		dataKey = "published_data"
+	}
+
+	// Leave out documents the read rule hides, other tenants' documents and, for owner-only
+	// reads, other users' documents
+	if err := dc.applyReadRule(c, db, schema, dataKey, matchFilter, countFilter); err != nil {
+		return nil, nil, err
+	}
+	dc.applyOwnerFilter(c, schema, models.AccessRead, matchFilter, countFilter)
+	dc.applyTenantFilter(c, schema, matchFilter, countFilter)
+
+	// Apply geospatial filters
+	geoNearStage, err := dc.applyGeoFilters(c, schema, dataKey, matchFilter, countFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create aggregation pipeline with population
+	var pipeline []bson.M
+	if geoNearStage != nil {
+		// $geoNear has to be the first stage and already sorts by distance
+		pipeline = dc.appendPopulationStages(c, userID, schema, []bson.M{geoNearStage}, publishedView)
+	} else {
+		pipeline = dc.createPopulationPipeline(c, userID, schema, matchFilter, publishedView)
+		pipeline = append(pipeline, bson.M{"$sort": bson.M{"created_at": -1}})
+	}
+
+	return pipeline, countFilter, nil
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestedFieldsOnlyAllowsPublicFields(t *testing.T) {
+	schema := &models.Schema{Fields: []models.SchemaField{
+		{Name: "title", Type: "string", Visibility: "public"},
+		{Name: "secret", Type: "string", Visibility: "private"},
+	}}
+	dc := &DynamicAPIController{}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/posts/export?fields=title,created_at", nil)
+	fields, err := dc.requestedFields(c, schema)
+	if err != nil {
+		t.Fatalf("public fields were refused: %v", err)
+	}
+
+	document := map[string]interface{}{"id": "1", "title": "Hello", "created_at": "now", "updated_at": "later"}
+	selectDocumentFields(document, fields)
+	if want := map[string]interface{}{"id": "1", "title": "Hello", "created_at": "now"}; !reflect.DeepEqual(document, want) {
+		t.Errorf("selected document = %v, want %v", document, want)
+	}
+
+	private, _ := gin.CreateTestContext(httptest.NewRecorder())
+	private.Request = httptest.NewRequest("GET", "/api/posts/export?fields=secret", nil)
+	if _, err := dc.requestedFields(private, schema); err == nil {
+		t.Error("private field was selectable")
+	}
+}
@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// realtimeMaxSubscriptions caps the subscriptions of one connection
+	realtimeMaxSubscriptions = 100
+	// realtimeMaxMessageSize caps the size of client messages
+	realtimeMaxMessageSize = 64 << 10
+	// realtimeMaxCachedDocuments caps the documents remembered per subscription to compute diffs
+	realtimeMaxCachedDocuments = 1000
+	// realtimePingInterval is how often the server pings idle clients
+	realtimePingInterval = 30 * time.Second
+	// realtimePongWait is how long a client may stay silent before the connection is closed
+	realtimePongWait = 2 * realtimePingInterval
+	// realtimeWriteWait bounds a single write to the client
+	realtimeWriteWait = 10 * time.Second
+)
+
+// Sources of realtime events reported to clients
+const (
+	realtimeSourceChangeStream = "change_stream"
+	realtimeSourceLocal        = "local"
+)
+
+// Clients authenticate with the API key rather than cookies, so any origin may connect
+var realtimeUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// realtimeSubscription is one collection or document watched over a realtime connection
+type realtimeSubscription struct {
+	id         string
+	schema     *models.Schema
+	documentID primitive.ObjectID
+	// ctx carries the query, headers and dynamic auth of the subscription for the shared read helpers
+	ctx *gin.Context
+	// documents holds the last state sent for each document, used to send diffs
+	documents map[primitive.ObjectID]map[string]interface{}
+	// delivered holds the documents sent to the client, only their deletes are forwarded
+	delivered deliveredDocuments
+}
+
+// realtimeConnection multiplexes the subscriptions of one WebSocket client
+type realtimeConnection struct {
+	dc     *DynamicAPIController
+	conn   *websocket.Conn
+	base   *gin.Context
+	db     *mongo.Database
+	userID primitive.ObjectID
+
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	authorization string
+	subscriptions map[string]*realtimeSubscription
+}
+
+// Helper function to tell realtime subscribers and webhooks about a document change made through the API.
+// data is the stored document data when the caller already has it, nil loads it for webhooks.
+func (dc *DynamicAPIController) notifyDocumentChange(db *mongo.Database, userID primitive.ObjectID, collectionName, eventType string, documentID primitive.ObjectID, data map[string]interface{}) {
+	utils.DocumentEvents.Publish(utils.DocumentEvent{
+		UserID:     userID,
+		Collection: collectionName,
+		Type:       eventType,
+		DocumentID: documentID,
+	})
+
+	webhookEvent := eventType
+	if eventType == utils.DocumentEventInsert {
+		webhookEvent = models.WebhookEventCreate
+	}
+	triggerWebhooks(db, userID, collectionName, webhookEvent, documentID, data)
+}
+
+// Helper function to compare the public state of two document versions.
+// Values are compared in their JSON form so IDs and dates compare by value.
+func diffRealtimeDocuments(previous, current map[string]interface{}) (map[string]interface{}, []string) {
+	changes := make(map[string]interface{})
+	for key, value := range current {
+		oldValue, ok := previous[key]
+		if ok {
+			oldJSON, _ := json.Marshal(oldValue)
+			newJSON, _ := json.Marshal(value)
+			if string(oldJSON) == string(newJSON) {
+				continue
+			}
+		}
+		changes[key] = value
+	}
+
+	removed := []string{}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	return changes, removed
+}
+
+func (rc *realtimeConnection) send(message gin.H) error {
+	rc.writeMu.Lock()
+	defer rc.writeMu.Unlock()
+
+	rc.conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+	return rc.conn.WriteJSON(message)
+}
+
+func (rc *realtimeConnection) sendError(id string, err error) {
+	message := gin.H{"type": "error", "error": err.Error()}
+	if id != "" {
+		message["id"] = id
+	}
+	rc.send(message)
+}
+
+// Helper function to create a subscription, validating its query the same way as the list endpoint
+func (rc *realtimeConnection) subscribe(message models.RealtimeClientMessage) (*realtimeSubscription, map[string]interface{}, error) {
+	if message.ID == "" {
+		return nil, nil, errors.New("Subscription id is required")
+	}
+	if message.Collection == "" {
+		return nil, nil, errors.New("Collection is required")
+	}
+
+	rc.mu.Lock()
+	_, duplicate := rc.subscriptions[message.ID]
+	count := len(rc.subscriptions)
+	authorization := rc.authorization
+	rc.mu.Unlock()
+
+	if duplicate {
+		return nil, nil, errors.New("Subscription id already in use: " + message.ID)
+	}
+	if count >= realtimeMaxSubscriptions {
+		return nil, nil, fmt.Errorf("A connection can have at most %d subscriptions", realtimeMaxSubscriptions)
+	}
+
+	schema, err := rc.dc.getSchemaByCollection(rc.userID, message.Collection)
+	if err != nil {
+		return nil, nil, errors.New("Schema not found for collection: " + message.Collection)
+	}
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		return nil, nil, errors.New("Authentication collections cannot be subscribed to")
+	}
+
+	if _, err := url.ParseQuery(message.Query); err != nil {
+		return nil, nil, errors.New("Invalid query: " + err.Error())
+	}
+
+	// Each subscription gets its own request so the read helpers see its query string
+	ctx := rc.base.Copy()
+	ctx.Request = rc.base.Request.Clone(context.Background())
+	ctx.Request.URL.RawQuery = message.Query
+
+	// Protected collections require a dynamic auth token, like GET requests. Read rules can use
+	// the signed-in user, so a token is also read when one is sent.
+	requiresAuth := utils.DynamicEndpointRequiresAuth(schema, models.AccessRead) || utils.DynamicEndpointOwnerOnly(schema, models.AccessRead)
+	if requiresAuth || (utils.AccessRuleFor(schema, models.AccessRead) != "" && authorization != "") {
+		claims, _, err := utils.ValidateDynamicAuthHeader(rc.userID, schema, authorization)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !utils.HasAnyRole(claims.Roles, utils.DynamicEndpointRoles(schema, models.AccessRead)) {
+			return nil, nil, errors.New("Insufficient role")
+		}
+		if schema.TenantField != "" && claims.Tenant == "" {
+			return nil, nil, errors.New("Token has no tenant")
+		}
+		ctx.Set("dynamic_auth_user_id", claims.SchemaUserID)
+		ctx.Set("dynamic_auth_schema_id", claims.SchemaID)
+		ctx.Set("dynamic_auth_collection", claims.Collection)
+		ctx.Set("dynamic_auth_roles", claims.Roles)
+		ctx.Set("dynamic_auth_tenant", claims.Tenant)
+		if !requiresAuth {
+			ctx.Set("dynamic_auth_optional", true)
+		}
+	}
+
+	if _, err := rc.dc.applyPublishFilters(ctx, schema, bson.M{}); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rc.dc.applyGeoFilters(ctx, schema, "data", bson.M{}, bson.M{}); err != nil {
+		return nil, nil, err
+	}
+
+	subscription := &realtimeSubscription{
+		id:        message.ID,
+		schema:    schema,
+		ctx:       ctx,
+		documents: make(map[primitive.ObjectID]map[string]interface{}),
+		delivered: deliveredDocuments{},
+	}
+
+	// Document subscriptions start with the current state of the document
+	var document map[string]interface{}
+	if message.DocumentID != "" {
+		subscription.documentID, err = primitive.ObjectIDFromHex(message.DocumentID)
+		if err != nil {
+			return nil, nil, errors.New("Invalid document ID")
+		}
+
+		document, err = rc.dc.loadStreamDocument(ctx, rc.db, rc.userID, schema, subscription.documentID)
+		if err != nil {
+			return nil, nil, errors.New("Failed to load document")
+		}
+		if document == nil {
+			return nil, nil, errors.New("Document not found")
+		}
+		subscription.documents[subscription.documentID] = document
+		subscription.delivered.add(subscription.documentID)
+	}
+
+	return subscription, document, nil
+}
+
+// Helper function to handle one message from the client
+func (rc *realtimeConnection) handleMessage(data []byte) {
+	var message models.RealtimeClientMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		rc.sendError("", errors.New("Invalid message: "+err.Error()))
+		return
+	}
+
+	switch message.Type {
+	case models.RealtimeMessagePing:
+		rc.send(gin.H{"type": "pong"})
+
+	case models.RealtimeMessageAuth:
+		rc.mu.Lock()
+		rc.authorization = ""
+		if message.Token != "" {
+			rc.authorization = "Bearer " + strings.TrimPrefix(message.Token, "Bearer ")
+		}
+		rc.mu.Unlock()
+		rc.send(gin.H{"type": "authenticated"})
+
+	case models.RealtimeMessageSubscribe:
+		subscription, document, err := rc.subscribe(message)
+		if err != nil {
+			rc.sendError(message.ID, err)
+			return
+		}
+
+		rc.mu.Lock()
+		rc.subscriptions[subscription.id] = subscription
+		rc.mu.Unlock()
+
+		response := gin.H{"type": "subscribed", "id": subscription.id}
+		if document != nil {
+			response["document"] = document
+		}
+		rc.send(response)
+
+	case models.RealtimeMessageUnsubscribe:
+		rc.mu.Lock()
+		_, ok := rc.subscriptions[message.ID]
+		delete(rc.subscriptions, message.ID)
+		rc.mu.Unlock()
+
+		if !ok {
+			rc.sendError(message.ID, errors.New("Unknown subscription: "+message.ID))
+			return
+		}
+		rc.send(gin.H{"type": "unsubscribed", "id": message.ID})
+
+	default:
+		rc.sendError(message.ID, errors.New("Unknown message type: "+message.Type))
+	}
+}
+
+// Helper function to forward one document event to the subscriptions watching it
+func (rc *realtimeConnection) handleEvent(event utils.DocumentEvent) {
+	rc.mu.Lock()
+	var subscriptions []*realtimeSubscription
+	for _, subscription := range rc.subscriptions {
+		if subscription.schema.CollectionName != event.Collection {
+			continue
+		}
+		if !subscription.documentID.IsZero() && subscription.documentID != event.DocumentID {
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	rc.mu.Unlock()
+
+	for _, subscription := range subscriptions {
+		message := gin.H{"subscription": subscription.id, "id": event.DocumentID.Hex()}
+
+		if event.Type == utils.DocumentEventDelete {
+			delete(subscription.documents, event.DocumentID)
+			if !subscription.delivered.take(event.DocumentID) {
+				// Deleted documents cannot be checked against the visibility rules of the subscription
+				continue
+			}
+			message["type"] = utils.DocumentEventDelete
+			if err := rc.send(message); err != nil {
+				return
+			}
+			continue
+		}
+
+		document, err := rc.dc.loadStreamDocument(subscription.ctx, rc.db, rc.userID, subscription.schema, event.DocumentID)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load changed document %s in %s: %v\n", event.DocumentID.Hex(), event.Collection, err)
+			continue
+		}
+
+		previous, known := subscription.documents[event.DocumentID]
+		switch {
+		case document == nil && !known && !subscription.delivered[event.DocumentID]:
+			// The document is not visible to this subscription
+			continue
+		case document == nil:
+			// The document no longer matches the filters or visibility rules
+			delete(subscription.documents, event.DocumentID)
+			subscription.delivered.take(event.DocumentID)
+			message["type"] = "remove"
+		case known:
+			changes, removed := diffRealtimeDocuments(previous, document)
+			if len(changes) == 0 && len(removed) == 0 {
+				continue
+			}
+			subscription.documents[event.DocumentID] = document
+			message["type"] = utils.DocumentEventUpdate
+			message["changes"] = changes
+			message["removed"] = removed
+		default:
+			if len(subscription.documents) >= realtimeMaxCachedDocuments {
+				// Forget an arbitrary document, it will be sent in full on its next change
+				for id := range subscription.documents {
+					delete(subscription.documents, id)
+					break
+				}
+			}
+			subscription.documents[event.DocumentID] = document
+			subscription.delivered.add(event.DocumentID)
+			message["type"] = event.Type
+			message["document"] = document
+		}
+
+		if err := rc.send(message); err != nil {
+			return
+		}
+	}
+}
+
+// Helper function to turn change stream events of the user's database into document events
+func (rc *realtimeConnection) watchChanges(ctx context.Context, events chan<- utils.DocumentEvent) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType": bson.M{"$in": []string{"insert", "update", "replace", "delete"}},
+			"$or": []bson.M{
+				{"fullDocument.user_id": rc.userID},
+				{"operationType": "delete"},
+			},
+		}}},
+	}
+
+	stream, err := rc.db.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.TODO())
+
+	for stream.Next(ctx) {
+		var change struct {
+			OperationType string `bson:"operationType"`
+			Namespace     struct {
+				Collection string `bson:"coll"`
+			} `bson:"ns"`
+			DocumentKey struct {
+				ID primitive.ObjectID `bson:"_id"`
+			} `bson:"documentKey"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			continue
+		}
+
+		eventType := change.OperationType
+		if eventType == "replace" {
+			eventType = utils.DocumentEventUpdate
+		}
+
+		select {
+		case events <- utils.DocumentEvent{UserID: rc.userID, Collection: change.Namespace.Collection, Type: eventType, DocumentID: change.DocumentKey.ID}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// @Summary Realtime WebSocket
+// @Description Open a WebSocket carrying many subscriptions to collections, single documents and filtered queries.
+// @Description Client messages: {"type":"subscribe","id":"s1","collection":"posts","document_id":"...","query":"status=draft&within=52.3,13.1,52.7,13.8"}, {"type":"unsubscribe","id":"s1"}, {"type":"auth","token":"<jwt>"} and {"type":"ping"}.
+// @Description Server messages: subscribed, unsubscribed, insert, update (with changes and removed fields), delete (only for documents sent on the subscription), remove (no longer matches), pong and error.
+// @Description Uses MongoDB change streams on replica sets and in-process events from API writes otherwise. Browsers may pass api_key and access_token as query parameters.
+// @Tags dynamic-api
+// @Security ApiKeyAuth
+// @Param api_key query string false "API key, for clients that cannot set headers"
+// @Param access_token query string false "Dynamic auth token for protected collections"
+// @Success 101 "Switching Protocols"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Router /api/ws [get]
+func (dc *DynamicAPIController) Realtime(c *gin.Context) {
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	useChangeStreams, err := config.SupportsTransactions(db)
+	if err != nil {
+		fmt.Printf("Warning: Failed to inspect MongoDB deployment, using local events: %v\n", err)
+	}
+
+	conn, err := realtimeUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	rc := &realtimeConnection{
+		dc:            dc,
+		conn:          conn,
+		base:          c,
+		db:            db,
+		userID:        userID,
+		subscriptions: make(map[string]*realtimeSubscription),
+	}
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		rc.authorization = authorization
+	} else if token := c.Query("access_token"); token != "" {
+		rc.authorization = "Bearer " + token
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Pick the event source, change streams also see writes made outside the API
+	var events <-chan utils.DocumentEvent
+	source := realtimeSourceLocal
+	if useChangeStreams {
+		source = realtimeSourceChangeStream
+		streamEvents := make(chan utils.DocumentEvent, 256)
+		events = streamEvents
+		go func() {
+			if err := rc.watchChanges(ctx, streamEvents); err != nil {
+				fmt.Printf("Warning: Realtime change stream stopped: %v\n", err)
+				rc.sendError("", errors.New("Change stream closed"))
+			}
+			cancel()
+		}()
+	} else {
+		localEvents, unsubscribe := utils.DocumentEvents.Subscribe(userID)
+		defer unsubscribe()
+		events = localEvents
+	}
+
+	// Read client messages until the connection closes
+	conn.SetReadLimit(realtimeMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	})
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+			rc.handleMessage(data)
+		}
+	}()
+
+	if err := rc.send(gin.H{"type": "connected", "source": source}); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(realtimePingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			rc.handleEvent(event)
+		case <-ticker.C:
+			rc.writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(realtimeWriteWait))
+			rc.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffRealtimeDocumentsComparesValuesAsJSON(t *testing.T) {
+	id := primitive.NewObjectID()
+	updated := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	previous := map[string]interface{}{"id": id, "title": "Draft", "tags": []interface{}{"a"}, "summary": "Old"}
+	current := map[string]interface{}{"id": id, "title": "Final", "tags": []string{"a"}, "updated_at": updated}
+
+	changes, removed := diffRealtimeDocuments(previous, current)
+	if want := map[string]interface{}{"title": "Final", "updated_at": updated}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if want := []string{"summary"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Helper function to parse the rule guarding an operation, nil when the operation is open.
+// Rules are validated when the schema is saved, a rule that no longer parses denies access.
+func accessRule(schema *models.Schema, operation string) *utils.Rule {
+	source := utils.AccessRuleFor(schema, operation)
+	if source == "" {
+		return nil
+	}
+	rule, err := utils.ParseRule(source)
+	if err != nil {
+		fmt.Printf("Warning: Invalid %s rule on %s: %v\n", operation, schema.CollectionName, err)
+		rule, _ = utils.ParseRule("false")
+	}
+	return rule
+}
+
+// Helper function to check whether access rules are skipped, functions read and write with
+// the rules bypassed like any other server-side code
+func rulesBypassed(c *gin.Context) bool {
+	return c.GetBool("dynamic_rules_bypass")
+}
+
+// Helper function to build the auth variable of a rule: null without a token, otherwise the
+// token's user id, collection, roles and tenant plus the user's profile when the rule reads other fields
+func (dc *DynamicAPIController) ruleAuth(c *gin.Context, db *mongo.Database, rule *utils.Rule) interface{} {
+	authUserID, exists := c.Get("dynamic_auth_user_id")
+	if !exists {
+		return nil
+	}
+	schemaUserID := authUserID.(primitive.ObjectID)
+
+	auth := map[string]interface{}{}
+	if rule.ReferencesAuthProfile() {
+		for key, value := range dc.authProfile(c, db, schemaUserID) {
+			auth[key] = value
+		}
+	}
+	auth["id"] = schemaUserID.Hex()
+	auth["collection"] = c.GetString("dynamic_auth_collection")
+	auth["roles"] = utils.RuleValue(c.GetStringSlice("dynamic_auth_roles"))
+	auth["tenant"] = requestTenant(c)
+	return auth
+}
+
+// Helper function to load the signed-in user's fields without the password. The profile is
+// loaded once per request.
+func (dc *DynamicAPIController) authProfile(c *gin.Context, db *mongo.Database, schemaUserID primitive.ObjectID) map[string]interface{} {
+	if cached, exists := c.Get("dynamic_auth_profile"); exists {
+		return cached.(map[string]interface{})
+	}
+
+	profile := map[string]interface{}{}
+	defer func() { c.Set("dynamic_auth_profile", profile) }()
+
+	apiUserID, _ := c.Get("api_user_id")
+	authSchemaID, _ := c.Get("dynamic_auth_schema_id")
+
+	var authSchema models.Schema
+	filter := bson.M{"_id": authSchemaID, "user_id": apiUserID}
+	if err := config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&authSchema); err != nil || authSchema.AuthConfig == nil {
+		return profile
+	}
+
+	userCollection := authSchema.AuthConfig.UserCollection
+	if userCollection == "" {
+		userCollection = authSchema.CollectionName + "_users"
+	}
+
+	var user bson.M
+	if err := db.Collection(userCollection).FindOne(context.TODO(), bson.M{"_id": schemaUserID}).Decode(&user); err != nil {
+		return profile
+	}
+	delete(user, "_id")
+	delete(user, authSchema.AuthConfig.PasswordField)
+
+	if value, ok := utils.RuleValue(user).(map[string]interface{}); ok {
+		profile = value
+	}
+	return profile
+}
+
+// Helper function to build the doc and data variables of a rule from stored or prepared data
+func ruleDocument(documentID primitive.ObjectID, data map[string]interface{}) map[string]interface{} {
+	document, ok := utils.RuleValue(data).(map[string]interface{})
+	if !ok {
+		document = map[string]interface{}{}
+	}
+	document["id"] = documentID.Hex()
+	return document
+}
+
+// Helper function to apply validated update data to stored data. Keys like "title.en" set a
+// single locale of a localized field.
+func ruleMergedData(current, changes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(current)+len(changes))
+	for key, value := range current {
+		merged[key] = value
+	}
+
+	for key, value := range changes {
+		name, locale, nested := strings.Cut(key, ".")
+		if !nested {
+			merged[key] = value
+			continue
+		}
+
+		translations := map[string]interface{}{}
+		switch existing := merged[name].(type) {
+		case bson.M:
+			for k, v := range existing {
+				translations[k] = v
+			}
+		case map[string]interface{}:
+			for k, v := range existing {
+				translations[k] = v
+			}
+		}
+		translations[locale] = value
+		merged[name] = translations
+	}
+	return merged
+}
+
+// Helper function to convert an ID compared in a rule to the stored ObjectID
+func ruleObjectID(value interface{}) interface{} {
+	if hex, ok := value.(string); ok {
+		if id, err := primitive.ObjectIDFromHex(hex); err == nil {
+			return id
+		}
+	}
+	return value
+}
+
+// Helper function to convert a date compared in a rule to the stored date
+func ruleDate(value interface{}) interface{} {
+	if _, ok := value.(string); ok {
+		if date, err := utils.ParseDate(value); err == nil {
+			return date
+		}
+	}
+	return value
+}
+
+// Helper function to add the read rule to list and detail filters, documents the caller may
+// not read are left out as if they did not exist
+func (dc *DynamicAPIController) applyReadRule(c *gin.Context, db *mongo.Database, schema *models.Schema, dataKey string, filters ...bson.M) error {
+	if rulesBypassed(c) {
+		return nil
+	}
+	rule := accessRule(schema, models.AccessRead)
+	if rule == nil {
+		return nil
+	}
+
+	mapper := func(fields []string) (string, func(interface{}) interface{}) {
+		if len(fields) == 1 && fields[0] == "id" {
+			return "_id", ruleObjectID
+		}
+		key := dataKey + "." + strings.Join(fields, ".")
+		if len(fields) == 1 {
+			for _, field := range schema.Fields {
+				if field.Name != fields[0] {
+					continue
+				}
+				switch field.Type {
+				case "relation":
+					return key, ruleObjectID
+				case "date":
+					return key, ruleDate
+				}
+			}
+		}
+		return key, nil
+	}
+
+	condition, err := rule.QueryFilter(map[string]interface{}{"auth": dc.ruleAuth(c, db, rule)}, mapper)
+	if err != nil {
+		return err
+	}
+	if len(condition) == 0 {
+		return nil
+	}
+
+	for _, filter := range filters {
+		conditions, _ := filter["$and"].(bson.A)
+		filter["$and"] = append(conditions, condition)
+	}
+	return nil
+}
+
+// writeRuleCheck reports whether a write is allowed given the stored data (nil for creates) and
+// the data after the write (nil for deletes)
+type writeRuleCheck func(documentID primitive.ObjectID, current, data map[string]interface{}) bool
+
+// Helper function to prepare the check of a create, update or delete rule, nil is returned
+// when the operation is open
+func (dc *DynamicAPIController) writeRule(c *gin.Context, db *mongo.Database, schema *models.Schema, operation string) writeRuleCheck {
+	if rulesBypassed(c) {
+		return nil
+	}
+	rule := accessRule(schema, operation)
+	if rule == nil {
+		return nil
+	}
+
+	auth := dc.ruleAuth(c, db, rule)
+	return func(documentID primitive.ObjectID, current, data map[string]interface{}) bool {
+		variables := map[string]interface{}{"auth": auth}
+		if current != nil {
+			variables["doc"] = ruleDocument(documentID, current)
+		}
+		if data != nil {
+			variables["data"] = ruleDocument(documentID, data)
+		}
+		return rule.Evaluate(variables)
+	}
+}
+
+// Helper function to check the rule of a write made through the API. Changes are the prepared
+// data of a create or update, updates and deletes are checked against the stored document.
+// After an error response has been written it returns false.
+func (dc *DynamicAPIController) checkWriteRule(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, operation string, documentID primitive.ObjectID, changes map[string]interface{}) bool {
+	allowed := dc.writeRule(c, db, schema, operation)
+	if allowed == nil {
+		return true
+	}
+
+	var current, data map[string]interface{}
+	if operation != models.AccessCreate {
+		var found bool
+		if current, found = dc.loadDocumentData(c, db, schema.CollectionName, dc.documentFilter(c, schema, operation, documentID, userID)); !found {
+			return false
+		}
+	}
+	switch operation {
+	case models.AccessCreate:
+		data = changes
+	case models.AccessUpdate:
+		data = ruleMergedData(current, changes)
+	}
+
+	if !allowed(documentID, current, data) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied by " + operation + " rule"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// streamHeartbeatInterval keeps idle connections open through proxies
+	streamHeartbeatInterval = 15 * time.Second
+	// streamMaxAwaitTime bounds how long one poll of the change stream waits for events
+	streamMaxAwaitTime = 5 * time.Second
+	// streamRetryMillis tells EventSource clients how long to wait before reconnecting
+	streamRetryMillis = 3000
+	// streamMaxDeliveredDocuments caps the document IDs remembered per subscriber for delete events
+	streamMaxDeliveredDocuments = 100000
+)
+
+// deliveredDocuments remembers the documents sent to a subscriber. Deleted documents are gone by
+// the time the event arrives and cannot be checked against the filters and visibility rules, so
+// delete events only go to subscribers that were sent the document.
+type deliveredDocuments map[primitive.ObjectID]bool
+
+func (d deliveredDocuments) add(documentID primitive.ObjectID) {
+	if !d[documentID] && len(d) >= streamMaxDeliveredDocuments {
+		// Forget an arbitrary document, its delete will not be sent
+		for id := range d {
+			delete(d, id)
+			break
+		}
+	}
+	d[documentID] = true
+}
+
+// take reports whether the document was sent to the subscriber and forgets it
+func (d deliveredDocuments) take(documentID primitive.ObjectID) bool {
+	delivered := d[documentID]
+	delete(d, documentID)
+	return delivered
+}
+
+// changeStreamEvent holds the parts of a MongoDB change event used by the stream
+type changeStreamEvent struct {
+	ID            bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+}
+
+// Helper function to turn a Last-Event-ID back into a change stream resume token
+func parseStreamResumeToken(lastEventID string) (bson.M, error) {
+	if lastEventID == "" {
+		return nil, nil
+	}
+	if _, err := hex.DecodeString(lastEventID); err != nil {
+		return nil, errors.New("Invalid Last-Event-ID")
+	}
+	return bson.M{"_data": lastEventID}, nil
+}
+
+// Helper function to write one server-sent event
+func writeStreamEvent(c *gin.Context, id, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", id, event, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// Helper function to load a changed document through the same filters and population as reads.
+// Nil is returned when the document is not visible to the caller.
+func (dc *DynamicAPIController) loadStreamDocument(c *gin.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, documentID primitive.ObjectID) (map[string]interface{}, error) {
+	matchFilter := bson.M{"_id": documentID, "user_id": userID}
+	publishedView, err := dc.applyPublishFilters(c, schema, matchFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := "data"
+	if publishedView {
+		dataKey = "published_data"
+	}
+	if err := dc.applyReadRule(c, db, schema, dataKey, matchFilter); err != nil {
+		return nil, err
+	}
+	dc.applyOwnerFilter(c, schema, models.AccessRead, matchFilter)
+	dc.applyTenantFilter(c, schema, matchFilter)
+	geoNearStage, err := dc.applyGeoFilters(c, schema, dataKey, matchFilter, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var pipeline []bson.M
+	if geoNearStage != nil {
+		// $geoNear has to be the first stage, it adds the distance of the document
+		pipeline = dc.appendPopulationStages(c, userID, schema, []bson.M{geoNearStage}, publishedView)
+	} else {
+		pipeline = dc.createPopulationPipeline(c, userID, schema, matchFilter, publishedView)
+	}
+	cursor, err := db.Collection(schema.CollectionName).Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var documents []bson.M
+	if err := cursor.All(context.TODO(), &documents); err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	publicData := dc.filterPublicFieldsWithRelations(documents[0], schema, userID, fieldViewerFor(c))
+	dc.localizeDocument(publicData, schema, dc.requestedLocales(c))
+	return publicData, nil
+}
+
+// @Summary Stream document changes
+// @Description Push insert, update and delete events of a collection as Server-Sent Events. Accepts the same publish, geo and locale parameters and visibility rules as the list endpoint. Delete events are only sent for documents delivered on the same connection. Reconnecting clients resume from the Last-Event-ID header. Requires a MongoDB replica set or sharded cluster.
+// @Tags dynamic-api
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param collection path string true "Collection name"
+// @Param Last-Event-ID header string false "ID of the last received event to resume from"
+// @Param last_event_id query string false "Same as Last-Event-ID, for clients that cannot set headers"
+// @Param near query string false "Add the distance from a point (lat,lng) to each document, combine with max_distance to filter"
+// @Param max_distance query number false "Maximum distance in meters, used with near"
+// @Param within query string false "Only send documents inside a bounding box (minLat,minLng,maxLat,maxLng) or polygon (lat,lng;lat,lng;...)"
+// @Param geo_field query string false "Geopoint field to query when the schema has several"
+// @Param locale query string false "Locale for localized fields (falls back to Accept-Language, 'all' returns every translation)"
+// @Success 200 "Event stream"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/{collection}/stream [get]
+func (dc *DynamicAPIController) StreamDocuments(c *gin.Context) {
+	collectionName := c.Param("collection")
+
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+
+	// Get schema
+	schema, err := dc.getSchemaByCollection(userID, collectionName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + collectionName})
+		return
+	}
+
+	// Validate query parameters before the stream starts, errors cannot be reported afterwards
+	if _, err := dc.applyPublishFilters(c, schema, bson.M{}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := dc.applyGeoFilters(c, schema, "data", bson.M{}, bson.M{}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	resumeToken, err := parseStreamResumeToken(lastEventID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	supported, err := config.SupportsTransactions(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inspect MongoDB deployment: " + err.Error()})
+		return
+	}
+	if !supported {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Change streams require a MongoDB replica set or sharded cluster. Your MongoDB server is running as a standalone instance."})
+		return
+	}
+
+	// Deleted documents are gone by the time the event arrives, delete events are matched against
+	// the documents delivered to this subscriber instead. They only carry the document ID.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType": bson.M{"$in": []string{"insert", "update", "replace", "delete"}},
+			"$or": []bson.M{
+				{"fullDocument.user_id": userID},
+				{"operationType": "delete"},
+			},
+		}}},
+	}
+
+	streamOptions := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetMaxAwaitTime(streamMaxAwaitTime)
+	if resumeToken != nil {
+		streamOptions.SetResumeAfter(resumeToken)
+	}
+
+	ctx := c.Request.Context()
+	stream, err := db.Collection(collectionName).Watch(ctx, pipeline, streamOptions)
+	if err != nil {
+		if resumeToken != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot resume from Last-Event-ID: " + err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open change stream"})
+		}
+		return
+	}
+	defer stream.Close(context.TODO())
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryMillis)
+	c.Writer.Flush()
+
+	delivered := deliveredDocuments{}
+	lastWrite := time.Now()
+	for {
+		if !stream.TryNext(ctx) {
+			if err := stream.Err(); err != nil {
+				if ctx.Err() == nil {
+					fmt.Printf("Warning: Change stream on %s stopped: %v\n", collectionName, err)
+					fmt.Fprintf(c.Writer, "event: error\ndata: {\"error\":\"Change stream closed\"}\n\n")
+					c.Writer.Flush()
+				}
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			if time.Since(lastWrite) >= streamHeartbeatInterval {
+				if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				lastWrite = time.Now()
+			}
+			continue
+		}
+
+		var event changeStreamEvent
+		if err := stream.Decode(&event); err != nil {
+			fmt.Printf("Warning: Failed to decode change event on %s: %v\n", collectionName, err)
+			continue
+		}
+		eventID, _ := event.ID.Lookup("_data").StringValueOK()
+		documentID := event.DocumentKey.ID
+
+		var eventType string
+		payload := gin.H{"id": documentID.Hex()}
+
+		switch event.OperationType {
+		case "delete":
+			if !delivered.take(documentID) {
+				continue
+			}
+			eventType = "delete"
+		default:
+			eventType = event.OperationType
+			if eventType == "replace" {
+				eventType = "update"
+			}
+
+			document, err := dc.loadStreamDocument(c, db, userID, schema, documentID)
+			if err != nil {
+				fmt.Printf("Warning: Failed to load changed document %s in %s: %v\n", documentID.Hex(), collectionName, err)
+				continue
+			}
+			if document == nil {
+				// The document does not match the filters or is not visible to the caller
+				continue
+			}
+			payload["document"] = document
+			delivered.add(documentID)
+		}
+
+		if err := writeStreamEvent(c, eventID, eventType, payload); err != nil {
+			return
+		}
+		lastWrite = time.Now()
+	}
+}
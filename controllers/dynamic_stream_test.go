@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDeliveredDocumentsOnlyReportsSentDocuments(t *testing.T) {
+	delivered := deliveredDocuments{}
+	sent := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	delivered.add(sent)
+	if delivered.take(other) {
+		t.Error("delete of a document never sent to the subscriber was reported")
+	}
+	if !delivered.take(sent) {
+		t.Error("delete of a sent document was not reported")
+	}
+	if delivered.take(sent) {
+		t.Error("delete of a sent document was reported twice")
+	}
+}
+
+func TestDeliveredDocumentsIsCapped(t *testing.T) {
+	delivered := deliveredDocuments{}
+	for i := 0; i < streamMaxDeliveredDocuments+10; i++ {
+		delivered.add(primitive.NewObjectID())
+	}
+	if len(delivered) != streamMaxDeliveredDocuments {
+		t.Errorf("remembered %d documents, want %d", len(delivered), streamMaxDeliveredDocuments)
+	}
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Helper function to get the tenant claim of the caller's token, empty without one
+func requestTenant(c *gin.Context) string {
+	return c.GetString("dynamic_auth_tenant")
+}
+
+// Helper function to check whether requests only see the caller's tenant of a collection.
+// Server-side code such as functions sees every tenant, like with owner filters.
+func tenantScoped(c *gin.Context, schema *models.Schema) bool {
+	return !rulesBypassed(c) && utils.TenantKey(schema) != ""
+}
+
+// Helper function to build the condition matching documents of a tenant, nothing matches
+// without a tenant
+func tenantCondition(key, tenant string) bson.M {
+	if tenant == "" {
+		return bson.M{key: bson.M{"$in": bson.A{}}}
+	}
+	return bson.M{key: tenant}
+}
+
+// Helper function to restrict filters to the caller's tenant. The condition is added to $and
+// so field filters on the tenant field can't replace it.
+func (dc *DynamicAPIController) applyTenantFilter(c *gin.Context, schema *models.Schema, filters ...bson.M) {
+	if !tenantScoped(c, schema) {
+		return
+	}
+
+	condition := tenantCondition(utils.TenantKey(schema), requestTenant(c))
+	for _, filter := range filters {
+		conditions, _ := filter["$and"].(bson.A)
+		filter["$and"] = append(conditions, condition)
+	}
+}
+
+// Helper function to check whether stored document data belongs to the caller's tenant
+func (dc *DynamicAPIController) sameTenant(c *gin.Context, schema *models.Schema, data map[string]interface{}) bool {
+	if !tenantScoped(c, schema) {
+		return true
+	}
+	tenant, _ := data[schema.TenantField].(string)
+	return tenant != "" && tenant == requestTenant(c)
+}
+
+// Helper function to find the schema of a relation target, nil when the target has none
+func relationTargetSchema(userID primitive.ObjectID, target string) *models.Schema {
+	var targetSchema models.Schema
+	filter := bson.M{"user_id": userID, "collection_name": target, "is_active": true}
+	if err := config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&targetSchema); err != nil {
+		return nil
+	}
+	return &targetSchema
+}
+
+// Helper function to stamp the writer's tenant on prepared data and make sure relations only
+// point at documents of that tenant. On failure it returns the HTTP status to respond with.
+func (dc *DynamicAPIController) applyTenant(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, schema *models.Schema, tenant string, data map[string]interface{}) (int, error) {
+	if schema.TenantField != "" {
+		if tenant == "" {
+			return http.StatusForbidden, errors.New("A tenant is required to write to this collection")
+		}
+		data[schema.TenantField] = tenant
+	}
+
+	for _, field := range schema.Fields {
+		relationID, ok := data[field.Name].(primitive.ObjectID)
+		if field.Type != "relation" || field.Target == "" || !ok {
+			continue
+		}
+
+		targetSchema := relationTargetSchema(userID, field.Target)
+		if targetSchema == nil {
+			continue
+		}
+		key := utils.TenantKey(targetSchema)
+		if key == "" {
+			continue
+		}
+
+		// Auth users live in their own collection without the owner's user ID
+		filter := tenantCondition(key, tenant)
+		filter["_id"] = relationID
+		collection := field.Target
+		if targetSchema.AuthConfig != nil && targetSchema.AuthConfig.Enabled {
+			collection = targetSchema.AuthConfig.UserCollection
+			if collection == "" {
+				collection = field.Target + "_users"
+			}
+		} else {
+			filter["user_id"] = userID
+		}
+
+		count, err := db.Collection(collection).CountDocuments(ctx, filter)
+		if err != nil {
+			return http.StatusInternalServerError, errors.New("Failed to validate relation for field: " + field.Name)
+		}
+		if count == 0 {
+			return http.StatusBadRequest, errors.New("Referenced document not found for field: " + field.Name)
+		}
+	}
+	return http.StatusOK, nil
+}
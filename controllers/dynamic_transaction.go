@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxTransactionOperations caps the number of writes in a single transaction
+const maxTransactionOperations = 100
+
+// transactionError reports which operation made a transaction fail
+type transactionError struct {
+	index  int
+	status int
+	err    error
+}
+
+func (e *transactionError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.index, e.err)
+}
+
+// transactionWrite remembers a committed write so history and file cleanup can run afterwards
+type transactionWrite struct {
+	schema     *models.Schema
+	action     models.HistoryAction
+	documentID primitive.ObjectID
+	before     bson.M
+	after      map[string]interface{}
+}
+
+// transactionAuth validates the dynamic auth token of a transaction once and checks every
+// operation against the same claims
+type transactionAuth struct {
+	userID   primitive.ObjectID
+	header   string
+	claims   *models.DynamicAuthClaims
+	required bool
+}
+
+// Helper function to check that an operation on a schema is allowed for the transaction's caller.
+// Owner-only operations need to know the caller. A token is also read when a rule guards the
+// operation, rules can use the signed-in user.
+func (ta *transactionAuth) check(schema *models.Schema, action string) (int, error) {
+	requiresAuth := utils.DynamicEndpointRequiresAuth(schema, action) || utils.DynamicEndpointOwnerOnly(schema, action)
+	if !requiresAuth && (utils.AccessRuleFor(schema, action) == "" || ta.header == "") {
+		return http.StatusOK, nil
+	}
+	if requiresAuth {
+		ta.required = true
+	}
+
+	if ta.claims == nil {
+		claims, status, err := utils.ValidateDynamicAuthHeader(ta.userID, schema, ta.header)
+		if err != nil {
+			return status, err
+		}
+		ta.claims = claims
+	}
+	if !utils.HasAnyRole(ta.claims.Roles, utils.DynamicEndpointRoles(schema, action)) {
+		return http.StatusForbidden, errors.New("Insufficient role")
+	}
+	if schema.TenantField != "" && ta.claims.Tenant == "" {
+		return http.StatusForbidden, errors.New("Token has no tenant")
+	}
+	return http.StatusOK, nil
+}
+
+// Helper function to expose the validated caller to the rest of the request
+func (ta *transactionAuth) apply(c *gin.Context) {
+	if ta.claims == nil {
+		return
+	}
+	c.Set("dynamic_auth_user_id", ta.claims.SchemaUserID)
+	c.Set("dynamic_auth_schema_id", ta.claims.SchemaID)
+	c.Set("dynamic_auth_collection", ta.claims.Collection)
+	c.Set("dynamic_auth_roles", ta.claims.Roles)
+	c.Set("dynamic_auth_tenant", ta.claims.Tenant)
+	if !ta.required {
+		// Tokens only read for rules don't make the caller an editor
+		c.Set("dynamic_auth_optional", true)
+	}
+}
+
+// Helper function to check that an operation of a transaction may write to a collection. Before-write
+// hooks are not called inside transactions, so collections using them for the action are refused.
+// Before trigger functions are checked separately by beforeTriggerBypassError.
+func transactionSchemaError(schema *models.Schema, action string) error {
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		return errors.New("Authentication collections cannot be modified in a transaction")
+	}
+	return beforeWriteHookBypassError(schema, action)
+}
+
+// @Summary Run a transaction
+// @Description Run create, update and delete operations on several collections atomically. Requires a MongoDB replica set or sharded cluster.
+// @Tags dynamic-api
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.TransactionRequest true "Operations to run"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /api/transactions [post]
+func (dc *DynamicAPIController) RunTransaction(c *gin.Context) {
+	apiUserID, exists := c.Get("api_user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID := apiUserID.(primitive.ObjectID)
+
+	var req models.TransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Operations) > maxTransactionOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A transaction can contain at most %d operations", maxTransactionOperations)})
+		return
+	}
+
+	// Resolve schemas and check endpoint protection before touching any data
+	auth := &transactionAuth{userID: userID, header: c.GetHeader("Authorization")}
+	schemas := make(map[string]*models.Schema)
+	documentIDs := make([]primitive.ObjectID, len(req.Operations))
+	for i, op := range req.Operations {
+		if op.Action != models.TransactionActionCreate && op.Action != models.TransactionActionUpdate && op.Action != models.TransactionActionDelete {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be one of: create, update, delete", "operation": i})
+			return
+		}
+
+		schema, ok := schemas[op.Collection]
+		if !ok {
+			var err error
+			schema, err = dc.getSchemaByCollection(userID, op.Collection)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found for collection: " + op.Collection, "operation": i})
+				return
+			}
+			schemas[op.Collection] = schema
+		}
+
+		if err := transactionSchemaError(schema, op.Action); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "operation": i})
+			return
+		}
+		if status, err := beforeTriggerBypassError(schema, op.Action); err != nil {
+			c.JSON(status, gin.H{"error": err.Error(), "operation": i})
+			return
+		}
+
+		if status, err := auth.check(schema, op.Action); err != nil {
+			c.JSON(status, gin.H{"error": err.Error(), "operation": i})
+			return
+		}
+
+		// Create operations may choose their ID, the others must reference an existing document
+		switch {
+		case op.ID != "":
+			documentID, err := primitive.ObjectIDFromHex(op.ID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID", "operation": i})
+				return
+			}
+			documentIDs[i] = documentID
+		case op.Action == models.TransactionActionCreate:
+			documentIDs[i] = primitive.NewObjectID()
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Document ID is required for " + op.Action, "operation": i})
+			return
+		}
+
+		if op.Action != models.TransactionActionDelete && op.Data == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Data is required for " + op.Action, "operation": i})
+			return
+		}
+	}
+	auth.apply(c)
+
+	// Get user's database
+	db, err := dc.getUserDatabase(c)
+	if err != nil {
+		if err.Error() == "MongoDB connection not configured" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		}
+		return
+	}
+
+	supported, err := config.SupportsTransactions(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inspect MongoDB deployment: " + err.Error()})
+		return
+	}
+	if !supported {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transactions require a MongoDB replica set or sharded cluster. Your MongoDB server is running as a standalone instance."})
+		return
+	}
+
+	// Field write policies only depend on the caller and the document owner
+	for i, op := range req.Operations {
+		if op.Action == models.TransactionActionDelete {
+			continue
+		}
+		var documentID *primitive.ObjectID
+		if op.Action != models.TransactionActionCreate {
+			documentID = &documentIDs[i]
+		}
+		if status, err := dc.checkFieldWrites(c, db, userID, schemas[op.Collection], documentID, op.Data); err != nil {
+			c.JSON(status, gin.H{"error": err.Error(), "operation": i})
+			return
+		}
+	}
+
+	// Rules are checked inside the transaction, a denied operation rolls back the others
+	rules := make([]writeRuleCheck, len(req.Operations))
+	for i, op := range req.Operations {
+		rules[i] = dc.writeRule(c, db, schemas[op.Collection], op.Action)
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+	defer session.EndSession(context.TODO())
+
+	var results []gin.H
+	var writes []transactionWrite
+
+	_, err = session.WithTransaction(context.TODO(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		// The callback is retried on transient errors, so start from a clean slate
+		results = nil
+		writes = nil
+
+		for i, op := range req.Operations {
+			schema := schemas[op.Collection]
+			documentID := documentIDs[i]
+			collection := db.Collection(op.Collection)
+			filter := dc.documentFilter(c, schema, op.Action, documentID, userID)
+			now := time.Now()
+
+			switch op.Action {
+			case models.TransactionActionCreate:
+				docData, status, err := dc.buildDocumentData(sessCtx, db, userID, schema, op.Data, false)
+				if err != nil {
+					return nil, &transactionError{index: i, status: status, err: err}
+				}
+				if status, err := dc.applyTenant(sessCtx, db, userID, schema, requestTenant(c), docData); err != nil {
+					return nil, &transactionError{index: i, status: status, err: err}
+				}
+				if rules[i] != nil && !rules[i](documentID, nil, docData) {
+					return nil, &transactionError{index: i, status: http.StatusForbidden, err: errors.New("Access denied by create rule")}
+				}
+
+				document := models.DynamicData{
+					ID:        documentID,
+					Data:      docData,
+					UserID:    userID,
+					CreatedAt: now,
+					UpdatedAt: now,
+					CreatedBy: dynamicAuthUserID(c),
+					UpdatedBy: dynamicAuthUserID(c),
+				}
+				if schema.Publishable {
+					document.Status = models.DocumentStatusDraft
+				}
+
+				if _, err := collection.InsertOne(sessCtx, document); err != nil {
+					if mongo.IsDuplicateKeyError(err) {
+						return nil, &transactionError{index: i, status: http.StatusConflict, err: errors.New("Document ID already exists")}
+					}
+					return nil, err
+				}
+				writes = append(writes, transactionWrite{schema: schema, action: models.HistoryActionCreate, documentID: documentID, after: docData})
+
+			case models.TransactionActionUpdate:
+				fieldData, status, err := dc.buildDocumentData(sessCtx, db, userID, schema, op.Data, true)
+				if err != nil {
+					return nil, &transactionError{index: i, status: status, err: err}
+				}
+				if status, err := dc.applyTenant(sessCtx, db, userID, schema, requestTenant(c), fieldData); err != nil {
+					return nil, &transactionError{index: i, status: status, err: err}
+				}
+
+				updateData := make(map[string]interface{})
+				for key, value := range fieldData {
+					updateData["data."+key] = value
+				}
+				updateData["updated_at"] = now
+				if authUserID := dynamicAuthUserID(c); authUserID != nil {
+					updateData["updated_by"] = *authUserID
+				}
+
+				var previous bson.M
+				if err := collection.FindOneAndUpdate(sessCtx, filter, bson.M{"$set": updateData}).Decode(&previous); err != nil {
+					if err == mongo.ErrNoDocuments {
+						return nil, &transactionError{index: i, status: http.StatusNotFound, err: errors.New("Document not found")}
+					}
+					return nil, err
+				}
+
+				write := transactionWrite{schema: schema, action: models.HistoryActionUpdate, documentID: documentID}
+				write.before, _ = previous["data"].(bson.M)
+				if rules[i] != nil && !rules[i](documentID, write.before, ruleMergedData(write.before, fieldData)) {
+					return nil, &transactionError{index: i, status: http.StatusForbidden, err: errors.New("Access denied by update rule")}
+				}
+				if schema.History {
+					var updated bson.M
+					if err := collection.FindOne(sessCtx, filter).Decode(&updated); err == nil {
+						write.after, _ = updated["data"].(bson.M)
+					}
+				}
+				writes = append(writes, write)
+
+			case models.TransactionActionDelete:
+				var deleted bson.M
+				if err := collection.FindOneAndDelete(sessCtx, filter).Decode(&deleted); err != nil {
+					if err == mongo.ErrNoDocuments {
+						return nil, &transactionError{index: i, status: http.StatusNotFound, err: errors.New("Document not found")}
+					}
+					return nil, err
+				}
+				deletedData, _ := deleted["data"].(bson.M)
+				if rules[i] != nil && !rules[i](documentID, deletedData, nil) {
+					return nil, &transactionError{index: i, status: http.StatusForbidden, err: errors.New("Access denied by delete rule")}
+				}
+				writes = append(writes, transactionWrite{schema: schema, action: models.HistoryActionDelete, documentID: documentID, before: deleted})
+			}
+
+			results = append(results, gin.H{
+				"operation":  i,
+				"action":     op.Action,
+				"collection": op.Collection,
+				"id":         documentID.Hex(),
+			})
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		var txErr *transactionError
+		if errors.As(err, &txErr) {
+			c.JSON(txErr.status, gin.H{"error": txErr.err.Error(), "operation": txErr.index})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed: " + err.Error()})
+		return
+	}
+
+	// Side effects that must only happen once the transaction is committed
+	for _, write := range writes {
+		switch write.action {
+		case models.HistoryActionDelete:
+			deletedData, _ := write.before["data"].(bson.M)
+			dc.recordHistory(c, db, write.schema, userID, write.documentID, write.action, deletedData, nil)
+			if schemaHasFieldType(*write.schema, "file") {
+				dc.deleteDocumentFiles(db, write.schema, write.before, nil)
+			}
+			dc.notifyDocumentChange(db, userID, write.schema.CollectionName, utils.DocumentEventDelete, write.documentID, deletedData)
+		case models.HistoryActionCreate:
+			dc.recordHistory(c, db, write.schema, userID, write.documentID, write.action, write.before, write.after)
+			dc.notifyDocumentChange(db, userID, write.schema.CollectionName, utils.DocumentEventInsert, write.documentID, write.after)
+		default:
+			dc.recordHistory(c, db, write.schema, userID, write.documentID, write.action, write.before, write.after)
+			dc.notifyDocumentChange(db, userID, write.schema.CollectionName, utils.DocumentEventUpdate, write.documentID, write.after)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Transaction committed successfully",
+		"results": results,
+	})
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTransactionAuthUsesOneCallerForEveryOperation(t *testing.T) {
+	userID := primitive.NewObjectID()
+	callerID := primitive.NewObjectID()
+	authConfig := &models.AuthConfig{Enabled: true, JWTSecret: "transaction-secret"}
+	articles := &models.Schema{CollectionName: "articles", AuthConfig: authConfig, EndpointProtection: &models.EndpointProtection{Put: true}}
+	comments := &models.Schema{CollectionName: "comments", AuthConfig: authConfig, EndpointProtection: &models.EndpointProtection{Post: true}}
+
+	token, _, err := (&DynamicAuthController{}).generateDynamicJWT(userID, callerID, primitive.NewObjectID(), "users", nil, "", authConfig.JWTSecret, 1)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	auth := &transactionAuth{userID: userID, header: "Bearer " + token}
+	if _, err := auth.check(articles, models.TransactionActionCreate); err != nil || auth.claims != nil {
+		t.Fatalf("unprotected create was checked: %v", err)
+	}
+	if _, err := auth.check(comments, models.TransactionActionCreate); err != nil {
+		t.Fatalf("protected create was refused: %v", err)
+	}
+	auth.header = "Bearer other"
+	if _, err := auth.check(articles, models.TransactionActionUpdate); err != nil {
+		t.Fatalf("protected update was refused: %v", err)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	auth.apply(c)
+	if got, _ := c.Get("dynamic_auth_user_id"); got != callerID {
+		t.Errorf("dynamic_auth_user_id = %v, want %v", got, callerID)
+	}
+
+	invalid := &transactionAuth{userID: userID, header: "Bearer invalid"}
+	if status, err := invalid.check(articles, models.TransactionActionUpdate); err == nil || status != http.StatusUnauthorized {
+		t.Errorf("invalid token got %d, %v, want 401", status, err)
+	}
+}
+
+func TestTransactionAuthChecksRolesOfEachOperation(t *testing.T) {
+	userID := primitive.NewObjectID()
+	authConfig := &models.AuthConfig{Enabled: true, JWTSecret: "transaction-secret"}
+	articles := &models.Schema{CollectionName: "articles", AuthConfig: authConfig, EndpointProtection: &models.EndpointProtection{
+		Roles: models.EndpointRoles{Put: []string{"editor"}, Delete: []string{"admin"}},
+	}}
+
+	token, _, err := (&DynamicAuthController{}).generateDynamicJWT(userID, primitive.NewObjectID(), primitive.NewObjectID(), "users", []string{"editor"}, "", authConfig.JWTSecret, 1)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	auth := &transactionAuth{userID: userID, header: "Bearer " + token}
+	if _, err := auth.check(articles, models.TransactionActionUpdate); err != nil {
+		t.Errorf("editor was refused an update: %v", err)
+	}
+	if status, err := auth.check(articles, models.TransactionActionDelete); err == nil || status != http.StatusForbidden {
+		t.Errorf("editor delete got %d, %v, want 403", status, err)
+	}
+}
+
+func TestTransactionAuthRequiresTenantForTenantCollections(t *testing.T) {
+	userID := primitive.NewObjectID()
+	authConfig := &models.AuthConfig{Enabled: true, JWTSecret: "transaction-secret"}
+	invoices := &models.Schema{CollectionName: "invoices", AuthConfig: authConfig, TenantField: "org"}
+
+	sign := func(tenant string) string {
+		token, _, err := (&DynamicAuthController{}).generateDynamicJWT(userID, primitive.NewObjectID(), primitive.NewObjectID(), "users", nil, tenant, authConfig.JWTSecret, 1)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return "Bearer " + token
+	}
+
+	anonymous := &transactionAuth{userID: userID}
+	if status, err := anonymous.check(invoices, models.TransactionActionCreate); err == nil || status != http.StatusUnauthorized {
+		t.Errorf("create without token got %d, %v, want 401", status, err)
+	}
+	untenanted := &transactionAuth{userID: userID, header: sign("")}
+	if status, err := untenanted.check(invoices, models.TransactionActionCreate); err == nil || status != http.StatusForbidden {
+		t.Errorf("create without tenant got %d, %v, want 403", status, err)
+	}
+
+	tenanted := &transactionAuth{userID: userID, header: sign("acme")}
+	if _, err := tenanted.check(invoices, models.TransactionActionDelete); err != nil {
+		t.Fatalf("tenant token was refused: %v", err)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tenanted.apply(c)
+	if got := c.GetString("dynamic_auth_tenant"); got != "acme" {
+		t.Errorf("dynamic_auth_tenant = %q, want acme", got)
+	}
+}
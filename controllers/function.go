@@ -0,0 +1,397 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxFunctionsPerSchema caps the number of functions attached to one schema
+const maxFunctionsPerSchema = 20
+
+// functionNamePattern keeps function names usable in URLs
+var functionNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+var validFunctionTriggers = map[string]bool{
+	models.FunctionTriggerBeforeCreate: true,
+	models.FunctionTriggerAfterCreate:  true,
+	models.FunctionTriggerBeforeUpdate: true,
+	models.FunctionTriggerAfterUpdate:  true,
+	models.FunctionTriggerBeforeDelete: true,
+	models.FunctionTriggerAfterDelete:  true,
+	models.FunctionTriggerEndpoint:     true,
+}
+
+type FunctionController struct {
+	dc *DynamicAPIController
+}
+
+func NewFunctionController() *FunctionController {
+	return &FunctionController{dc: NewDynamicAPIController()}
+}
+
+// Helper function to validate the trigger, code and time limit of a function
+func validateFunction(name, trigger, code string, timeoutMs int) error {
+	if !validFunctionTriggers[trigger] {
+		return errors.New("Invalid trigger: " + trigger + ". Supported triggers: beforeCreate, afterCreate, beforeUpdate, afterUpdate, beforeDelete, afterDelete, endpoint")
+	}
+	if timeoutMs < 0 || timeoutMs > utils.MaxFunctionTimeout {
+		return fmt.Errorf("timeout_ms must be between 1 and %d", utils.MaxFunctionTimeout)
+	}
+	if err := utils.CompileFunction(name, code); err != nil {
+		return errors.New("Invalid function code: " + err.Error())
+	}
+	return nil
+}
+
+// Helper function to load a function owned by the current user
+func (fc *FunctionController) getFunction(c *gin.Context) (*models.SchemaFunction, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	functionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid function ID"})
+		return nil, false
+	}
+
+	var function models.SchemaFunction
+	err = config.DB.Collection("functions").FindOne(context.TODO(), bson.M{"_id": functionID, "user_id": userID}).Decode(&function)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Function not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find function"})
+		}
+		return nil, false
+	}
+	return &function, true
+}
+
+// @Summary Create function
+// @Description Attach a JavaScript function to a schema. The code is the body of a function receiving event, db and console. Trigger functions run before or after writes, endpoint functions are called through /api/{collection}/fn/{name}.
+// @Tags functions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param function body models.CreateFunctionRequest true "Function"
+// @Success 201 {object} models.SchemaFunction
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 409 "Conflict"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/functions [post]
+func (fc *FunctionController) CreateFunction(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	var req models.CreateFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var schema models.Schema
+	err = config.DB.Collection("schemas").FindOne(context.TODO(), bson.M{"_id": schemaID, "user_id": userID, "is_active": true}).Decode(&schema)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find schema"})
+		}
+		return
+	}
+
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Functions are not supported for authentication schemas"})
+		return
+	}
+
+	if !functionNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Function name must start with a letter and contain only letters, digits, '-' and '_' (max 64 characters)"})
+		return
+	}
+
+	if err := validateFunction(req.Name, req.Trigger, req.Code, req.TimeoutMs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	functions := config.DB.Collection("functions")
+	count, err := functions.CountDocuments(context.TODO(), bson.M{"schema_id": schemaID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count functions"})
+		return
+	}
+	if count >= maxFunctionsPerSchema {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A schema can have at most %d functions", maxFunctionsPerSchema)})
+		return
+	}
+
+	existing, err := functions.CountDocuments(context.TODO(), bson.M{"schema_id": schemaID, "name": req.Name})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A function with this name already exists"})
+		return
+	}
+
+	timeout := req.TimeoutMs
+	if timeout == 0 {
+		timeout = utils.DefaultFunctionTimeout
+	}
+
+	now := time.Now()
+	function := models.SchemaFunction{
+		ID:             primitive.NewObjectID(),
+		UserID:         userID.(primitive.ObjectID),
+		SchemaID:       schemaID,
+		CollectionName: schema.CollectionName,
+		Name:           req.Name,
+		Trigger:        req.Trigger,
+		Code:           req.Code,
+		TimeoutMs:      timeout,
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if _, err := functions.InsertOne(context.TODO(), function); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create function"})
+		return
+	}
+
+	go LogActivityWithContext(c, function.UserID, models.ActivityTypeCreate, "Added function \""+function.Name+"\" to \""+schema.CollectionName+"\"", "Server-side function created", "function", function.ID.Hex(), map[string]any{
+		"collection_name": schema.CollectionName,
+		"trigger":         function.Trigger,
+	})
+
+	c.JSON(http.StatusCreated, function)
+}
+
+// @Summary Get functions
+// @Description Get the functions attached to a schema
+// @Tags functions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Success 200 {array} models.SchemaFunction
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/functions [get]
+func (fc *FunctionController) GetFunctions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := config.DB.Collection("functions").Find(context.TODO(), bson.M{"schema_id": schemaID, "user_id": userID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch functions"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	functions := []models.SchemaFunction{}
+	if err := cursor.All(context.TODO(), &functions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode functions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, functions)
+}
+
+// @Summary Update function
+// @Description Change the code, trigger, time limit or active state of a function
+// @Tags functions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Function ID"
+// @Param function body models.UpdateFunctionRequest true "Function changes"
+// @Success 200 {object} models.SchemaFunction
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /functions/{id} [put]
+func (fc *FunctionController) UpdateFunction(c *gin.Context) {
+	function, ok := fc.getFunction(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trigger, code, timeout := function.Trigger, function.Code, function.TimeoutMs
+	if req.Trigger != "" {
+		trigger = req.Trigger
+	}
+	if req.Code != nil {
+		code = *req.Code
+	}
+	if req.TimeoutMs != nil {
+		timeout = *req.TimeoutMs
+		if timeout == 0 {
+			timeout = utils.DefaultFunctionTimeout
+		}
+	}
+
+	if err := validateFunction(function.Name, trigger, code, timeout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := bson.M{
+		"trigger":    trigger,
+		"code":       code,
+		"timeout_ms": timeout,
+		"updated_at": time.Now(),
+	}
+	if req.Active != nil {
+		set["active"] = *req.Active
+	}
+
+	var updated models.SchemaFunction
+	err := config.DB.Collection("functions").FindOneAndUpdate(context.TODO(), bson.M{"_id": function.ID}, bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update function"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary Delete function
+// @Description Delete a function
+// @Tags functions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Function ID"
+// @Success 200 "Success"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /functions/{id} [delete]
+func (fc *FunctionController) DeleteFunction(c *gin.Context) {
+	function, ok := fc.getFunction(c)
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Collection("functions").DeleteOne(context.TODO(), bson.M{"_id": function.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete function"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Function deleted successfully"})
+}
+
+// @Summary Test function
+// @Description Run a function once with the given event and return its result and console output. Database calls made by the function are executed against your database.
+// @Tags functions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Function ID"
+// @Param event body models.TestFunctionRequest false "Event passed to the function"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /functions/{id}/test [post]
+func (fc *FunctionController) TestFunction(c *gin.Context) {
+	function, ok := fc.getFunction(c)
+	if !ok {
+		return
+	}
+
+	var req models.TestFunctionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Event == nil {
+		req.Event = map[string]interface{}{}
+	}
+
+	var user models.User
+	if err := config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": function.UserID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return
+	}
+	if user.MongoDBURI == "" || user.DatabaseName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please configure your MongoDB connection first"})
+		return
+	}
+
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		return
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	// The db helpers read the owner from the API request context
+	c.Set("api_user", user)
+	c.Set("api_user_id", user.ID)
+
+	started := time.Now()
+	result, logs, err := fc.dc.runFunction(c, db, user.ID, function, req.Event)
+	response := gin.H{
+		"result":      result,
+		"logs":        logs,
+		"duration_ms": time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/M-awais-rasool/SchemaCraft-go/config"
 	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type SchemaController struct{}
@@ -23,47 +28,8 @@ func NewSchemaController() *SchemaController {
 	return &SchemaController{}
 }
 
-// @Summary Create schema
-// @Description Create a new schema/collection definition
-// @Tags schema
-// @Accept json
-// @Produce json
-// @Security BearerAuth
-// @Param request body models.CreateSchemaRequest true "Schema data"
-// @Success 201 "Created"
-// @Failure 400 "Bad Request"
-// @Failure 401 "Unauthorized"
-// @Failure 409 "Conflict"
-// @Failure 500 "Internal Server Error"
-// @Router /schemas [post]
-func (sc *SchemaController) CreateSchema(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	// Get user info to check if MongoDB URI is configured
-	var user models.User
-	err := config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
-		return
-	}
-
-	// Check if user has configured MongoDB URI
-	if user.MongoDBURI == "" || user.DatabaseName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Please first add a MongoDB connection"})
-		return
-	}
-
-	var req models.CreateSchemaRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate field types
+// Helper function to validate field definitions shared by create and update
+func (sc *SchemaController) validateSchemaFields(userID interface{}, fields []models.SchemaField) (int, error) {
 	validTypes := map[string]bool{
 		"string":   true,
 		"number":   true,
@@ -72,19 +38,21 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 		"object":   true,
 		"array":    true,
 		"relation": true,
+		"geopoint": true,
+		"file":     true,
 	}
 
-	for _, field := range req.Fields {
+	for i := range fields {
+		field := &fields[i]
+
 		if !validTypes[field.Type] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field type: " + field.Type})
-			return
+			return http.StatusBadRequest, errors.New("Invalid field type: " + field.Type)
 		}
 
 		// Validate relation fields
 		if field.Type == "relation" {
 			if field.Target == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Target collection is required for relation field: " + field.Name})
-				return
+				return http.StatusBadRequest, errors.New("Target collection is required for relation field: " + field.Name)
 			}
 
 			// Check if target collection exists and belongs to the same user
@@ -93,11 +61,41 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 			targetErr := config.DB.Collection("schemas").FindOne(context.TODO(), targetFilter).Decode(&targetSchema)
 			if targetErr != nil {
 				if targetErr == mongo.ErrNoDocuments {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Target collection '" + field.Target + "' not found for relation field: " + field.Name})
-					return
-				} else {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error while validating target collection"})
-					return
+					return http.StatusBadRequest, errors.New("Target collection '" + field.Target + "' not found for relation field: " + field.Name)
+				}
+				return http.StatusInternalServerError, errors.New("Database error while validating target collection")
+			}
+		}
+
+		// Validate geopoint defaults so they can be stored as GeoJSON
+		if field.Type == "geopoint" && field.Default != nil {
+			point, err := utils.ParseGeoPoint(field.Default)
+			if err != nil {
+				return http.StatusBadRequest, errors.New("Invalid default location for field " + field.Name + ": " + err.Error())
+			}
+			field.Default = point
+		}
+
+		// Only string fields can hold one value per locale
+		if field.Localized && field.Type != "string" {
+			return http.StatusBadRequest, errors.New("Only string fields can be localized: " + field.Name)
+		}
+
+		// Validate file fields, their content is uploaded separately after the document exists
+		if field.Type == "file" {
+			if field.Required {
+				return http.StatusBadRequest, errors.New("File field '" + field.Name + "' cannot be required, files are uploaded after the document is created")
+			}
+			if field.Default != nil {
+				return http.StatusBadRequest, errors.New("File field '" + field.Name + "' cannot have a default value")
+			}
+			if field.MaxSize < 0 || field.MaxSize > maxFileFieldSize {
+				return http.StatusBadRequest, fmt.Errorf("max_size for file field '%s' must be between 0 and %d bytes", field.Name, maxFileFieldSize)
+			}
+			for _, allowedType := range field.AllowedTypes {
+				parts := strings.Split(allowedType, "/")
+				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+					return http.StatusBadRequest, errors.New("Invalid MIME type '" + allowedType + "' for file field: " + field.Name)
 				}
 			}
 		}
@@ -106,68 +104,352 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 			field.Visibility = "public" // Default to public
 		}
 		if field.Visibility != "public" && field.Visibility != "private" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Field visibility must be 'public' or 'private'"})
-			return
+			return http.StatusBadRequest, errors.New("Field visibility must be 'public' or 'private'")
+		}
+
+		// Read and write policies refine visibility, a read policy keeps visibility in sync
+		if err := utils.ValidateFieldPolicy(field.ReadPolicy); err != nil {
+			return http.StatusBadRequest, errors.New("Invalid read_policy for field " + field.Name + ": " + err.Error())
+		}
+		if err := utils.ValidateFieldPolicy(field.WritePolicy); err != nil {
+			return http.StatusBadRequest, errors.New("Invalid write_policy for field " + field.Name + ": " + err.Error())
+		}
+		if field.ReadPolicy == models.FieldPolicyPublic {
+			field.Visibility = "public"
+		} else if field.ReadPolicy != "" {
+			field.Visibility = "private"
 		}
 	}
 
-	// Validate auth configuration if provided
-	var authConfig *models.AuthConfig
-	if req.AuthConfig != nil && req.AuthConfig.Enabled {
-		// Validate auth configuration
-		if req.AuthConfig.UserCollection == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User collection is required for authentication"})
-			return
+	return http.StatusOK, nil
+}
+
+// reservedCollectionNames would be shadowed by platform endpoints under /api
+var reservedCollectionNames = map[string]bool{
+	"transactions": true,
+	"batch":        true,
+	"ws":           true,
+}
+
+// Helper function to validate schema level options shared by create and update
+func (sc *SchemaController) validateSchemaOptions(req *models.CreateSchemaRequest) (int, error) {
+	if reservedCollectionNames[req.CollectionName] {
+		return http.StatusBadRequest, errors.New("Collection name is reserved: " + req.CollectionName)
+	}
+
+	if req.Expiry != nil {
+		if req.Expiry.TTLSeconds == 0 && req.Expiry.Field == "" {
+			// An empty expiry object disables expiry
+			req.Expiry = nil
+		} else {
+			if req.Expiry.TTLSeconds != 0 && req.Expiry.Field != "" {
+				return http.StatusBadRequest, errors.New("Expiry must use either ttl_seconds or field, not both")
+			}
+			if req.Expiry.TTLSeconds < 0 || req.Expiry.TTLSeconds > math.MaxInt32 {
+				return http.StatusBadRequest, fmt.Errorf("Expiry ttl_seconds must be between 1 and %d", math.MaxInt32)
+			}
+			if req.AuthConfig != nil && req.AuthConfig.Enabled {
+				return http.StatusBadRequest, errors.New("Expiry is not supported for authentication schemas")
+			}
+			if req.Expiry.Field != "" {
+				found := false
+				for _, field := range req.Fields {
+					if field.Name == req.Expiry.Field {
+						if field.Type != "date" {
+							return http.StatusBadRequest, errors.New("Expiry field '" + field.Name + "' must be a date field")
+						}
+						found = true
+					}
+				}
+				if !found {
+					return http.StatusBadRequest, errors.New("Expiry field '" + req.Expiry.Field + "' not found in schema")
+				}
+			}
 		}
+	}
 
-		if req.AuthConfig.LoginFields.EmailField == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email field is required for authentication"})
-			return
+	if req.Publishable && req.AuthConfig != nil && req.AuthConfig.Enabled {
+		return http.StatusBadRequest, errors.New("Authentication schemas cannot be publishable")
+	}
+
+	if req.BeforeWrite != nil {
+		if req.BeforeWrite.URL == "" {
+			// An empty hook URL disables the hook
+			req.BeforeWrite = nil
+		} else {
+			if err := utils.ValidateWebhookURL(req.BeforeWrite.URL); err != nil {
+				return http.StatusBadRequest, errors.New("Invalid before_write url: " + err.Error())
+			}
+			for _, operation := range req.BeforeWrite.Operations {
+				if operation != models.BeforeWriteCreate && operation != models.BeforeWriteUpdate && operation != models.BeforeWriteDelete {
+					return http.StatusBadRequest, errors.New("Unsupported before_write operation: " + operation + ". Supported operations: create, update, delete")
+				}
+			}
+			if req.BeforeWrite.TimeoutMs < 0 || req.BeforeWrite.TimeoutMs > utils.MaxBeforeWriteTimeout {
+				return http.StatusBadRequest, fmt.Errorf("before_write timeout_ms must be between 1 and %d", utils.MaxBeforeWriteTimeout)
+			}
 		}
+	}
 
-		if req.AuthConfig.PasswordField == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Password field is required for authentication"})
-			return
+	if req.AuthConfig != nil && req.AuthConfig.Enabled {
+		if err := utils.ValidateAuthRoles(req.AuthConfig); err != nil {
+			return http.StatusBadRequest, errors.New("Invalid auth_config: " + err.Error())
+		}
+		if tenantField := req.AuthConfig.TenantField; tenantField != "" && (tenantField == req.AuthConfig.PasswordField || tenantField == models.AuthRolesField) {
+			return http.StatusBadRequest, errors.New("Invalid auth_config: tenant_field must not be the password or roles field")
+		}
+	}
+
+	if err := utils.ValidateTenantField(req); err != nil {
+		return http.StatusBadRequest, errors.New("Invalid tenant_field: " + err.Error())
+	}
+
+	if protection := req.EndpointProtection; protection != nil {
+		roles := protection.Roles
+		for _, methodRoles := range [][]string{roles.Get, roles.Post, roles.Put, roles.Delete} {
+			for _, role := range methodRoles {
+				if err := utils.ValidateRoleName(role); err != nil {
+					return http.StatusBadRequest, errors.New("Invalid endpoint_protection: " + err.Error())
+				}
+			}
 		}
+	}
 
-		// Verify that the specified fields exist in the schema
-		fieldNames := make(map[string]bool)
-		for _, field := range req.Fields {
-			fieldNames[field.Name] = true
+	if req.Rules != nil {
+		if err := utils.ValidateAccessRules(req.Rules); err != nil {
+			return http.StatusBadRequest, errors.New("Invalid rules: " + err.Error())
 		}
+		if *req.Rules == (models.AccessRules{}) {
+			req.Rules = nil
+		}
+	}
 
-		if !fieldNames[req.AuthConfig.LoginFields.EmailField] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email field '" + req.AuthConfig.LoginFields.EmailField + "' not found in schema"})
-			return
+	// Validate localization settings
+	hasLocalizedFields := false
+	for _, field := range req.Fields {
+		if field.Localized {
+			hasLocalizedFields = true
 		}
+	}
 
-		if !fieldNames[req.AuthConfig.PasswordField] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Password field '" + req.AuthConfig.PasswordField + "' not found in schema"})
-			return
+	if hasLocalizedFields && (req.Localization == nil || req.Localization.DefaultLocale == "") {
+		return http.StatusBadRequest, errors.New("localization.default_locale is required when fields are localized")
+	}
+
+	if req.Localization != nil {
+		localization := req.Localization
+		localization.DefaultLocale = utils.NormalizeLocale(localization.DefaultLocale)
+		if localization.DefaultLocale == "" {
+			return http.StatusBadRequest, errors.New("localization.default_locale is required")
 		}
 
-		if req.AuthConfig.LoginFields.UsernameField != "" && !fieldNames[req.AuthConfig.LoginFields.UsernameField] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Username field '" + req.AuthConfig.LoginFields.UsernameField + "' not found in schema"})
-			return
+		allowedLocales := make(map[string]bool)
+		for i, locale := range localization.Locales {
+			localization.Locales[i] = utils.NormalizeLocale(locale)
+			allowedLocales[localization.Locales[i]] = true
+		}
+		if len(localization.Locales) > 0 && !allowedLocales[localization.DefaultLocale] {
+			return http.StatusBadRequest, errors.New("localization.locales must include the default locale")
 		}
 
-		// Set defaults
-		if req.AuthConfig.TokenExpiration == 0 {
-			req.AuthConfig.TokenExpiration = 24 // 24 hours default
+		for i, locale := range localization.Fallback {
+			localization.Fallback[i] = utils.NormalizeLocale(locale)
+			if len(localization.Locales) > 0 && !allowedLocales[localization.Fallback[i]] {
+				return http.StatusBadRequest, errors.New("Fallback locale '" + locale + "' is not in localization.locales")
+			}
 		}
 
-		// Generate JWT secret if not provided
-		if req.AuthConfig.JWTSecret == "" {
-			secretBytes := make([]byte, 32)
-			_, err := rand.Read(secretBytes)
+		// Store localized defaults as locale maps
+		for i := range req.Fields {
+			field := &req.Fields[i]
+			if !field.Localized || field.Default == nil {
+				continue
+			}
+
+			defaults, err := normalizeLocalizedValue(field.Default, localization)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate JWT secret"})
-				return
+				return http.StatusBadRequest, errors.New("Invalid default for localized field " + field.Name + ": " + err.Error())
+			}
+			if _, ok := defaults[localization.DefaultLocale]; !ok {
+				return http.StatusBadRequest, errors.New("Default for localized field " + field.Name + " must include the default locale")
 			}
-			req.AuthConfig.JWTSecret = hex.EncodeToString(secretBytes)
+			field.Default = defaults
 		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// Helper function to check the auth configuration of a schema request and fill in defaults.
+// The JWT secret of the existing configuration is kept so issued tokens stay valid. On failure
+// it returns the HTTP status to respond with.
+func prepareAuthConfig(req *models.CreateSchemaRequest, existing *models.AuthConfig) (*models.AuthConfig, int, error) {
+	if req.AuthConfig == nil || !req.AuthConfig.Enabled {
+		return nil, http.StatusOK, nil
+	}
+
+	if req.AuthConfig.UserCollection == "" {
+		return nil, http.StatusBadRequest, errors.New("User collection is required for authentication")
+	}
 
-		authConfig = req.AuthConfig
+	if req.AuthConfig.LoginFields.EmailField == "" {
+		return nil, http.StatusBadRequest, errors.New("Email field is required for authentication")
+	}
+
+	if req.AuthConfig.PasswordField == "" {
+		return nil, http.StatusBadRequest, errors.New("Password field is required for authentication")
+	}
+
+	// Verify that the specified fields exist in the schema
+	fieldNames := make(map[string]bool)
+	for _, field := range req.Fields {
+		fieldNames[field.Name] = true
+	}
+
+	if !fieldNames[req.AuthConfig.LoginFields.EmailField] {
+		return nil, http.StatusBadRequest, errors.New("Email field '" + req.AuthConfig.LoginFields.EmailField + "' not found in schema")
+	}
+
+	if !fieldNames[req.AuthConfig.PasswordField] {
+		return nil, http.StatusBadRequest, errors.New("Password field '" + req.AuthConfig.PasswordField + "' not found in schema")
+	}
+
+	if req.AuthConfig.LoginFields.UsernameField != "" && !fieldNames[req.AuthConfig.LoginFields.UsernameField] {
+		return nil, http.StatusBadRequest, errors.New("Username field '" + req.AuthConfig.LoginFields.UsernameField + "' not found in schema")
+	}
+
+	// Set defaults
+	if req.AuthConfig.TokenExpiration == 0 {
+		req.AuthConfig.TokenExpiration = 24 // 24 hours default
+	}
+
+	if req.AuthConfig.JWTSecret == "" && existing != nil {
+		req.AuthConfig.JWTSecret = existing.JWTSecret
+	}
+
+	// Generate JWT secret if not provided
+	if req.AuthConfig.JWTSecret == "" {
+		secretBytes := make([]byte, 32)
+		if _, err := rand.Read(secretBytes); err != nil {
+			return nil, http.StatusInternalServerError, errors.New("Failed to generate JWT secret")
+		}
+		req.AuthConfig.JWTSecret = hex.EncodeToString(secretBytes)
+	}
+
+	return req.AuthConfig, http.StatusOK, nil
+}
+
+// Helper function to give a before-write hook a signing secret, keeping the existing one when none is provided
+func prepareBeforeWriteSecret(hook, existing *models.BeforeWriteHook) error {
+	if hook == nil || hook.Secret != "" {
+		return nil
+	}
+	if existing != nil && existing.Secret != "" {
+		hook.Secret = existing.Secret
+		return nil
+	}
+	secret, err := utils.GenerateWebhookSecret()
+	if err != nil {
+		return err
+	}
+	hook.Secret = secret
+	return nil
+}
+
+// Helper function to turn a localized value into a map of normalized locale to string.
+// A plain string is treated as the value for the default locale.
+func normalizeLocalizedValue(value interface{}, localization *models.LocalizationConfig) (map[string]interface{}, error) {
+	allowedLocales := make(map[string]bool)
+	for _, locale := range localization.Locales {
+		allowedLocales[locale] = true
+	}
+
+	switch v := value.(type) {
+	case string:
+		return map[string]interface{}{localization.DefaultLocale: v}, nil
+	case map[string]interface{}:
+		values := make(map[string]interface{})
+		for locale, translation := range v {
+			normalized := utils.NormalizeLocale(locale)
+			if len(allowedLocales) > 0 && !allowedLocales[normalized] {
+				return nil, errors.New("locale '" + locale + "' is not configured for this schema")
+			}
+			text, ok := translation.(string)
+			if !ok {
+				return nil, errors.New("translation for locale '" + locale + "' must be a string")
+			}
+			values[normalized] = text
+		}
+		return values, nil
+	case bson.M:
+		return normalizeLocalizedValue(map[string]interface{}(v), localization)
+	default:
+		return nil, errors.New("expected an object of locale to string")
+	}
+}
+
+// @Summary Create schema
+// @Description Create a new schema/collection definition
+// @Tags schema
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateSchemaRequest true "Schema data"
+// @Success 201 "Created"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 409 "Conflict"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas [post]
+func (sc *SchemaController) CreateSchema(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get user info to check if MongoDB URI is configured
+	var user models.User
+	err := config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
+		return
+	}
+
+	// Check if user has configured MongoDB URI
+	if user.MongoDBURI == "" || user.DatabaseName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please first add a MongoDB connection"})
+		return
+	}
+
+	var req models.CreateSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate field definitions
+	if status, err := sc.validateSchemaFields(userID, req.Fields); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate schema level options
+	if status, err := sc.validateSchemaOptions(&req); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := prepareBeforeWriteSecret(req.BeforeWrite, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate before_write secret"})
+		return
+	}
+
+	// Validate auth configuration if provided
+	authConfig, status, err := prepareAuthConfig(&req, nil)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Check if active schema already exists for this user
@@ -195,6 +477,13 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 				"fields":              req.Fields,
 				"auth_config":         authConfig,
 				"endpoint_protection": req.EndpointProtection,
+				"expiry":              req.Expiry,
+				"localization":        req.Localization,
+				"publishable":         req.Publishable,
+				"history":             req.History,
+				"before_write":        req.BeforeWrite,
+				"rules":               req.Rules,
+				"tenant_field":        req.TenantField,
 				"updated_at":          time.Now(),
 				"is_active":           true,
 			},
@@ -211,9 +500,18 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 		updatedSchema.Fields = req.Fields
 		updatedSchema.AuthConfig = authConfig
 		updatedSchema.EndpointProtection = req.EndpointProtection
+		updatedSchema.Expiry = req.Expiry
+		updatedSchema.Localization = req.Localization
+		updatedSchema.Publishable = req.Publishable
+		updatedSchema.History = req.History
+		updatedSchema.BeforeWrite = req.BeforeWrite
+		updatedSchema.Rules = req.Rules
+		updatedSchema.TenantField = req.TenantField
 		updatedSchema.UpdatedAt = time.Now()
 		updatedSchema.IsActive = true
 
+		recordSchemaVersion(c, updatedSchema, &inactiveSchema, models.SchemaVersionActionCreate, 0)
+
 		// Log schema reactivation activity
 		go LogActivityWithContext(c, userID.(primitive.ObjectID), models.ActivityTypeUpdate, "Reactivated table \""+req.CollectionName+"\"", "Database table schema reactivated", "schema", updatedSchema.ID.Hex(), map[string]any{
 			"collection_name": req.CollectionName,
@@ -222,6 +520,10 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 			"action":          "reactivated",
 		})
 
+		if err := sc.syncSchemaIndexes(user, updatedSchema); err != nil {
+			fmt.Printf("Warning: Failed to sync indexes for collection %s: %v\n", updatedSchema.CollectionName, err)
+		}
+
 		c.JSON(http.StatusCreated, updatedSchema)
 		return
 	} else if inactiveErr != mongo.ErrNoDocuments {
@@ -237,6 +539,13 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 		Fields:             req.Fields,
 		AuthConfig:         authConfig,
 		EndpointProtection: req.EndpointProtection,
+		Expiry:             req.Expiry,
+		Localization:       req.Localization,
+		Publishable:        req.Publishable,
+		History:            req.History,
+		BeforeWrite:        req.BeforeWrite,
+		Rules:              req.Rules,
+		TenantField:        req.TenantField,
 		CreatedAt:          time.Now(),
 		UpdatedAt:          time.Now(),
 		IsActive:           true,
@@ -249,6 +558,8 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 		return
 	}
 
+	recordSchemaVersion(c, schema, nil, models.SchemaVersionActionCreate, 0)
+
 	// Log schema creation activity
 	go LogActivityWithContext(c, userID.(primitive.ObjectID), models.ActivityTypeCreate, "Created table \""+req.CollectionName+"\"", "New database table schema created", "schema", schema.ID.Hex(), map[string]any{
 		"collection_name": req.CollectionName,
@@ -256,6 +567,10 @@ func (sc *SchemaController) CreateSchema(c *gin.Context) {
 		"has_auth":        req.AuthConfig != nil && req.AuthConfig.Enabled,
 	})
 
+	if err := sc.syncSchemaIndexes(user, schema); err != nil {
+		fmt.Printf("Warning: Failed to sync indexes for collection %s: %v\n", schema.CollectionName, err)
+	}
+
 	c.JSON(http.StatusCreated, schema)
 }
 
@@ -383,113 +698,6 @@ func (sc *SchemaController) UpdateSchema(c *gin.Context) {
 		return
 	}
 
-	// Validate field types
-	validTypes := map[string]bool{
-		"string":   true,
-		"number":   true,
-		"boolean":  true,
-		"date":     true,
-		"object":   true,
-		"array":    true,
-		"relation": true,
-	}
-
-	for _, field := range req.Fields {
-		if !validTypes[field.Type] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field type: " + field.Type})
-			return
-		}
-
-		// Validate relation fields
-		if field.Type == "relation" {
-			if field.Target == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Target collection is required for relation field: " + field.Name})
-				return
-			}
-
-			// Check if target collection exists and belongs to the same user
-			var targetSchema models.Schema
-			targetFilter := bson.M{"user_id": userID, "collection_name": field.Target, "is_active": true}
-			targetErr := config.DB.Collection("schemas").FindOne(context.TODO(), targetFilter).Decode(&targetSchema)
-			if targetErr != nil {
-				if targetErr == mongo.ErrNoDocuments {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Target collection '" + field.Target + "' not found for relation field: " + field.Name})
-					return
-				} else {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error while validating target collection"})
-					return
-				}
-			}
-		}
-
-		if field.Visibility == "" {
-			field.Visibility = "public" // Default to public
-		}
-		if field.Visibility != "public" && field.Visibility != "private" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Field visibility must be 'public' or 'private'"})
-			return
-		}
-	}
-
-	// Validate auth configuration if provided
-	var authConfig *models.AuthConfig
-	if req.AuthConfig != nil && req.AuthConfig.Enabled {
-		// Validate auth configuration
-		if req.AuthConfig.UserCollection == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User collection is required for authentication"})
-			return
-		}
-
-		if req.AuthConfig.LoginFields.EmailField == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email field is required for authentication"})
-			return
-		}
-
-		if req.AuthConfig.PasswordField == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Password field is required for authentication"})
-			return
-		}
-
-		// Verify that the specified fields exist in the schema
-		fieldNames := make(map[string]bool)
-		for _, field := range req.Fields {
-			fieldNames[field.Name] = true
-		}
-
-		if !fieldNames[req.AuthConfig.LoginFields.EmailField] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email field '" + req.AuthConfig.LoginFields.EmailField + "' not found in schema"})
-			return
-		}
-
-		if !fieldNames[req.AuthConfig.PasswordField] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Password field '" + req.AuthConfig.PasswordField + "' not found in schema"})
-			return
-		}
-
-		if req.AuthConfig.LoginFields.UsernameField != "" && !fieldNames[req.AuthConfig.LoginFields.UsernameField] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Username field '" + req.AuthConfig.LoginFields.UsernameField + "' not found in schema"})
-			return
-		}
-
-		// Set defaults
-		if req.AuthConfig.TokenExpiration == 0 {
-			req.AuthConfig.TokenExpiration = 24 // 24 hours default
-		}
-
-		// Generate JWT secret if not provided
-		if req.AuthConfig.JWTSecret == "" {
-			secretBytes := make([]byte, 32)
-			_, err := rand.Read(secretBytes)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate JWT secret"})
-				return
-			}
-			req.AuthConfig.JWTSecret = hex.EncodeToString(secretBytes)
-		}
-
-		authConfig = req.AuthConfig
-	}
-
 	// Check if schema exists and belongs to user
 	var existingSchema models.Schema
 	filter := bson.M{"_id": schemaID, "user_id": userID, "is_active": true}
@@ -503,59 +711,123 @@ func (sc *SchemaController) UpdateSchema(c *gin.Context) {
 		return
 	}
 
+	updatedSchema, status, err := sc.applySchemaUpdate(user, existingSchema, &req)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordSchemaVersion(c, *updatedSchema, &existingSchema, models.SchemaVersionActionUpdate, 0)
+
+	// Log schema update activity
+	go LogActivityWithContext(c, userID.(primitive.ObjectID), models.ActivityTypeUpdate, "Updated table \""+req.CollectionName+"\"", "Database table schema updated", "schema", updatedSchema.ID.Hex(), map[string]any{
+		"collection_name": req.CollectionName,
+		"field_count":     len(req.Fields),
+		"has_auth":        req.AuthConfig != nil && req.AuthConfig.Enabled,
+		"action":          "updated",
+	})
+
+	c.JSON(http.StatusOK, updatedSchema)
+}
+
+// Helper function to check a new definition of a schema and fill in its defaults without storing
+// it. On failure it returns the HTTP status to respond with.
+func (sc *SchemaController) validateSchemaUpdate(user models.User, existingSchema models.Schema, req *models.CreateSchemaRequest) (*models.AuthConfig, int, error) {
+	// Validate field definitions
+	if status, err := sc.validateSchemaFields(user.ID, req.Fields); err != nil {
+		return nil, status, err
+	}
+
+	// Validate schema level options
+	if status, err := sc.validateSchemaOptions(req); err != nil {
+		return nil, status, err
+	}
+
+	// Validate auth configuration if provided
+	authConfig, status, err := prepareAuthConfig(req, existingSchema.AuthConfig)
+	if err != nil {
+		return nil, status, err
+	}
+
+	// Keep the hook secret so receivers don't have to update their verification
+	if err := prepareBeforeWriteSecret(req.BeforeWrite, existingSchema.BeforeWrite); err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Failed to generate before_write secret")
+	}
+
 	// Check if collection name is being changed and if another active schema exists with the new name
 	if req.CollectionName != existingSchema.CollectionName {
 		var conflictSchema models.Schema
-		conflictFilter := bson.M{"user_id": userID, "collection_name": req.CollectionName, "is_active": true}
+		conflictFilter := bson.M{"user_id": user.ID, "collection_name": req.CollectionName, "is_active": true}
 		conflictErr := config.DB.Collection("schemas").FindOne(context.TODO(), conflictFilter).Decode(&conflictSchema)
 		if conflictErr == nil {
-			c.JSON(http.StatusConflict, gin.H{"error": "Schema already exists for this collection name"})
-			return
+			return nil, http.StatusConflict, errors.New("Schema already exists for this collection name")
 		} else if conflictErr != mongo.ErrNoDocuments {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-			return
+			return nil, http.StatusInternalServerError, errors.New("Database error")
 		}
 	}
 
+	return authConfig, http.StatusOK, nil
+}
+
+// Helper function to validate a new definition of a schema and store it in place of the old one.
+// Webhooks, functions and indexes follow the new definition. On failure it returns the HTTP
+// status to respond with.
+func (sc *SchemaController) applySchemaUpdate(user models.User, existingSchema models.Schema, req *models.CreateSchemaRequest) (*models.Schema, int, error) {
+	authConfig, status, err := sc.validateSchemaUpdate(user, existingSchema, req)
+	if err != nil {
+		return nil, status, err
+	}
+
 	// Update schema
+	filter := bson.M{"_id": existingSchema.ID, "user_id": user.ID, "is_active": true}
 	updateDoc := bson.M{
 		"$set": bson.M{
 			"collection_name":     req.CollectionName,
 			"fields":              req.Fields,
 			"auth_config":         authConfig,
 			"endpoint_protection": req.EndpointProtection,
+			"expiry":              req.Expiry,
+			"localization":        req.Localization,
+			"publishable":         req.Publishable,
+			"history":             req.History,
+			"before_write":        req.BeforeWrite,
+			"rules":               req.Rules,
+			"tenant_field":        req.TenantField,
 			"updated_at":          time.Now(),
 		},
 	}
 
 	result, err := config.DB.Collection("schemas").UpdateOne(context.TODO(), filter, updateDoc)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schema"})
-		return
+		return nil, http.StatusInternalServerError, errors.New("Failed to update schema")
 	}
 
 	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
-		return
+		return nil, http.StatusNotFound, errors.New("Schema not found")
 	}
 
 	// Fetch and return updated schema
 	var updatedSchema models.Schema
 	err = config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&updatedSchema)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated schema"})
-		return
+		return nil, http.StatusInternalServerError, errors.New("Failed to fetch updated schema")
 	}
 
-	// Log schema update activity
-	go LogActivityWithContext(c, userID.(primitive.ObjectID), models.ActivityTypeUpdate, "Updated table \""+req.CollectionName+"\"", "Database table schema updated", "schema", updatedSchema.ID.Hex(), map[string]any{
-		"collection_name": req.CollectionName,
-		"field_count":     len(req.Fields),
-		"has_auth":        req.AuthConfig != nil && req.AuthConfig.Enabled,
-		"action":          "updated",
-	})
+	// Webhooks and functions keep the collection name of their schema
+	if req.CollectionName != existingSchema.CollectionName {
+		for _, collection := range []string{"webhooks", "functions"} {
+			_, err := config.DB.Collection(collection).UpdateMany(context.TODO(), bson.M{"schema_id": existingSchema.ID}, bson.M{"$set": bson.M{"collection_name": req.CollectionName}})
+			if err != nil {
+				fmt.Printf("Warning: Failed to update %s of renamed schema: %v\n", collection, err)
+			}
+		}
+	}
 
-	c.JSON(http.StatusOK, updatedSchema)
+	if err := sc.syncSchemaIndexes(user, updatedSchema); err != nil {
+		fmt.Printf("Warning: Failed to sync indexes for collection %s: %v\n", updatedSchema.CollectionName, err)
+	}
+
+	return &updatedSchema, http.StatusOK, nil
 }
 
 // @Summary Delete schema
@@ -625,6 +897,12 @@ func (sc *SchemaController) DeleteSchema(c *gin.Context) {
 			},
 		}
 
+		// Remember their definitions so the change is recorded as a new version
+		var protectedSchemas []models.Schema
+		if cursor, err := config.DB.Collection("schemas").Find(context.TODO(), protectedSchemasFilter); err == nil {
+			cursor.All(context.TODO(), &protectedSchemas)
+		}
+
 		// Remove endpoint protection from those schemas
 		removeProtectionUpdate := bson.M{
 			"$set": bson.M{
@@ -641,8 +919,139 @@ func (sc *SchemaController) DeleteSchema(c *gin.Context) {
 		if err != nil {
 			// Log the error but don't fail the delete operation
 			fmt.Printf("Warning: Failed to remove endpoint protection from related schemas: %v\n", err)
+		} else {
+			for _, protectedSchema := range protectedSchemas {
+				unprotectedSchema := protectedSchema
+				unprotectedSchema.EndpointProtection = nil
+				recordSchemaVersion(c, unprotectedSchema, &protectedSchema, models.SchemaVersionActionUpdate, 0)
+			}
 		}
 	}
 
+	// Webhooks must not fire for a new schema that reuses the collection name
+	if _, err := config.DB.Collection("webhooks").DeleteMany(context.TODO(), bson.M{"schema_id": schemaID}); err != nil {
+		fmt.Printf("Warning: Failed to delete webhooks of schema: %v\n", err)
+	}
+	if _, err := config.DB.Collection("functions").DeleteMany(context.TODO(), bson.M{"schema_id": schemaID}); err != nil {
+		fmt.Printf("Warning: Failed to delete functions of schema: %v\n", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Schema deleted successfully"})
 }
+
+// schemaIndexPrefix marks indexes managed by SchemaCraft so user created indexes are left alone
+const schemaIndexPrefix = "schemacraft_"
+
+// Helper function to build the indexes a schema needs in the user's database.
+// Index names encode their options so a changed definition produces a new name.
+func schemaIndexModels(schema models.Schema) []mongo.IndexModel {
+	var indexes []mongo.IndexModel
+
+	for _, field := range schema.Fields {
+		if field.Type == "geopoint" {
+			indexes = append(indexes, mongo.IndexModel{
+				Keys:    bson.D{{Key: "data." + field.Name, Value: "2dsphere"}},
+				Options: options.Index().SetName(schemaIndexPrefix + "geo_" + field.Name),
+			})
+		}
+	}
+
+	// Published snapshots are queried separately so their locations need their own index
+	if schema.Publishable {
+		for _, field := range schema.Fields {
+			if field.Type == "geopoint" {
+				indexes = append(indexes, mongo.IndexModel{
+					Keys:    bson.D{{Key: "published_data." + field.Name, Value: "2dsphere"}},
+					Options: options.Index().SetName(schemaIndexPrefix + "geo_published_" + field.Name),
+				})
+			}
+		}
+	}
+
+	// Owner-only collections filter every read or write by the creating user
+	if protection := schema.EndpointProtection; protection != nil && (protection.OwnerOnly.Get || protection.OwnerOnly.Put || protection.OwnerOnly.Delete) {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "created_by", Value: 1}},
+			Options: options.Index().SetName(schemaIndexPrefix + "owner"),
+		})
+	}
+
+	// Tenant-scoped collections filter every request by the caller's tenant
+	if schema.TenantField != "" {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "data." + schema.TenantField, Value: 1}},
+			Options: options.Index().SetName(schemaIndexPrefix + "tenant"),
+		})
+	}
+
+	// TTL index removing expired documents
+	if schema.Expiry != nil {
+		if schema.Expiry.TTLSeconds > 0 {
+			indexes = append(indexes, mongo.IndexModel{
+				Keys: bson.D{{Key: "created_at", Value: 1}},
+				Options: options.Index().
+					SetName(fmt.Sprintf("%sttl_created_at_%d", schemaIndexPrefix, schema.Expiry.TTLSeconds)).
+					SetExpireAfterSeconds(int32(schema.Expiry.TTLSeconds)),
+			})
+		} else if schema.Expiry.Field != "" {
+			indexes = append(indexes, mongo.IndexModel{
+				Keys: bson.D{{Key: "data." + schema.Expiry.Field, Value: 1}},
+				Options: options.Index().
+					SetName(schemaIndexPrefix + "ttl_" + schema.Expiry.Field).
+					SetExpireAfterSeconds(0),
+			})
+		}
+	}
+
+	return indexes
+}
+
+// Helper function to create missing schema indexes and drop managed ones that are no longer needed
+func (sc *SchemaController) syncSchemaIndexes(user models.User, schema models.Schema) error {
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	collection := db.Collection(schema.CollectionName)
+	desired := schemaIndexModels(schema)
+	desiredNames := make(map[string]bool)
+	for _, index := range desired {
+		desiredNames[*index.Options.Name] = true
+	}
+
+	// Listing fails when the collection does not exist yet, in which case there is nothing to drop
+	cursor, err := collection.Indexes().List(context.TODO())
+	if err == nil {
+		var existing []bson.M
+		if err := cursor.All(context.TODO(), &existing); err == nil {
+			for _, index := range existing {
+				name, _ := index["name"].(string)
+				if strings.HasPrefix(name, schemaIndexPrefix) && !desiredNames[name] {
+					if _, err := collection.Indexes().DropOne(context.TODO(), name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	// Versions are unique per document so concurrent writers cannot record the same version
+	if schema.History {
+		historyIndex := mongo.IndexModel{
+			Keys:    bson.D{{Key: "document_id", Value: 1}, {Key: "version", Value: 1}},
+			Options: options.Index().SetName(schemaIndexPrefix + "history_version").SetUnique(true),
+		}
+		if _, err := db.Collection(historyCollectionName(schema.CollectionName)).Indexes().CreateOne(context.TODO(), historyIndex); err != nil {
+			return err
+		}
+	}
+
+	if len(desired) == 0 {
+		return nil
+	}
+
+	_, err = collection.Indexes().CreateMany(context.TODO(), desired)
+	return err
+}
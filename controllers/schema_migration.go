@@ -0,0 +1,703 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxMigrationOperations caps the operations of one migration
+	maxMigrationOperations = 50
+	// migrationProgressInterval is the number of documents between progress updates
+	migrationProgressInterval = 100
+	// maxMigrationErrors caps the document errors kept in a report
+	maxMigrationErrors = 1000
+	// maxMigrationSamples caps the before and after examples of a dry run
+	maxMigrationSamples = 10
+)
+
+// Helper function to get the collection holding the backups of a collection's migrations
+func migrationBackupCollectionName(collectionName string) string {
+	return collectionName + "__migrations"
+}
+
+// migrationBackup is the data of a document before a migration changed it
+type migrationBackup struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty"`
+	JobID         primitive.ObjectID     `bson:"job_id"`
+	DocumentID    primitive.ObjectID     `bson:"document_id"`
+	Data          map[string]interface{} `bson:"data"`
+	PublishedData map[string]interface{} `bson:"published_data,omitempty"`
+	CreatedAt     time.Time              `bson:"created_at"`
+}
+
+// migrationResult collects the outcome of a migration or rollback
+type migrationResult struct {
+	processed int64
+	modified  int64
+	failed    int64
+	errors    []models.MigrationDocumentError
+	truncated bool
+	samples   []models.MigrationSample
+}
+
+func (r *migrationResult) addError(documentID primitive.ObjectID, err error) {
+	r.failed++
+	if len(r.errors) >= maxMigrationErrors {
+		r.truncated = true
+		return
+	}
+	r.errors = append(r.errors, models.MigrationDocumentError{DocumentID: documentID, Error: err.Error()})
+}
+
+// Helper function to decode a stored document or job with nested documents as maps, so data
+// and samples serialize to plain JSON objects
+func decodeWithMaps(raw bson.Raw, value interface{}) error {
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return err
+	}
+	decoder.DefaultDocumentM()
+	return decoder.Decode(value)
+}
+
+// Helper function to build the $set and $unset of the keys that differ between two versions of
+// document data stored under prefix
+func migrationChanges(prefix string, before, after map[string]interface{}, set, unset bson.M) {
+	for key, value := range after {
+		if previous, ok := before[key]; !ok || !reflect.DeepEqual(previous, value) {
+			set[prefix+key] = value
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			unset[prefix+key] = ""
+		}
+	}
+}
+
+// Helper function to run migration operations over every document of a collection. Changed
+// documents are backed up before they are updated, dry runs only collect samples. The progress
+// callback is called every migrationProgressInterval documents.
+func runMigration(db *mongo.Database, job *models.MigrationJob, progress func(*migrationResult)) (*migrationResult, error) {
+	result := &migrationResult{errors: []models.MigrationDocumentError{}}
+	collection := db.Collection(job.Collection)
+	backups := db.Collection(migrationBackupCollectionName(job.Collection))
+
+	findOptions := options.Find().SetProjection(bson.M{"data": 1, "published_data": 1})
+	cursor, err := collection.Find(context.TODO(), bson.M{"user_id": job.UserID}, findOptions)
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var document struct {
+			ID            primitive.ObjectID     `bson:"_id"`
+			Data          map[string]interface{} `bson:"data"`
+			PublishedData map[string]interface{} `bson:"published_data"`
+		}
+		if err := decodeWithMaps(cursor.Current, &document); err != nil {
+			return result, err
+		}
+
+		result.processed++
+		if progress != nil && result.processed%migrationProgressInterval == 0 {
+			progress(result)
+		}
+
+		data, changed, err := utils.ApplyMigration(document.Data, job.Operations)
+		if err != nil {
+			result.addError(document.ID, err)
+			continue
+		}
+
+		// Published snapshots get the same changes so readers of published documents see the new shape
+		publishedData, publishedChanged := document.PublishedData, false
+		if document.PublishedData != nil {
+			publishedData, publishedChanged, err = utils.ApplyMigration(document.PublishedData, job.Operations)
+			if err != nil {
+				result.addError(document.ID, err)
+				continue
+			}
+		}
+
+		if !changed && !publishedChanged {
+			continue
+		}
+		result.modified++
+
+		if job.DryRun {
+			if len(result.samples) < maxMigrationSamples {
+				result.samples = append(result.samples, models.MigrationSample{DocumentID: document.ID, Before: document.Data, After: data})
+			}
+			continue
+		}
+
+		backup := migrationBackup{
+			JobID:         job.ID,
+			DocumentID:    document.ID,
+			Data:          document.Data,
+			PublishedData: document.PublishedData,
+			CreatedAt:     time.Now(),
+		}
+		if _, err := backups.InsertOne(context.TODO(), backup); err != nil {
+			return result, errors.New("Failed to back up document " + document.ID.Hex() + ": " + err.Error())
+		}
+
+		set, unset := bson.M{"updated_at": time.Now()}, bson.M{}
+		migrationChanges("data.", document.Data, data, set, unset)
+		if publishedChanged {
+			migrationChanges("published_data.", document.PublishedData, publishedData, set, unset)
+		}
+		update := bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if _, err := collection.UpdateOne(context.TODO(), bson.M{"_id": document.ID, "user_id": job.UserID}, update); err != nil {
+			return result, errors.New("Failed to update document " + document.ID.Hex() + ": " + err.Error())
+		}
+	}
+
+	return result, cursor.Err()
+}
+
+// Helper function to restore the documents changed by a migration from their backups
+func runMigrationRollback(db *mongo.Database, job *models.MigrationJob, progress func(*migrationResult)) (*migrationResult, error) {
+	result := &migrationResult{errors: []models.MigrationDocumentError{}}
+	collection := db.Collection(job.Collection)
+	backups := db.Collection(migrationBackupCollectionName(job.Collection))
+
+	cursor, err := backups.Find(context.TODO(), bson.M{"job_id": *job.RollbackOf})
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var backup migrationBackup
+		if err := decodeWithMaps(cursor.Current, &backup); err != nil {
+			return result, err
+		}
+
+		result.processed++
+		if progress != nil && result.processed%migrationProgressInterval == 0 {
+			progress(result)
+		}
+
+		set := bson.M{"data": backup.Data, "updated_at": time.Now()}
+		if backup.PublishedData != nil {
+			set["published_data"] = backup.PublishedData
+		}
+		updateResult, err := collection.UpdateOne(context.TODO(), bson.M{"_id": backup.DocumentID, "user_id": job.UserID}, bson.M{"$set": set})
+		if err != nil {
+			return result, errors.New("Failed to restore document " + backup.DocumentID.Hex() + ": " + err.Error())
+		}
+		if updateResult.MatchedCount == 0 {
+			result.addError(backup.DocumentID, errors.New("document no longer exists"))
+			continue
+		}
+		result.modified++
+	}
+	if err := cursor.Err(); err != nil {
+		return result, err
+	}
+
+	// The backups are spent, the migration cannot be rolled back twice
+	if _, err := backups.DeleteMany(context.TODO(), bson.M{"job_id": *job.RollbackOf}); err != nil {
+		fmt.Printf("Warning: Failed to delete backups of migration %s: %v\n", job.RollbackOf.Hex(), err)
+	}
+	return result, nil
+}
+
+// Helper function to run a migration or rollback job and keep its progress up to date
+func runMigrationJob(db *mongo.Database, job *models.MigrationJob) {
+	defer db.Client().Disconnect(context.TODO())
+
+	jobs := config.DB.Collection("migration_jobs")
+	updateJob := func(filter bson.M, fields bson.M) {
+		fields["updated_at"] = time.Now()
+		if _, err := jobs.UpdateOne(context.TODO(), filter, bson.M{"$set": fields}); err != nil {
+			fmt.Printf("Warning: Failed to update migration job %s: %v\n", job.ID.Hex(), err)
+		}
+	}
+	resultFields := func(result *migrationResult) bson.M {
+		return bson.M{
+			"processed_documents": result.processed,
+			"modified_documents":  result.modified,
+			"failed_documents":    result.failed,
+			"errors":              result.errors,
+			"errors_truncated":    result.truncated,
+			"samples":             result.samples,
+		}
+	}
+
+	jobFilter := bson.M{"_id": job.ID}
+	updateJob(jobFilter, bson.M{"status": models.MigrationStatusRunning})
+
+	run := runMigration
+	if job.RollbackOf != nil {
+		run = runMigrationRollback
+	}
+	result, err := run(db, job, func(progress *migrationResult) {
+		updateJob(jobFilter, resultFields(progress))
+	})
+
+	fields := resultFields(result)
+	completedAt := time.Now()
+	fields["completed_at"] = completedAt
+	if err != nil {
+		fields["status"] = models.MigrationStatusFailed
+		fields["error"] = err.Error()
+	} else {
+		fields["status"] = models.MigrationStatusCompleted
+	}
+	updateJob(jobFilter, fields)
+
+	if job.RollbackOf != nil && err == nil {
+		updateJob(bson.M{"_id": *job.RollbackOf}, bson.M{"status": models.MigrationStatusRolledBack, "rolled_back_by": job.ID})
+	}
+
+	title := fmt.Sprintf("Migrated %d documents of \"%s\"", result.modified, job.Collection)
+	switch {
+	case job.DryRun:
+		title = fmt.Sprintf("Dry run would migrate %d documents of \"%s\"", result.modified, job.Collection)
+	case job.RollbackOf != nil:
+		title = fmt.Sprintf("Rolled back the migration of %d documents of \"%s\"", result.modified, job.Collection)
+	}
+	LogActivity(job.UserID, models.ActivityTypeUpdate, title, "Migration job finished", "schema", job.SchemaID.Hex(), map[string]any{
+		"job_id":   job.ID.Hex(),
+		"modified": result.modified,
+		"failed":   result.failed,
+	})
+}
+
+// Helper function to derive migration operations from the diff of two schema versions.
+// After an error response has been written it returns false.
+func migrationOperationsFromVersions(c *gin.Context, schemaID primitive.ObjectID, req *models.CreateMigrationRequest) ([]models.MigrationOperation, bool) {
+	versionValue := func(version int) string {
+		if version == 0 {
+			return ""
+		}
+		return strconv.Itoa(version)
+	}
+
+	to, ok := loadSchemaVersion(c, schemaID, versionValue(req.ToVersion))
+	if !ok {
+		return nil, false
+	}
+	fromVersion := req.FromVersion
+	if fromVersion == 0 {
+		fromVersion = to.Version - 1
+	}
+	if fromVersion < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Version 1 has no previous version to migrate from"})
+		return nil, false
+	}
+	from, ok := loadSchemaVersion(c, schemaID, versionValue(fromVersion))
+	if !ok {
+		return nil, false
+	}
+
+	operations := utils.PlanMigration(utils.DiffSchemas(&from.Definition, &to.Definition))
+	if len(operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Versions %d and %d need no data migration", from.Version, to.Version)})
+		return nil, false
+	}
+	req.FromVersion, req.ToVersion = from.Version, to.Version
+	return operations, true
+}
+
+// Helper function to refuse a new job while another one runs on the same schema.
+// After an error response has been written it returns false.
+func migrationSlotFree(c *gin.Context, schemaID primitive.ObjectID) bool {
+	filter := bson.M{"schema_id": schemaID, "status": bson.M{"$in": []string{models.MigrationStatusPending, models.MigrationStatusRunning}}}
+	count, err := config.DB.Collection("migration_jobs").CountDocuments(context.TODO(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another migration of this schema is still running"})
+		return false
+	}
+	return true
+}
+
+// Helper function to insert a migration job and start it in the background
+func startMigrationJob(c *gin.Context, user models.User, job *models.MigrationJob) {
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		return
+	}
+
+	if _, err := config.DB.Collection("migration_jobs").InsertOne(context.TODO(), job); err != nil {
+		db.Client().Disconnect(context.TODO())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create migration job"})
+		return
+	}
+
+	go runMigrationJob(db, job)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Migration started",
+		"job_id":     job.ID.Hex(),
+		"status":     job.Status,
+		"dry_run":    job.DryRun,
+		"operations": job.Operations,
+		"status_url": "/schemas/" + job.SchemaID.Hex() + "/migrations/" + job.ID.Hex(),
+	})
+}
+
+// Helper function to get the user with a configured MongoDB connection.
+// After an error response has been written it returns false.
+func connectedUser(c *gin.Context, userID interface{}) (models.User, bool) {
+	var user models.User
+	if err := config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
+		return user, false
+	}
+	if user.MongoDBURI == "" || user.DatabaseName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please first add a MongoDB connection"})
+		return user, false
+	}
+	return user, true
+}
+
+// @Summary Start data migration
+// @Description Run migration operations over the stored documents of a schema in the background: rename_field, convert_type, backfill_default, drop_field, split_field and merge_fields. Without operations they are derived from the diff between two schema versions. Dry runs report what would change without writing.
+// @Tags schema
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param request body models.CreateMigrationRequest true "Operations or schema versions to migrate between"
+// @Success 202 "Accepted"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 409 "Conflict"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/migrations [post]
+func (sc *SchemaController) CreateMigration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, ok := connectedUser(c, userID)
+	if !ok {
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Authentication schemas cannot be migrated"})
+		return
+	}
+
+	var req models.CreateMigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.FromVersion < 0 || req.ToVersion < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Versions must be positive"})
+		return
+	}
+
+	operations := req.Operations
+	if len(operations) == 0 {
+		if operations, ok = migrationOperationsFromVersions(c, schema.ID, &req); !ok {
+			return
+		}
+	} else {
+		req.FromVersion, req.ToVersion = 0, 0
+	}
+	if len(operations) > maxMigrationOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A migration can have at most %d operations", maxMigrationOperations)})
+		return
+	}
+	for i, op := range operations {
+		if err := utils.ValidateMigrationOperation(op); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Operation %d: %v", i, err)})
+			return
+		}
+	}
+
+	if !migrationSlotFree(c, schema.ID) {
+		return
+	}
+
+	now := time.Now()
+	job := &models.MigrationJob{
+		ID:          primitive.NewObjectID(),
+		UserID:      schema.UserID,
+		SchemaID:    schema.ID,
+		Collection:  schema.CollectionName,
+		Operations:  operations,
+		FromVersion: req.FromVersion,
+		ToVersion:   req.ToVersion,
+		DryRun:      req.DryRun,
+		Status:      models.MigrationStatusPending,
+		Errors:      []models.MigrationDocumentError{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	job.TotalDocuments, _ = countMigrationDocuments(user, bson.M{"user_id": schema.UserID}, schema.CollectionName)
+
+	startMigrationJob(c, user, job)
+}
+
+// Helper function to count the documents a job will go through, used for progress
+func countMigrationDocuments(user models.User, filter bson.M, collectionName string) (int64, error) {
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Client().Disconnect(context.TODO())
+	return db.Collection(collectionName).CountDocuments(context.TODO(), filter)
+}
+
+// @Summary Get data migrations
+// @Description List the migration jobs of a schema, newest first
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/migrations [get]
+func (sc *SchemaController) GetMigrations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	skip := (page - 1) * limit
+
+	filter := bson.M{"schema_id": schema.ID}
+	findOptions := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	collection := config.DB.Collection("migration_jobs")
+	cursor, err := collection.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch migrations"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	jobs := []models.MigrationJob{}
+	for cursor.Next(context.TODO()) {
+		var job models.MigrationJob
+		if err := decodeWithMaps(cursor.Current, &job); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode migrations"})
+			return
+		}
+		jobs = append(jobs, job)
+	}
+
+	total, err := collection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		total = 0
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": jobs,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// Helper function to load a migration job of a schema by the job_id path parameter.
+// After an error response has been written it returns false.
+func loadMigrationJob(c *gin.Context, schemaID primitive.ObjectID) (*models.MigrationJob, bool) {
+	jobID, err := primitive.ObjectIDFromHex(c.Param("job_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return nil, false
+	}
+
+	raw, err := config.DB.Collection("migration_jobs").FindOne(context.TODO(), bson.M{"_id": jobID, "schema_id": schemaID}).DecodeBytes()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Migration job not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+
+	var job models.MigrationJob
+	if err := decodeWithMaps(raw, &job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode migration job"})
+		return nil, false
+	}
+	return &job, true
+}
+
+// @Summary Get data migration
+// @Description Get the progress, document errors and dry run samples of a migration job
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param job_id path string true "Migration job ID"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/migrations/{job_id} [get]
+func (sc *SchemaController) GetMigration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	job, ok := loadMigrationJob(c, schema.ID)
+	if !ok {
+		return
+	}
+
+	progress := 0.0
+	if job.TotalDocuments > 0 {
+		progress = float64(job.ProcessedDocuments) / float64(job.TotalDocuments) * 100
+	}
+	if job.Status == models.MigrationStatusCompleted || job.Status == models.MigrationStatusRolledBack || progress > 100 {
+		progress = 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":      job,
+		"progress": progress,
+	})
+}
+
+// @Summary Roll back data migration
+// @Description Restore the documents changed by a migration from the backups taken while it ran. Changes made to those documents since the migration are overwritten. Runs in the background like migrations.
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param job_id path string true "Migration job ID"
+// @Success 202 "Accepted"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 409 "Conflict"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/migrations/{job_id}/rollback [post]
+func (sc *SchemaController) RollbackMigration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, ok := connectedUser(c, userID)
+	if !ok {
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	original, ok := loadMigrationJob(c, schema.ID)
+	if !ok {
+		return
+	}
+
+	switch {
+	case original.DryRun:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dry runs changed nothing to roll back"})
+		return
+	case original.RollbackOf != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rollbacks cannot be rolled back"})
+		return
+	case original.Status == models.MigrationStatusRolledBack:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Migration has already been rolled back"})
+		return
+	case original.Status != models.MigrationStatusCompleted && original.Status != models.MigrationStatusFailed:
+		c.JSON(http.StatusConflict, gin.H{"error": "Migration is still running"})
+		return
+	}
+
+	if !migrationSlotFree(c, schema.ID) {
+		return
+	}
+
+	// Documents are restored into the collection the migration ran on
+	now := time.Now()
+	job := &models.MigrationJob{
+		ID:         primitive.NewObjectID(),
+		UserID:     original.UserID,
+		SchemaID:   original.SchemaID,
+		Collection: original.Collection,
+		Operations: original.Operations,
+		RollbackOf: &original.ID,
+		Status:     models.MigrationStatusPending,
+		Errors:     []models.MigrationDocumentError{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	job.TotalDocuments, _ = countMigrationDocuments(user, bson.M{"job_id": original.ID}, migrationBackupCollectionName(original.Collection))
+
+	startMigrationJob(c, user, job)
+}
@@ -0,0 +1,495 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// previewFieldTypes are the BSON types, as named by the $type aggregation operator, each field type is stored as
+var previewFieldTypes = map[string][]string{
+	"string":   {"string"},
+	"number":   {"double", "int", "long", "decimal"},
+	"boolean":  {"bool"},
+	"date":     {"date", "string"},
+	"object":   {"object"},
+	"array":    {"array"},
+	"relation": {"objectId"},
+	"geopoint": {"object"},
+	"file":     {"object"},
+}
+
+// Helper function to build the aggregation expression matching documents where a data field is missing or null
+func previewMissingExpr(field string) bson.M {
+	return bson.M{"$in": bson.A{bson.M{"$type": "$data." + field}, bson.A{"missing", "null"}}}
+}
+
+// Helper function to build the aggregation expression matching documents where a data field is
+// set to a value of another type. Localized strings are stored as one value per locale.
+func previewWrongTypeExpr(field models.SchemaField) bson.M {
+	allowed := bson.A{"missing", "null"}
+	types := previewFieldTypes[field.Type]
+	if field.Localized {
+		types = []string{"object"}
+	}
+	for _, bsonType := range types {
+		allowed = append(allowed, bsonType)
+	}
+	return bson.M{"$not": bson.A{bson.M{"$in": bson.A{bson.M{"$type": "$data." + field.Name}, allowed}}}}
+}
+
+// Helper function to count the stored documents that would fail the required and type checks
+// of the new fields. The documents stay in the collection they are stored in when it is renamed.
+func previewDocuments(db *mongo.Database, existing, proposed *models.Schema) (models.SchemaPreviewDocuments, error) {
+	documents := models.SchemaPreviewDocuments{
+		Collection: existing.CollectionName,
+		Fields:     []models.SchemaPreviewFieldCheck{},
+	}
+	collection := db.Collection(existing.CollectionName)
+	baseFilter := bson.M{"user_id": existing.UserID}
+	count := func(expr bson.M) (int64, error) {
+		return collection.CountDocuments(context.TODO(), bson.M{"user_id": existing.UserID, "$expr": expr})
+	}
+
+	total, err := collection.CountDocuments(context.TODO(), baseFilter)
+	if err != nil {
+		return documents, err
+	}
+	documents.TotalDocuments = total
+	if total == 0 {
+		return documents, nil
+	}
+
+	var failing bson.A
+	for _, field := range proposed.Fields {
+		check := models.SchemaPreviewFieldCheck{Field: field.Name}
+
+		if field.Required {
+			expr := previewMissingExpr(field.Name)
+			if check.MissingRequired, err = count(expr); err != nil {
+				return documents, err
+			}
+			failing = append(failing, expr)
+		}
+
+		expr := previewWrongTypeExpr(field)
+		if check.WrongType, err = count(expr); err != nil {
+			return documents, err
+		}
+		failing = append(failing, expr)
+
+		if check.MissingRequired > 0 || check.WrongType > 0 {
+			documents.Fields = append(documents.Fields, check)
+		}
+	}
+
+	if len(documents.Fields) > 0 {
+		if documents.FailingDocuments, err = count(bson.M{"$or": failing}); err != nil {
+			return documents, err
+		}
+	}
+	return documents, nil
+}
+
+// Helper function to list the locales of a localization config that the new one no longer has
+func removedLocales(before, after *models.LocalizationConfig) []string {
+	if before == nil {
+		return nil
+	}
+	kept := make(map[string]bool)
+	if after != nil {
+		kept[after.DefaultLocale] = true
+		for _, locale := range after.Locales {
+			kept[locale] = true
+		}
+	}
+
+	var removed []string
+	for _, locale := range append([]string{before.DefaultLocale}, before.Locales...) {
+		if !kept[locale] {
+			kept[locale] = true
+			removed = append(removed, locale)
+		}
+	}
+	return removed
+}
+
+// Helper function to get the collection holding the users of an authentication schema
+func previewUserCollection(schema *models.Schema) string {
+	if schema.AuthConfig.UserCollection != "" {
+		return schema.AuthConfig.UserCollection
+	}
+	return schema.CollectionName + "_users"
+}
+
+// Helper function to find the relation fields and access rules of other schemas the update breaks.
+// Relations follow collection names, rules read the profile of users of the authentication schema.
+func previewReferences(existing, proposed *models.Schema, diff models.SchemaDiff) ([]models.SchemaPreviewReference, error) {
+	references := []models.SchemaPreviewReference{}
+
+	var others []models.Schema
+	filter := bson.M{"user_id": existing.UserID, "is_active": true, "_id": bson.M{"$ne": existing.ID}}
+	cursor, err := config.DB.Collection("schemas").Find(context.TODO(), filter)
+	if err != nil {
+		return references, err
+	}
+	if err := cursor.All(context.TODO(), &others); err != nil {
+		return references, err
+	}
+
+	authBefore := existing.AuthConfig != nil && existing.AuthConfig.Enabled
+	authAfter := proposed.AuthConfig != nil && proposed.AuthConfig.Enabled
+
+	for _, other := range others {
+		for _, field := range other.Fields {
+			if field.Type != "relation" || field.Target != existing.CollectionName {
+				continue
+			}
+
+			reason := ""
+			switch {
+			case proposed.CollectionName != existing.CollectionName:
+				reason = fmt.Sprintf("Target collection '%s' is renamed to '%s'", existing.CollectionName, proposed.CollectionName)
+			case authBefore && !authAfter:
+				reason = fmt.Sprintf("Target is no longer an authentication schema, related users in '%s' are no longer found", previewUserCollection(existing))
+			case authBefore && previewUserCollection(existing) != previewUserCollection(proposed):
+				reason = fmt.Sprintf("Related users are looked up in '%s' instead of '%s'", previewUserCollection(proposed), previewUserCollection(existing))
+			}
+			if reason != "" {
+				references = append(references, models.SchemaPreviewReference{
+					SchemaID:       other.ID,
+					CollectionName: other.CollectionName,
+					Field:          field.Name,
+					Reason:         reason,
+				})
+			}
+		}
+	}
+
+	// Rules read the auth profile from the users of this schema
+	if !authBefore {
+		return references, nil
+	}
+	for _, other := range append(others, *proposed) {
+		for _, operation := range []string{models.AccessRead, models.AccessCreate, models.AccessUpdate, models.AccessDelete} {
+			source := utils.AccessRuleFor(&other, operation)
+			if source == "" {
+				continue
+			}
+			rule, err := utils.ParseRule(source)
+			if err != nil {
+				continue
+			}
+
+			reason := ""
+			if !authAfter && rule.ReferencesAuthProfile() {
+				reason = "Rule reads the auth profile, which is no longer configured"
+			} else {
+				for _, removed := range diff.RemovedFields {
+					if rule.ReferencesAuthField(removed.Name) {
+						reason = fmt.Sprintf("Rule reads auth.%s, which is removed", removed.Name)
+						break
+					}
+				}
+			}
+			if reason != "" {
+				references = append(references, models.SchemaPreviewReference{
+					SchemaID:       other.ID,
+					CollectionName: other.CollectionName,
+					Operation:      operation,
+					Reason:         reason,
+				})
+			}
+		}
+	}
+	return references, nil
+}
+
+// Helper function to describe what the update does to signup, login and issued tokens
+func previewAuth(db *mongo.Database, existing, proposed *models.Schema) (models.SchemaPreviewAuth, error) {
+	auth := models.SchemaPreviewAuth{
+		EnabledBefore: existing.AuthConfig != nil && existing.AuthConfig.Enabled,
+		EnabledAfter:  proposed.AuthConfig != nil && proposed.AuthConfig.Enabled,
+		Effects:       []string{},
+	}
+	addEffect := func(format string, args ...interface{}) {
+		auth.Effects = append(auth.Effects, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case !auth.EnabledBefore && auth.EnabledAfter:
+		addEffect("Signup and login are enabled for users stored in '%s'", previewUserCollection(proposed))
+
+		var otherAuthSchema models.Schema
+		filter := bson.M{"user_id": existing.UserID, "is_active": true, "auth_config.enabled": true, "_id": bson.M{"$ne": existing.ID}}
+		err := config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&otherAuthSchema)
+		if err == nil {
+			addEffect("'%s' already has authentication, protected endpoints of other schemas may check tokens against either one", otherAuthSchema.CollectionName)
+		} else if err != mongo.ErrNoDocuments {
+			return auth, err
+		}
+
+	case auth.EnabledBefore && !auth.EnabledAfter:
+		userCollection := previewUserCollection(existing)
+		users, err := db.Collection(userCollection).CountDocuments(context.TODO(), bson.M{})
+		if err != nil {
+			return auth, err
+		}
+		addEffect("Signup and login stop working, issued tokens are rejected")
+		addEffect("%d users in '%s' are kept but can no longer sign in", users, userCollection)
+
+		protectedFilter := bson.M{
+			"user_id":   existing.UserID,
+			"is_active": true,
+			"_id":       bson.M{"$ne": existing.ID},
+			"$or": []bson.M{
+				{"endpoint_protection.get": true},
+				{"endpoint_protection.post": true},
+				{"endpoint_protection.put": true},
+				{"endpoint_protection.delete": true},
+			},
+		}
+		protected, err := config.DB.Collection("schemas").CountDocuments(context.TODO(), protectedFilter)
+		if err != nil {
+			return auth, err
+		}
+		if protected > 0 {
+			addEffect("%d schemas with protected endpoints reject every request until another authentication schema exists", protected)
+		}
+
+	case auth.EnabledBefore && auth.EnabledAfter:
+		before, after := existing.AuthConfig, proposed.AuthConfig
+		users := db.Collection(previewUserCollection(proposed))
+
+		if previewUserCollection(existing) != previewUserCollection(proposed) {
+			moved, err := db.Collection(previewUserCollection(existing)).CountDocuments(context.TODO(), bson.M{})
+			if err != nil {
+				return auth, err
+			}
+			addEffect("%d users in '%s' are not moved to '%s' and can no longer sign in", moved, previewUserCollection(existing), previewUserCollection(proposed))
+		}
+
+		// Users keep the fields they signed up with, renamed login fields leave them without one
+		loginFields := []struct{ name, before, after string }{
+			{"email", before.LoginFields.EmailField, after.LoginFields.EmailField},
+			{"username", before.LoginFields.UsernameField, after.LoginFields.UsernameField},
+			{"password", before.PasswordField, after.PasswordField},
+		}
+		for _, field := range loginFields {
+			if field.after == "" || field.after == field.before {
+				continue
+			}
+			missing, err := users.CountDocuments(context.TODO(), bson.M{field.after: bson.M{"$exists": false}})
+			if err != nil {
+				return auth, err
+			}
+			addEffect("The %s field changes from '%s' to '%s', %d users have no '%s' and cannot sign in", field.name, field.before, field.after, missing, field.after)
+		}
+
+		for _, role := range before.Roles {
+			if utils.HasAnyRole(after.Roles, []string{role}) {
+				continue
+			}
+			holders, err := users.CountDocuments(context.TODO(), bson.M{models.AuthRolesField: role})
+			if err != nil {
+				return auth, err
+			}
+			addEffect("Role '%s' is removed, %d users holding it lose the access it grants", role, holders)
+		}
+
+		if before.TokenExpiration != after.TokenExpiration {
+			addEffect("New tokens expire after %d hours, issued tokens keep their expiry", after.TokenExpiration)
+		}
+		if before.TenantField != after.TenantField {
+			addEffect("New tokens carry the tenant from '%s', issued tokens keep the tenant they were issued with", after.TenantField)
+		}
+		if before.AllowSignup != after.AllowSignup {
+			if after.AllowSignup {
+				addEffect("Signup is opened")
+			} else {
+				addEffect("Signup is closed, existing users can still sign in")
+			}
+		}
+	}
+	return auth, nil
+}
+
+// Helper function to compare the managed indexes the new definition needs with the ones that exist
+func previewIndexes(db *mongo.Database, proposed *models.Schema) (models.SchemaPreviewIndexes, error) {
+	indexes := models.SchemaPreviewIndexes{Created: []string{}, Dropped: []string{}, Unchanged: []string{}}
+
+	existing := make(map[string]bool)
+	listIndexNames := func(collectionName string) error {
+		// Listing fails when the collection does not exist yet, in which case there is no index
+		cursor, err := db.Collection(collectionName).Indexes().List(context.TODO())
+		if err != nil {
+			return nil
+		}
+		var list []bson.M
+		if err := cursor.All(context.TODO(), &list); err != nil {
+			return err
+		}
+		for _, index := range list {
+			if name, _ := index["name"].(string); strings.HasPrefix(name, schemaIndexPrefix) {
+				existing[name] = true
+			}
+		}
+		return nil
+	}
+	if err := listIndexNames(proposed.CollectionName); err != nil {
+		return indexes, err
+	}
+
+	desired := make(map[string]bool)
+	for _, index := range schemaIndexModels(*proposed) {
+		desired[*index.Options.Name] = true
+	}
+	for name := range desired {
+		if existing[name] {
+			indexes.Unchanged = append(indexes.Unchanged, name)
+		} else {
+			indexes.Created = append(indexes.Created, name)
+		}
+	}
+	for name := range existing {
+		if !desired[name] {
+			indexes.Dropped = append(indexes.Dropped, name)
+		}
+	}
+
+	// The version index of the history collection is created but never dropped
+	if proposed.History {
+		existing = make(map[string]bool)
+		if err := listIndexNames(historyCollectionName(proposed.CollectionName)); err != nil {
+			return indexes, err
+		}
+		name := schemaIndexPrefix + "history_version"
+		if existing[name] {
+			indexes.Unchanged = append(indexes.Unchanged, name)
+		} else {
+			indexes.Created = append(indexes.Created, name)
+		}
+	}
+
+	sort.Strings(indexes.Created)
+	sort.Strings(indexes.Dropped)
+	sort.Strings(indexes.Unchanged)
+	return indexes, nil
+}
+
+// @Summary Preview schema update
+// @Description Report what an update would break without saving it: stored documents failing the new validation, relations and rules of other schemas pointing at renamed collections or removed fields, effects on authentication and the indexes that would change
+// @Tags schema
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param request body models.CreateSchemaRequest true "Updated schema data"
+// @Success 200 {object} models.SchemaPreview
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 409 "Conflict"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/preview [post]
+func (sc *SchemaController) PreviewSchemaUpdate(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, ok := connectedUser(c, userID)
+	if !ok {
+		return
+	}
+
+	existingSchema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	var req models.CreateSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The update would be rejected the same way, so nothing would break
+	authConfig, status, err := sc.validateSchemaUpdate(user, *existingSchema, &req)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	proposedSchema := *existingSchema
+	proposedSchema.CollectionName = req.CollectionName
+	proposedSchema.Fields = req.Fields
+	proposedSchema.AuthConfig = authConfig
+	proposedSchema.EndpointProtection = req.EndpointProtection
+	proposedSchema.Expiry = req.Expiry
+	proposedSchema.Localization = req.Localization
+	proposedSchema.Publishable = req.Publishable
+	proposedSchema.History = req.History
+	proposedSchema.BeforeWrite = req.BeforeWrite
+	proposedSchema.Rules = req.Rules
+	proposedSchema.TenantField = req.TenantField
+
+	from, to := schemaDefinition(*existingSchema), schemaDefinition(proposedSchema)
+	diff := utils.DiffSchemas(&from, &to)
+
+	preview := models.SchemaPreview{
+		Diff:      diff,
+		Migration: utils.PlanMigration(diff),
+		Warnings:  []string{},
+	}
+	if preview.Migration == nil {
+		preview.Migration = []models.MigrationOperation{}
+	}
+
+	if proposedSchema.CollectionName != existingSchema.CollectionName {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("Stored documents stay in '%s', the API serves '%s' after the update", existingSchema.CollectionName, proposedSchema.CollectionName))
+	}
+	if existingSchema.TenantField != "" && proposedSchema.TenantField != existingSchema.TenantField {
+		preview.Warnings = append(preview.Warnings, "Documents are partitioned by a different tenant field, existing documents may become visible to other tenants or to none")
+	}
+	if removed := removedLocales(existingSchema.Localization, proposedSchema.Localization); len(removed) > 0 {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("Stored translations for %s are kept but no longer served", strings.Join(removed, ", ")))
+	}
+
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		return
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	if preview.Documents, err = previewDocuments(db, existingSchema, &proposedSchema); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check stored documents"})
+		return
+	}
+	if preview.Relations, err = previewReferences(existingSchema, &proposedSchema, diff); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check related schemas"})
+		return
+	}
+	if preview.Auth, err = previewAuth(db, existingSchema, &proposedSchema); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check authentication"})
+		return
+	}
+	if preview.Indexes, err = previewIndexes(db, &proposedSchema); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check indexes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
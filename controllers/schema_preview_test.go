@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+)
+
+func TestRemovedLocalesListsDroppedTranslations(t *testing.T) {
+	before := &models.LocalizationConfig{DefaultLocale: "en", Locales: []string{"de", "fr", "en"}}
+	after := &models.LocalizationConfig{DefaultLocale: "de", Locales: []string{"en"}}
+	if got := removedLocales(before, after); !reflect.DeepEqual(got, []string{"fr"}) {
+		t.Errorf("removed locales = %v, want [fr]", got)
+	}
+	if got := removedLocales(before, nil); !reflect.DeepEqual(got, []string{"en", "de", "fr"}) {
+		t.Errorf("removed locales without localization = %v, want [en de fr]", got)
+	}
+	if got := removedLocales(nil, after); got != nil {
+		t.Errorf("removed locales of a new localization = %v, want none", got)
+	}
+}
+
+func TestRulesReadingRemovedAuthFieldsAreFound(t *testing.T) {
+	rule, err := utils.ParseRule("auth.plan == 'pro' && auth.tenant == doc.org")
+	if err != nil {
+		t.Fatalf("failed to parse rule: %v", err)
+	}
+	if !rule.ReferencesAuthField("plan") {
+		t.Error("rule reading auth.plan was not reported")
+	}
+	if rule.ReferencesAuthField("tenant") || rule.ReferencesAuthField("email") {
+		t.Error("token claims or unused fields were reported as profile fields")
+	}
+}
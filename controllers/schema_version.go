@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// schemaVersionRecordAttempts bounds retries when concurrent changes pick the same version number
+const schemaVersionRecordAttempts = 3
+
+var schemaVersionIndexesOnce sync.Once
+
+// Helper function to create the unique version index of the schema_versions collection
+func ensureSchemaVersionIndexes() {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "schema_id", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := config.DB.Collection("schema_versions").Indexes().CreateOne(context.TODO(), index); err != nil {
+		fmt.Printf("Warning: Failed to create schema version indexes: %v\n", err)
+	}
+}
+
+// Helper function to get the definition of a schema recorded by versions. Versions don't keep
+// copies of the JWT secret, rollbacks keep the current one.
+func schemaDefinition(schema models.Schema) models.SchemaDefinition {
+	definition := models.SchemaDefinition{
+		CollectionName:     schema.CollectionName,
+		Fields:             schema.Fields,
+		EndpointProtection: schema.EndpointProtection,
+		Expiry:             schema.Expiry,
+		Localization:       schema.Localization,
+		Publishable:        schema.Publishable,
+		History:            schema.History,
+		BeforeWrite:        schema.BeforeWrite,
+		Rules:              schema.Rules,
+		TenantField:        schema.TenantField,
+	}
+	if schema.AuthConfig != nil {
+		authConfig := *schema.AuthConfig
+		authConfig.JWTSecret = ""
+		definition.AuthConfig = &authConfig
+	}
+	return definition
+}
+
+// Helper function to get the author of a schema change from the JWT claims
+func schemaVersionAuthor(c *gin.Context) *models.SchemaVersionAuthor {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return nil
+	}
+	id, ok := userID.(primitive.ObjectID)
+	if !ok {
+		return nil
+	}
+	return &models.SchemaVersionAuthor{ID: id, Email: c.GetString("user_email")}
+}
+
+// Helper function to record the current definition of a schema as its next version. Schemas
+// changed for the first time since versions were introduced get their previous definition as
+// version 1. Returns the recorded version number, 0 when recording failed.
+func recordSchemaVersion(c *gin.Context, schema models.Schema, previous *models.Schema, action models.SchemaVersionAction, rolledBackFrom int) int {
+	schemaVersionIndexesOnce.Do(ensureSchemaVersionIndexes)
+
+	entry := models.SchemaVersion{
+		SchemaID:       schema.ID,
+		UserID:         schema.UserID,
+		Action:         action,
+		Definition:     schemaDefinition(schema),
+		Author:         schemaVersionAuthor(c),
+		RolledBackFrom: rolledBackFrom,
+		CreatedAt:      time.Now(),
+	}
+
+	collection := config.DB.Collection("schema_versions")
+	var err error
+	for attempt := 0; attempt < schemaVersionRecordAttempts; attempt++ {
+		var latest models.SchemaVersion
+		findOptions := options.FindOne().SetSort(bson.M{"version": -1}).SetProjection(bson.M{"version": 1})
+		latestErr := collection.FindOne(context.TODO(), bson.M{"schema_id": schema.ID}, findOptions).Decode(&latest)
+		if latestErr != nil && latestErr != mongo.ErrNoDocuments {
+			err = latestErr
+			break
+		}
+
+		if latest.Version == 0 && previous != nil {
+			baseline := models.SchemaVersion{
+				ID:         primitive.NewObjectID(),
+				SchemaID:   previous.ID,
+				UserID:     previous.UserID,
+				Version:    1,
+				Action:     models.SchemaVersionActionCreate,
+				Definition: schemaDefinition(*previous),
+				CreatedAt:  previous.UpdatedAt,
+			}
+			if _, err = collection.InsertOne(context.TODO(), baseline); err != nil && !mongo.IsDuplicateKeyError(err) {
+				break
+			}
+			latest.Version = 1
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.Version = latest.Version + 1
+		if _, err = collection.InsertOne(context.TODO(), entry); err == nil {
+			return entry.Version
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+
+	fmt.Printf("Warning: Failed to record version of schema %s: %v\n", schema.ID.Hex(), err)
+	return 0
+}
+
+// Helper function to find an active schema of the signed-in user by the id path parameter.
+// After an error response has been written it returns false.
+func (sc *SchemaController) ownedSchema(c *gin.Context, userID interface{}) (*models.Schema, bool) {
+	schemaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return nil, false
+	}
+
+	var schema models.Schema
+	filter := bson.M{"_id": schemaID, "user_id": userID, "is_active": true}
+	err = config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&schema)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return &schema, true
+}
+
+// Helper function to load one version of a schema. Version 0 or less loads the latest one.
+func findSchemaVersion(schemaID primitive.ObjectID, version int) (*models.SchemaVersion, error) {
+	filter := bson.M{"schema_id": schemaID}
+	findOptions := options.FindOne().SetSort(bson.M{"version": -1})
+	if version > 0 {
+		filter["version"] = version
+	}
+
+	var entry models.SchemaVersion
+	if err := config.DB.Collection("schema_versions").FindOne(context.TODO(), filter, findOptions).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Helper function to load the version named by a path or query parameter.
+// After an error response has been written it returns false.
+func loadSchemaVersion(c *gin.Context, schemaID primitive.ObjectID, value string) (*models.SchemaVersion, bool) {
+	version := 0
+	if value != "" {
+		var err error
+		version, err = strconv.Atoi(value)
+		if err != nil || version < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version: " + value})
+			return nil, false
+		}
+	}
+
+	entry, err := findSchemaVersion(schemaID, version)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schema version not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schema version"})
+		}
+		return nil, false
+	}
+	return entry, true
+}
+
+// @Summary Get schema versions
+// @Description List the recorded definitions of a schema, newest first
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/versions [get]
+func (sc *SchemaController) GetSchemaVersions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	skip := (page - 1) * limit
+
+	filter := bson.M{"schema_id": schema.ID}
+	findOptions := options.Find().
+		SetSort(bson.M{"version": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	collection := config.DB.Collection("schema_versions")
+	cursor, err := collection.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schema versions"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	versions := []models.SchemaVersion{}
+	if err := cursor.All(context.TODO(), &versions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode schema versions"})
+		return
+	}
+
+	total, err := collection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		total = 0
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": versions,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// @Summary Get schema version
+// @Description Get one recorded definition of a schema
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param version path int true "Schema version"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/versions/{version} [get]
+func (sc *SchemaController) GetSchemaVersion(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	entry, ok := loadSchemaVersion(c, schema.ID, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// @Summary Diff schema versions
+// @Description Compare two recorded definitions of a schema: added, removed and changed fields plus changed schema options
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param from query int false "Older version (default: the version before to)"
+// @Param to query int false "Newer version (default: the latest version)"
+// @Success 200 {object} models.SchemaDiff
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/versions/diff [get]
+func (sc *SchemaController) DiffSchemaVersions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	to, ok := loadSchemaVersion(c, schema.ID, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	fromValue := c.Query("from")
+	if fromValue == "" {
+		if to.Version == 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Version 1 has no previous version to compare with"})
+			return
+		}
+		fromValue = strconv.Itoa(to.Version - 1)
+	}
+	from, ok := loadSchemaVersion(c, schema.ID, fromValue)
+	if !ok {
+		return
+	}
+
+	diff := utils.DiffSchemas(&from.Definition, &to.Definition)
+	diff.FromVersion = from.Version
+	diff.ToVersion = to.Version
+	c.JSON(http.StatusOK, diff)
+}
+
+// @Summary Roll back schema
+// @Description Restore the definition of a schema from a version. The rollback is validated like an update and recorded as a new version. The collection name and secrets stay as they are.
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param version path int true "Schema version to restore"
+// @Success 200 "Success"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 409 "Conflict"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/versions/{version}/rollback [post]
+func (sc *SchemaController) RollbackSchema(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
+		return
+	}
+
+	if user.MongoDBURI == "" || user.DatabaseName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please first add a MongoDB connection"})
+		return
+	}
+
+	schema, ok := sc.ownedSchema(c, userID)
+	if !ok {
+		return
+	}
+
+	entry, ok := loadSchemaVersion(c, schema.ID, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	// Renaming the collection back would detach the documents stored since, so the name stays
+	definition := entry.Definition
+	req := models.CreateSchemaRequest{
+		CollectionName:     schema.CollectionName,
+		Fields:             definition.Fields,
+		AuthConfig:         definition.AuthConfig,
+		EndpointProtection: definition.EndpointProtection,
+		Expiry:             definition.Expiry,
+		Localization:       definition.Localization,
+		Publishable:        definition.Publishable,
+		History:            definition.History,
+		BeforeWrite:        definition.BeforeWrite,
+		Rules:              definition.Rules,
+		TenantField:        definition.TenantField,
+	}
+	// Hook receivers verify with the current secret
+	if req.BeforeWrite != nil && schema.BeforeWrite != nil {
+		req.BeforeWrite.Secret = ""
+	}
+
+	updatedSchema, status, err := sc.applySchemaUpdate(user, *schema, &req)
+	if err != nil {
+		c.JSON(status, gin.H{"error": "Cannot roll back to version " + strconv.Itoa(entry.Version) + ": " + err.Error()})
+		return
+	}
+
+	version := recordSchemaVersion(c, *updatedSchema, schema, models.SchemaVersionActionRollback, entry.Version)
+
+	go LogActivityWithContext(c, user.ID, models.ActivityTypeUpdate, "Rolled back table \""+schema.CollectionName+"\"", "Database table schema restored from version "+strconv.Itoa(entry.Version), "schema", schema.ID.Hex(), map[string]any{
+		"collection_name": schema.CollectionName,
+		"from_version":    entry.Version,
+		"action":          "rolled_back",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Schema rolled back to version " + strconv.Itoa(entry.Version),
+		"version": version,
+		"schema":  updatedSchema,
+	})
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxSeedDocuments caps the number of documents generated by one seed request
+	maxSeedDocuments = 5000
+	// maxSeedRelationIDs caps the number of target IDs loaded for each relation field
+	maxSeedRelationIDs = 1000
+	// seedOptionalChance is the probability that an optional field is left to its default
+	seedOptionalChance = 0.2
+)
+
+// Helper function to load the IDs a relation field can point to, ordered so seeding is reproducible
+func (sc *SchemaController) seedRelationIDs(db *mongo.Database, userID primitive.ObjectID, field models.SchemaField) ([]primitive.ObjectID, error) {
+	collectionName := field.Target
+	filter := bson.M{"user_id": userID}
+
+	var targetSchema models.Schema
+	targetFilter := bson.M{"user_id": userID, "collection_name": field.Target, "is_active": true}
+	if err := config.DB.Collection("schemas").FindOne(context.TODO(), targetFilter).Decode(&targetSchema); err == nil &&
+		targetSchema.AuthConfig != nil && targetSchema.AuthConfig.Enabled {
+		// Authentication collections keep their users in a separate collection without user_id
+		collectionName = targetSchema.AuthConfig.UserCollection
+		if collectionName == "" {
+			collectionName = field.Target + "_users"
+		}
+		filter = bson.M{}
+	}
+
+	findOptions := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.M{"_id": 1}).
+		SetLimit(maxSeedRelationIDs)
+	cursor, err := db.Collection(collectionName).Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var documents []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &documents); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(documents))
+	for i, document := range documents {
+		ids[i] = document.ID
+	}
+	return ids, nil
+}
+
+// Helper function to generate the data of one fake document
+func seedDocumentData(seeder *utils.Seeder, schema *models.Schema, relationIDs map[string][]primitive.ObjectID) map[string]interface{} {
+	data := make(map[string]interface{})
+	for _, field := range schema.Fields {
+		// File content is uploaded separately, so file fields stay empty
+		if field.Type == "file" {
+			continue
+		}
+
+		// Leave some optional fields out so defaults and missing values are represented
+		if !field.Required && seeder.Chance(seedOptionalChance) {
+			if field.Default != nil {
+				data[field.Name] = field.Default
+			}
+			continue
+		}
+
+		if field.Type == "relation" {
+			ids := relationIDs[field.Name]
+			if len(ids) > 0 {
+				data[field.Name] = ids[seeder.Intn(len(ids))]
+			}
+			continue
+		}
+
+		// The expiry field must be a future BSON date, otherwise the TTL index removes the document right away
+		if schema.Expiry != nil && schema.Expiry.Field == field.Name {
+			data[field.Name] = time.Now().Add(time.Duration(1+seeder.Intn(30*24)) * time.Hour)
+			continue
+		}
+
+		data[field.Name] = seeder.FieldValue(field, schema.Localization)
+	}
+	return data
+}
+
+// @Summary Seed schema with fake data
+// @Description Generate fake documents that match the schema field types, defaults and relations. Relation fields point to existing documents of the target collection. Pass the same seed to reproduce the generated values.
+// @Tags schema
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param count query int false "Number of documents to generate (default: 10, max: 5000)"
+// @Param seed query int false "Random seed for reproducible data (default: random)"
+// @Success 201 "Created"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/seed [post]
+func (sc *SchemaController) SeedSchema(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaIDStr := c.Param("id")
+	schemaID, err := primitive.ObjectIDFromHex(schemaIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "10"))
+	if err != nil || count < 1 || count > maxSeedDocuments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", maxSeedDocuments)})
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	if seedStr := c.Query("seed"); seedStr != "" {
+		seed, err = strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seed must be an integer"})
+			return
+		}
+	}
+
+	// Get user info to check if MongoDB URI is configured
+	var user models.User
+	err = config.DB.Collection("users").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
+		return
+	}
+
+	// Check if user has configured MongoDB URI
+	if user.MongoDBURI == "" || user.DatabaseName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please first add a MongoDB connection"})
+		return
+	}
+
+	var schema models.Schema
+	filter := bson.M{"_id": schemaID, "user_id": userID, "is_active": true}
+	err = config.DB.Collection("schemas").FindOne(context.TODO(), filter).Decode(&schema)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find schema"})
+		}
+		return
+	}
+
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Authentication collections cannot be seeded, create users through the signup endpoint"})
+		return
+	}
+
+	db, err := config.GetUserDatabase(user.MongoDBURI, user.DatabaseName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error: " + err.Error()})
+		return
+	}
+	defer db.Client().Disconnect(context.TODO())
+
+	// Relation fields pick from documents that already exist in the target collection
+	relationIDs := make(map[string][]primitive.ObjectID)
+	for _, field := range schema.Fields {
+		if field.Type != "relation" || field.Target == "" {
+			continue
+		}
+
+		ids, err := sc.seedRelationIDs(db, user.ID, field)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load documents of target collection: " + field.Target})
+			return
+		}
+		if len(ids) == 0 && field.Required {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Target collection '" + field.Target + "' has no documents for required relation field: " + field.Name})
+			return
+		}
+		relationIDs[field.Name] = ids
+	}
+
+	seeder := utils.NewSeeder(seed)
+	collection := db.Collection(schema.CollectionName)
+	inserted := 0
+
+	for inserted < count {
+		batchSize := count - inserted
+		if batchSize > importBatchSize {
+			batchSize = importBatchSize
+		}
+
+		now := time.Now()
+		documents := make([]interface{}, batchSize)
+		for i := range documents {
+			document := models.DynamicData{
+				ID:        primitive.NewObjectID(),
+				Data:      seedDocumentData(seeder, &schema, relationIDs),
+				UserID:    user.ID,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if schema.Publishable {
+				document.Status = models.DocumentStatusDraft
+			}
+			documents[i] = document
+		}
+
+		if _, err := collection.InsertMany(context.TODO(), documents); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert seed documents", "inserted": inserted})
+			return
+		}
+		inserted += batchSize
+	}
+
+	go LogActivityWithContext(c, user.ID, models.ActivityTypeCreate, fmt.Sprintf("Seeded %d documents into \"%s\"", inserted, schema.CollectionName), "Fake data generated from schema", "schema", schema.ID.Hex(), map[string]any{
+		"collection_name": schema.CollectionName,
+		"count":           inserted,
+		"seed":            seed,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Seed data created successfully",
+		"collection": schema.CollectionName,
+		"inserted":   inserted,
+		"seed":       seed,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/M-awais-rasool/SchemaCraft-go/config"
 	"github.com/M-awais-rasool/SchemaCraft-go/models"
@@ -270,6 +271,12 @@ func (uc *UserController) GetAPIDocumentation(c *gin.Context) {
 					"description": "Create a new user account in the " + collectionName + " authentication system",
 					"tags":        []string{collectionName + " Auth"},
 					"parameters": []gin.H{
+						{
+							"name":        "Idempotency-Key",
+							"in":          "header",
+							"type":        "string",
+							"description": "Unique key for safe retries. A repeated key returns the stored successful response, a key reused with a different body returns 422. Failed requests do not use up the key",
+						},
 						{
 							"name":        "body",
 							"in":          "body",
@@ -443,11 +450,66 @@ func (uc *UserController) GetAPIDocumentation(c *gin.Context) {
 			},
 		}
 
+		// GET /api/{collection}/export
+		exportEndpoint := gin.H{
+			"summary":     "Export " + collectionName,
+			"description": "Stream all matching documents as CSV, NDJSON or a JSON array",
+			"tags":        []string{collectionName},
+			"produces":    []string{"text/csv", "application/x-ndjson", "application/json"},
+			"parameters": []gin.H{
+				{
+					"name":        "format",
+					"in":          "query",
+					"type":        "string",
+					"enum":        []string{"csv", "ndjson", "json"},
+					"description": "Export format (default: ndjson)",
+				},
+				{
+					"name":        "fields",
+					"in":          "query",
+					"type":        "string",
+					"description": "Comma separated list of fields to export",
+				},
+			},
+			"responses": gin.H{
+				"200": gin.H{"description": "Success"},
+				"400": gin.H{"description": "Bad Request"},
+				"401": gin.H{"description": "Unauthorized"},
+			},
+		}
+
+		// GET /api/{collection}/stream
+		streamEndpoint := gin.H{
+			"summary":     "Stream " + collectionName + " changes",
+			"description": "Receive insert, update and delete events as Server-Sent Events. Reconnecting clients resume from the Last-Event-ID header. Requires a MongoDB replica set",
+			"tags":        []string{collectionName},
+			"produces":    []string{"text/event-stream"},
+			"parameters": []gin.H{
+				{
+					"name":        "Last-Event-ID",
+					"in":          "header",
+					"type":        "string",
+					"description": "ID of the last received event to resume from",
+				},
+			},
+			"responses": gin.H{
+				"200": gin.H{"description": "Event stream"},
+				"400": gin.H{"description": "Bad Request"},
+				"401": gin.H{"description": "Unauthorized"},
+			},
+		}
+
 		postEndpoint := gin.H{
 			"summary":     "Create " + collectionName,
 			"description": "Create a new document in the " + collectionName + " collection",
 			"tags":        []string{collectionName},
 			"parameters": []gin.H{
+				{
+					"name":        "Idempotency-Key",
+					"in":          "header",
+					"type":        "string",
+					"description": "Unique key for safe retries. A repeated key returns the stored successful response, a key reused with a different body returns 422. Failed requests do not use up the key",
+				},
 				{
 					"name":        "body",
 					"in":          "body",
@@ -466,11 +528,82 @@ func (uc *UserController) GetAPIDocumentation(c *gin.Context) {
 			},
 		}
 
+		// Mention automatic expiry so API consumers know documents are temporary
+		if expiry := describeExpiry(schema); expiry != "" {
+			getEndpoint["description"] = getEndpoint["description"].(string) + ". " + expiry
+			postEndpoint["description"] = postEndpoint["description"].(string) + ". " + expiry
+		}
+
+		// Explain which version readers get for publishable collections
+		if schema.Publishable {
+			getEndpoint["description"] = getEndpoint["description"].(string) + ". Only published documents are returned unless the request is authenticated"
+			statusParameter := gin.H{
+				"name":        "status",
+				"in":          "query",
+				"type":        "string",
+				"enum":        []string{models.DocumentStatusDraft, models.DocumentStatusPublished, models.DocumentStatusScheduled},
+				"description": "Filter by status, authenticated requests only",
+			}
+			getEndpoint["parameters"] = append(getEndpoint["parameters"].([]gin.H), statusParameter)
+			exportEndpoint["parameters"] = append(exportEndpoint["parameters"].([]gin.H), statusParameter)
+			streamEndpoint["parameters"] = append(streamEndpoint["parameters"].([]gin.H), statusParameter)
+			postEndpoint["description"] = postEndpoint["description"].(string) + ". New documents are created as drafts"
+		}
+
+		// Document locale selection for collections with localized fields
+		localeParameter := gin.H{
+			"name":        "locale",
+			"in":          "query",
+			"type":        "string",
+			"description": "Locale for localized fields, falls back to the Accept-Language header. Use 'all' to get every translation",
+		}
+		hasLocalizedFields := schemaHasLocalizedFields(schema)
+		if hasLocalizedFields {
+			getEndpoint["parameters"] = append(getEndpoint["parameters"].([]gin.H), localeParameter)
+			exportEndpoint["parameters"] = append(exportEndpoint["parameters"].([]gin.H), localeParameter)
+			streamEndpoint["parameters"] = append(streamEndpoint["parameters"].([]gin.H), localeParameter)
+		}
+
+		// Document geospatial query parameters for collections with location fields
+		if schemaHasFieldType(schema, "geopoint") {
+			geoParameters := []gin.H{
+				{
+					"name":        "near",
+					"in":          "query",
+					"type":        "string",
+					"description": "Sort results by distance from a point (lat,lng). Each result gets a distance field in meters",
+				},
+				{
+					"name":        "max_distance",
+					"in":          "query",
+					"type":        "number",
+					"description": "Maximum distance in meters, used with near",
+				},
+				{
+					"name":        "within",
+					"in":          "query",
+					"type":        "string",
+					"description": "Only return documents inside a bounding box (minLat,minLng,maxLat,maxLng) or polygon (lat,lng;lat,lng;...)",
+				},
+				{
+					"name":        "geo_field",
+					"in":          "query",
+					"type":        "string",
+					"description": "Geopoint field to query (defaults to the first one)",
+				},
+			}
+			getEndpoint["parameters"] = append(getEndpoint["parameters"].([]gin.H), geoParameters...)
+			exportEndpoint["parameters"] = append(exportEndpoint["parameters"].([]gin.H), geoParameters...)
+			streamEndpoint["parameters"] = append(streamEndpoint["parameters"].([]gin.H), geoParameters...)
+		}
+
 		// Add authentication requirement if endpoint is protected
-		if schema.EndpointProtection != nil && schema.EndpointProtection.Get {
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessRead) || utils.DynamicEndpointOwnerOnly(&schema, models.AccessRead) {
 			getEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			exportEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			streamEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
 		}
-		if schema.EndpointProtection != nil && schema.EndpointProtection.Post {
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessCreate) {
 			postEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
 		}
 
@@ -478,6 +611,8 @@ func (uc *UserController) GetAPIDocumentation(c *gin.Context) {
 			"get":  getEndpoint,
 			"post": postEndpoint,
 		}
+		paths["/"+collectionName+"/export"] = gin.H{"get": exportEndpoint}
+		paths["/"+collectionName+"/stream"] = gin.H{"get": streamEndpoint}
 
 		// GET/PUT/DELETE /api/{collection}/{id}
 		getByIdEndpoint := gin.H{
@@ -553,14 +688,18 @@ func (uc *UserController) GetAPIDocumentation(c *gin.Context) {
 			},
 		}
 
+		if hasLocalizedFields {
+			getByIdEndpoint["parameters"] = append(getByIdEndpoint["parameters"].([]gin.H), localeParameter)
+		}
+
 		// Add authentication requirement if endpoint is protected
-		if schema.EndpointProtection != nil && schema.EndpointProtection.Get {
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessRead) || utils.DynamicEndpointOwnerOnly(&schema, models.AccessRead) {
 			getByIdEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
 		}
-		if schema.EndpointProtection != nil && schema.EndpointProtection.Put {
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessUpdate) || utils.DynamicEndpointOwnerOnly(&schema, models.AccessUpdate) {
 			putEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
 		}
-		if schema.EndpointProtection != nil && schema.EndpointProtection.Delete {
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessDelete) || utils.DynamicEndpointOwnerOnly(&schema, models.AccessDelete) {
 			deleteEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
 		}
 
@@ -569,6 +708,451 @@ func (uc *UserController) GetAPIDocumentation(c *gin.Context) {
 			"put":    putEndpoint,
 			"delete": deleteEndpoint,
 		}
+
+		// File endpoints for each file field
+		for _, field := range schema.Fields {
+			if field.Type != "file" {
+				continue
+			}
+
+			fileParameters := []gin.H{
+				{
+					"name":        "id",
+					"in":          "path",
+					"required":    true,
+					"type":        "string",
+					"description": "Document ID",
+				},
+			}
+
+			constraints := fmt.Sprintf("Maximum size: %d bytes.", field.MaxSize)
+			if field.MaxSize == 0 {
+				constraints = fmt.Sprintf("Maximum size: %d bytes.", defaultMaxFileSize)
+			}
+			if len(field.AllowedTypes) > 0 {
+				constraints += " Allowed types: " + strings.Join(field.AllowedTypes, ", ") + "."
+			}
+
+			uploadEndpoint := gin.H{
+				"summary":     "Upload " + field.Name + " for " + collectionName,
+				"description": "Upload a file for the " + field.Name + " field, replacing any existing file. " + constraints,
+				"tags":        []string{collectionName},
+				"consumes":    []string{"multipart/form-data"},
+				"parameters": append(append([]gin.H{}, fileParameters...), gin.H{
+					"name":        "file",
+					"in":          "formData",
+					"required":    true,
+					"type":        "file",
+					"description": "File to upload",
+				}),
+				"responses": gin.H{
+					"201": gin.H{"description": "Created"},
+					"400": gin.H{"description": "Bad Request"},
+					"404": gin.H{"description": "Not Found"},
+					"413": gin.H{"description": "File too large"},
+					"415": gin.H{"description": "File type not allowed"},
+				},
+			}
+
+			downloadEndpoint := gin.H{
+				"summary":     "Download " + field.Name + " from " + collectionName,
+				"description": "Download the stored file. Single byte ranges are supported through the Range header.",
+				"tags":        []string{collectionName},
+				"produces":    []string{"application/octet-stream"},
+				"parameters": append(append([]gin.H{}, fileParameters...), gin.H{
+					"name":        "download",
+					"in":          "query",
+					"type":        "boolean",
+					"description": "Send as attachment instead of inline",
+				}),
+				"responses": gin.H{
+					"200": gin.H{"description": "File content"},
+					"206": gin.H{"description": "Partial content"},
+					"404": gin.H{"description": "Not Found"},
+					"416": gin.H{"description": "Range not satisfiable"},
+				},
+			}
+
+			deleteFileEndpoint := gin.H{
+				"summary":     "Delete " + field.Name + " from " + collectionName,
+				"description": "Delete the stored file",
+				"tags":        []string{collectionName},
+				"parameters":  fileParameters,
+				"responses": gin.H{
+					"200": gin.H{"description": "Success"},
+					"404": gin.H{"description": "Not Found"},
+				},
+			}
+
+			// Uploads and deletes change the document, so they follow update protection
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessUpdate) {
+				uploadEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+				deleteFileEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessRead) {
+				downloadEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+
+			paths["/"+collectionName+"/{id}/files/"+field.Name] = gin.H{
+				"post":   uploadEndpoint,
+				"get":    downloadEndpoint,
+				"delete": deleteFileEndpoint,
+			}
+		}
+
+		// Bulk import endpoints
+		importEndpoint := gin.H{
+			"summary":     "Import " + collectionName,
+			"description": "Import a CSV, JSON array or NDJSON file. Rows are validated against the schema and failures are reported per row. Files over 1 MB run as a background job",
+			"tags":        []string{collectionName},
+			"consumes":    []string{"multipart/form-data", "text/csv", "application/json", "application/x-ndjson"},
+			"parameters": []gin.H{
+				{
+					"name":        "file",
+					"in":          "formData",
+					"type":        "file",
+					"description": "File to import, the raw request body is used when omitted",
+				},
+				{
+					"name":        "format",
+					"in":          "query",
+					"type":        "string",
+					"enum":        []string{"csv", "json", "ndjson"},
+					"description": "Detected from the file name or content type when omitted",
+				},
+				{
+					"name":        "mapping",
+					"in":          "query",
+					"type":        "string",
+					"description": "CSV column mapping as JSON, e.g. {\"Full Name\":\"name\"}. Columns match field names by default",
+				},
+				{
+					"name":        "async",
+					"in":          "query",
+					"type":        "boolean",
+					"description": "Run as a background job regardless of the file size",
+				},
+			},
+			"responses": gin.H{
+				"200": gin.H{"description": "Import finished with a row error report"},
+				"202": gin.H{"description": "Import job started"},
+				"400": gin.H{"description": "Bad Request"},
+				"413": gin.H{"description": "File too large"},
+			},
+		}
+
+		importJobEndpoint := gin.H{
+			"summary":     "Get " + collectionName + " import job",
+			"description": "Get the progress and row errors of a background import",
+			"tags":        []string{collectionName},
+			"parameters": []gin.H{
+				{
+					"name":        "job_id",
+					"in":          "path",
+					"required":    true,
+					"type":        "string",
+					"description": "Import job ID",
+				},
+			},
+			"responses": gin.H{
+				"200": gin.H{"description": "Success"},
+				"404": gin.H{"description": "Not Found"},
+			},
+		}
+
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessCreate) {
+			importEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+		}
+		if utils.DynamicEndpointRequiresAuth(&schema, models.AccessRead) {
+			importJobEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+		}
+
+		if schema.AuthConfig == nil || !schema.AuthConfig.Enabled {
+			paths["/"+collectionName+"/import"] = gin.H{"post": importEndpoint}
+			paths["/"+collectionName+"/import/{job_id}"] = gin.H{"get": importJobEndpoint}
+		}
+
+		// Custom endpoint functions
+		endpointFunctions, err := findSchemaFunctions(schema.ID, models.FunctionTriggerEndpoint)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load functions for %s: %v\n", collectionName, err)
+		}
+		for _, function := range endpointFunctions {
+			functionEndpoint := gin.H{
+				"summary":     "Call " + function.Name,
+				"description": "Run the " + function.Name + " function. Query parameters and the JSON body are passed to the function, its return value is sent as result",
+				"tags":        []string{collectionName},
+				"parameters": []gin.H{
+					{
+						"name":     "body",
+						"in":       "body",
+						"required": false,
+						"schema":   gin.H{"type": "object"},
+					},
+				},
+				"responses": gin.H{
+					"200": gin.H{"description": "Function result"},
+					"422": gin.H{"description": "Error thrown by the function"},
+					"504": gin.H{"description": "Function exceeded its time limit"},
+				},
+			}
+			getFunctionEndpoint := gin.H{
+				"summary":     functionEndpoint["summary"],
+				"description": functionEndpoint["description"],
+				"tags":        functionEndpoint["tags"],
+				"responses":   functionEndpoint["responses"],
+			}
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessCreate) {
+				functionEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessRead) {
+				getFunctionEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+			paths["/"+collectionName+"/fn/"+function.Name] = gin.H{"get": getFunctionEndpoint, "post": functionEndpoint}
+		}
+
+		// Editorial workflow endpoints for publishable collections
+		if schema.Publishable {
+			publishParameters := []gin.H{
+				{
+					"name":        "id",
+					"in":          "path",
+					"required":    true,
+					"type":        "string",
+					"description": "Document ID",
+				},
+			}
+
+			publishEndpoint := gin.H{
+				"summary":     "Publish " + collectionName,
+				"description": "Publish the current draft. Set publish_at to schedule the publication",
+				"tags":        []string{collectionName},
+				"parameters": append(append([]gin.H{}, publishParameters...), gin.H{
+					"name":     "body",
+					"in":       "body",
+					"required": false,
+					"schema": gin.H{
+						"type": "object",
+						"properties": gin.H{
+							"publish_at": gin.H{"type": "string", "format": "date-time"},
+						},
+					},
+				}),
+				"responses": gin.H{
+					"200": gin.H{"description": "Success"},
+					"400": gin.H{"description": "Bad Request"},
+					"404": gin.H{"description": "Not Found"},
+				},
+			}
+
+			unpublishEndpoint := gin.H{
+				"summary":     "Unpublish " + collectionName,
+				"description": "Withdraw the published version. The draft is kept",
+				"tags":        []string{collectionName},
+				"parameters":  publishParameters,
+				"responses": gin.H{
+					"200": gin.H{"description": "Success"},
+					"404": gin.H{"description": "Not Found"},
+				},
+			}
+
+			// Publishing changes an existing document, so it follows update protection
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessUpdate) {
+				publishEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+				unpublishEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+
+			paths["/"+collectionName+"/{id}/publish"] = gin.H{"post": publishEndpoint}
+			paths["/"+collectionName+"/{id}/unpublish"] = gin.H{"post": unpublishEndpoint}
+		}
+
+		// Change history endpoints for collections that keep history
+		if schema.History {
+			historyEndpoint := gin.H{
+				"summary":     "Get " + collectionName + " history",
+				"description": "List the recorded changes of a document, newest version first. Each entry holds the snapshot, the changed fields and who made the change",
+				"tags":        []string{collectionName},
+				"parameters": []gin.H{
+					{
+						"name":        "id",
+						"in":          "path",
+						"required":    true,
+						"type":        "string",
+						"description": "Document ID",
+					},
+					{
+						"name":        "page",
+						"in":          "query",
+						"type":        "integer",
+						"description": "Page number",
+					},
+					{
+						"name":        "limit",
+						"in":          "query",
+						"type":        "integer",
+						"description": "Items per page",
+					},
+				},
+				"responses": gin.H{
+					"200": gin.H{"description": "Success"},
+					"404": gin.H{"description": "Not Found"},
+				},
+			}
+
+			revertEndpoint := gin.H{
+				"summary":     "Revert " + collectionName + " to a version",
+				"description": "Restore the document data from a history version. Deleted documents are recreated, file fields get the files of that version",
+				"tags":        []string{collectionName},
+				"parameters": []gin.H{
+					{
+						"name":        "id",
+						"in":          "path",
+						"required":    true,
+						"type":        "string",
+						"description": "Document ID",
+					},
+					{
+						"name":        "version",
+						"in":          "path",
+						"required":    true,
+						"type":        "integer",
+						"description": "History version",
+					},
+				},
+				"responses": gin.H{
+					"200": gin.H{"description": "Success"},
+					"404": gin.H{"description": "Not Found"},
+				},
+			}
+
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessRead) {
+				historyEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+			// Reverting changes an existing document, so it follows update protection
+			if utils.DynamicEndpointRequiresAuth(&schema, models.AccessUpdate) {
+				revertEndpoint["security"] = []gin.H{{"BearerAuth": []string{}}}
+			}
+
+			paths["/"+collectionName+"/{id}/history"] = gin.H{"get": historyEndpoint}
+			paths["/"+collectionName+"/{id}/history/{version}/revert"] = gin.H{"post": revertEndpoint}
+		}
+	}
+
+	// Cross-collection transactions
+	paths["/transactions"] = gin.H{
+		"post": gin.H{
+			"summary":     "Run a transaction",
+			"description": "Run create, update and delete operations on several collections atomically. Every operation is validated against its schema and endpoint protection. Requires a MongoDB replica set or sharded cluster",
+			"tags":        []string{"transactions"},
+			"parameters": []gin.H{
+				{
+					"name":     "body",
+					"in":       "body",
+					"required": true,
+					"schema": gin.H{
+						"$ref": "#/definitions/TransactionRequest",
+					},
+				},
+			},
+			"responses": gin.H{
+				"200": gin.H{"description": "Transaction committed"},
+				"400": gin.H{"description": "Bad Request or transactions not supported by the deployment"},
+				"404": gin.H{"description": "Document or collection not found"},
+				"500": gin.H{"description": "Internal Server Error"},
+			},
+		},
+	}
+
+	// Batched calls
+	paths["/batch"] = gin.H{
+		"post": gin.H{
+			"summary":     "Run batch request",
+			"description": "Run up to 20 API calls in one round trip. Sub-requests run in order through the regular authentication and quota checks. Paths and bodies may reference earlier responses with {{id.field}} or {{index.field}} placeholders",
+			"tags":        []string{"batch"},
+			"parameters": []gin.H{
+				{
+					"name":     "body",
+					"in":       "body",
+					"required": true,
+					"schema": gin.H{
+						"type": "object",
+						"properties": gin.H{
+							"requests": gin.H{
+								"type": "array",
+								"items": gin.H{
+									"type": "object",
+									"properties": gin.H{
+										"id":      gin.H{"type": "string", "description": "Name used to reference the response"},
+										"method":  gin.H{"type": "string", "enum": []string{"GET", "POST", "PUT", "DELETE"}},
+										"path":    gin.H{"type": "string", "example": "/api/orders/{{order.id}}"},
+										"body":    gin.H{"type": "object"},
+										"headers": gin.H{"type": "object", "additionalProperties": gin.H{"type": "string"}},
+									},
+									"required": []string{"method", "path"},
+								},
+							},
+						},
+						"required": []string{"requests"},
+					},
+				},
+			},
+			"responses": gin.H{
+				"200": gin.H{"description": "Responses of all sub-requests in order"},
+				"400": gin.H{"description": "Bad Request"},
+			},
+		},
+	}
+
+	paths["/ws"] = gin.H{
+		"get": gin.H{
+			"summary":     "Realtime WebSocket",
+			"description": "Open a WebSocket with many subscriptions. Send {\"type\":\"subscribe\",\"id\":\"s1\",\"collection\":\"posts\",\"query\":\"locale=de\"} to watch a collection, add document_id to watch one document. Changes arrive as insert, update (changed fields only), delete and remove messages. Send {\"type\":\"auth\",\"token\":\"<jwt>\"} before subscribing to protected collections",
+			"tags":        []string{"realtime"},
+			"parameters": []gin.H{
+				{
+					"name":        "api_key",
+					"in":          "query",
+					"type":        "string",
+					"description": "API key, for clients that cannot set the X-API-Key header",
+				},
+				{
+					"name":        "access_token",
+					"in":          "query",
+					"type":        "string",
+					"description": "Dynamic auth token for protected collections",
+				},
+			},
+			"responses": gin.H{
+				"101": gin.H{"description": "Switching Protocols"},
+				"401": gin.H{"description": "Unauthorized"},
+			},
+		},
+	}
+
+	definitions["TransactionRequest"] = gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"operations": gin.H{
+				"type": "array",
+				"items": gin.H{
+					"type": "object",
+					"properties": gin.H{
+						"action": gin.H{
+							"type": "string",
+							"enum": []string{models.TransactionActionCreate, models.TransactionActionUpdate, models.TransactionActionDelete},
+						},
+						"collection": gin.H{"type": "string"},
+						"id": gin.H{
+							"type":        "string",
+							"description": "Document ID. Optional for create, where it lets later operations reference the new document",
+						},
+						"data": gin.H{"type": "object"},
+					},
+					"required": []string{"action", "collection"},
+				},
+			},
+		},
+		"required": []string{"operations"},
 	}
 
 	// Add common authentication definitions
@@ -627,6 +1211,61 @@ func buildSchemaForCollection(schema models.Schema) gin.H {
 			fieldSchema["default"] = field.Default
 		}
 
+		if field.Type == "geopoint" {
+			fieldSchema["type"] = "object"
+			fieldSchema["description"] = strings.TrimSpace(field.Description + " Location given as {\"lat\": number, \"lng\": number} and returned as a GeoJSON point")
+			fieldSchema["properties"] = gin.H{
+				"lat": gin.H{"type": "number", "minimum": -90, "maximum": 90},
+				"lng": gin.H{"type": "number", "minimum": -180, "maximum": 180},
+			}
+		}
+
+		if field.Localized && schema.Localization != nil {
+			description := "Translations keyed by locale. The default locale (" + schema.Localization.DefaultLocale + ") is required"
+			if len(schema.Localization.Locales) > 0 {
+				description += ", supported locales: " + strings.Join(schema.Localization.Locales, ", ")
+			}
+			fieldSchema["type"] = "object"
+			fieldSchema["additionalProperties"] = gin.H{"type": "string"}
+			fieldSchema["description"] = strings.TrimSpace(field.Description + " " + description)
+		}
+
+		if field.Type == "file" {
+			fieldSchema["type"] = "object"
+			fieldSchema["readOnly"] = true
+			fieldSchema["description"] = strings.TrimSpace(field.Description + " File metadata with a download URL. Upload through /{id}/files/" + field.Name)
+			fieldSchema["properties"] = gin.H{
+				"id":           gin.H{"type": "string"},
+				"filename":     gin.H{"type": "string"},
+				"content_type": gin.H{"type": "string"},
+				"size":         gin.H{"type": "integer"},
+				"uploaded_at":  gin.H{"type": "string", "format": "date-time"},
+				"url":          gin.H{"type": "string"},
+			}
+		}
+
+		// Field policies decide who sees and sets the field
+		readPolicy, writePolicy := utils.FieldReadPolicy(&field), utils.FieldWritePolicy(&field)
+		fieldSchema["x-read-policy"] = readPolicy
+		fieldSchema["x-write-policy"] = writePolicy
+		switch {
+		case (readPolicy == models.FieldPolicyPrivate || readPolicy == models.FieldPolicyServerOnly) && field.Type != "file":
+			fieldSchema["writeOnly"] = true
+		case writePolicy == models.FieldPolicyServerOnly:
+			fieldSchema["readOnly"] = true
+		}
+		if policies := describeFieldPolicies(readPolicy, writePolicy); policies != "" {
+			fieldSchema["description"] = strings.TrimSpace(fieldSchema["description"].(string) + " " + policies)
+		}
+
+		// The tenant field is stamped from the token, values sent by clients are replaced
+		if field.Name == schema.TenantField {
+			if _, hidden := fieldSchema["writeOnly"]; !hidden {
+				fieldSchema["readOnly"] = true
+			}
+			fieldSchema["description"] = strings.TrimSpace(fieldSchema["description"].(string) + " Set to the tenant of the caller's token.")
+		}
+
 		properties[field.Name] = fieldSchema
 
 		if field.Required {
@@ -639,6 +1278,32 @@ func buildSchemaForCollection(schema models.Schema) gin.H {
 		"properties": properties,
 	}
 
+	if expiry := describeExpiry(schema); expiry != "" {
+		schemaDefinition["description"] = expiry
+		schemaDefinition["x-expiry"] = schema.Expiry
+		if schema.Expiry.TTLSeconds > 0 {
+			properties["expires_at"] = gin.H{
+				"type":        "string",
+				"format":      "date-time",
+				"readOnly":    true,
+				"description": "When the document will be deleted",
+			}
+		}
+	}
+
+	if schema.Publishable {
+		properties["status"] = gin.H{
+			"type":     "string",
+			"enum":     []string{models.DocumentStatusDraft, models.DocumentStatusPublished, models.DocumentStatusScheduled},
+			"readOnly": true,
+		}
+		properties["published_at"] = gin.H{
+			"type":     "string",
+			"format":   "date-time",
+			"readOnly": true,
+		}
+	}
+
 	if len(required) > 0 {
 		schemaDefinition["required"] = required
 	}
@@ -646,6 +1311,67 @@ func buildSchemaForCollection(schema models.Schema) gin.H {
 	return schemaDefinition
 }
 
+// Helper function to describe non-public field policies
+func describeFieldPolicies(readPolicy, writePolicy string) string {
+	describe := func(policy string) string {
+		switch policy {
+		case models.FieldPolicyAuthenticated:
+			return "signed-in users"
+		case models.FieldPolicyOwner:
+			return "the document owner"
+		case models.FieldPolicyServerOnly:
+			return "functions"
+		case models.FieldPolicyPrivate:
+			return "nobody"
+		}
+		return "users with the " + strings.TrimPrefix(policy, models.FieldPolicyRolePrefix) + " role"
+	}
+
+	var parts []string
+	if readPolicy != models.FieldPolicyPublic {
+		parts = append(parts, "Readable by "+describe(readPolicy)+".")
+	}
+	if writePolicy != models.FieldPolicyPublic {
+		parts = append(parts, "Writable by "+describe(writePolicy)+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Helper function to describe when documents of a schema expire
+func describeExpiry(schema models.Schema) string {
+	if schema.Expiry == nil {
+		return ""
+	}
+
+	if schema.Expiry.TTLSeconds > 0 {
+		return fmt.Sprintf("Documents expire %d seconds after they are created and are then deleted automatically (deletion can lag by up to a minute)", schema.Expiry.TTLSeconds)
+	}
+	if schema.Expiry.Field != "" {
+		return "Documents expire at the date stored in the " + schema.Expiry.Field + " field and are then deleted automatically (deletion can lag by up to a minute)"
+	}
+	return ""
+}
+
+// Helper function to check whether a schema has a field of the given type
+func schemaHasFieldType(schema models.Schema, fieldType string) bool {
+	for _, field := range schema.Fields {
+		if field.Type == fieldType {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to check whether a schema has localized fields
+func schemaHasLocalizedFields(schema models.Schema) bool {
+	for _, field := range schema.Fields {
+		if field.Localized {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper function to build auth schema properties
 func buildAuthSchemaProperties(schema models.Schema) gin.H {
 	properties := gin.H{}
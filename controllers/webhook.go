@@ -0,0 +1,502 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxWebhooksPerSchema caps the number of endpoints registered for one schema
+const maxWebhooksPerSchema = 10
+
+type WebhookController struct{}
+
+func NewWebhookController() *WebhookController {
+	return &WebhookController{}
+}
+
+// Helper function to queue webhook deliveries for a document event. The document is only
+// loaded when a webhook listens for the event and no data was passed.
+func triggerWebhooks(db *mongo.Database, userID primitive.ObjectID, collectionName, event string, documentID primitive.ObjectID, data map[string]interface{}) {
+	webhooks, err := utils.FindWebhooks(userID, collectionName, event)
+	if err != nil {
+		fmt.Printf("Warning: Failed to look up webhooks for %s: %v\n", collectionName, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	if data == nil && event != models.WebhookEventDelete {
+		var document bson.M
+		if err := db.Collection(collectionName).FindOne(context.TODO(), bson.M{"_id": documentID}).Decode(&document); err == nil {
+			data, _ = document["data"].(bson.M)
+		}
+	}
+
+	if err := utils.EnqueueWebhookDeliveries(webhooks, event, collectionName, documentID, data); err != nil {
+		fmt.Printf("Warning: Failed to queue webhook deliveries for %s: %v\n", collectionName, err)
+	}
+}
+
+// Helper function to list the events a schema can emit
+func schemaWebhookEvents(schema *models.Schema) []string {
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		return []string{models.WebhookEventSignup}
+	}
+	return []string{models.WebhookEventCreate, models.WebhookEventUpdate, models.WebhookEventDelete}
+}
+
+// Helper function to validate the subscribed events, defaulting to all events of the schema
+func validateWebhookEvents(schema *models.Schema, events []string) ([]string, error) {
+	supported := schemaWebhookEvents(schema)
+	if len(events) == 0 {
+		return supported, nil
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, event := range events {
+		valid := false
+		for _, supportedEvent := range supported {
+			if event == supportedEvent {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, errors.New("Unsupported event for this schema: " + event + ". Supported events: " + strings.Join(supported, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// Helper function to load a webhook owned by the current user
+func (wc *WebhookController) getWebhook(c *gin.Context) (*models.Webhook, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	var webhook models.Webhook
+	err = config.DB.Collection("webhooks").FindOne(context.TODO(), bson.M{"_id": webhookID, "user_id": userID}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find webhook"})
+		}
+		return nil, false
+	}
+	return &webhook, true
+}
+
+// @Summary Create webhook
+// @Description Register an endpoint notified about create, update and delete events of a schema, or signup events of an authentication schema. The signing secret is only returned once.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Param webhook body models.CreateWebhookRequest true "Webhook endpoint"
+// @Success 201 "Created"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/webhooks [post]
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var schema models.Schema
+	err = config.DB.Collection("schemas").FindOne(context.TODO(), bson.M{"_id": schemaID, "user_id": userID, "is_active": true}).Decode(&schema)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schema not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find schema"})
+		}
+		return
+	}
+
+	if err := utils.ValidateWebhookURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := validateWebhookEvents(&schema, req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, err := config.DB.Collection("webhooks").CountDocuments(context.TODO(), bson.M{"schema_id": schemaID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count webhooks"})
+		return
+	}
+	if count >= maxWebhooksPerSchema {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A schema can have at most %d webhooks", maxWebhooksPerSchema)})
+		return
+	}
+
+	secret, err := utils.GenerateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+
+	now := time.Now()
+	webhook := models.Webhook{
+		ID:             primitive.NewObjectID(),
+		UserID:         userID.(primitive.ObjectID),
+		SchemaID:       schemaID,
+		CollectionName: schema.CollectionName,
+		URL:            req.URL,
+		Events:         events,
+		Description:    req.Description,
+		Secret:         secret,
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if _, err := config.DB.Collection("webhooks").InsertOne(context.TODO(), webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	go LogActivityWithContext(c, webhook.UserID, models.ActivityTypeCreate, "Added webhook to \""+schema.CollectionName+"\"", "Webhook endpoint registered", "webhook", webhook.ID.Hex(), map[string]any{
+		"collection_name": schema.CollectionName,
+		"url":             webhook.URL,
+		"events":          webhook.Events,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully. Store the secret now, it will not be shown again",
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// @Summary Get webhooks
+// @Description Get the webhooks registered for a schema
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schema ID"
+// @Success 200 {array} models.Webhook
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 500 "Internal Server Error"
+// @Router /schemas/{id}/webhooks [get]
+func (wc *WebhookController) GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	schemaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema ID"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := config.DB.Collection("webhooks").Find(context.TODO(), bson.M{"schema_id": schemaID, "user_id": userID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(context.TODO(), &webhooks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Update webhook
+// @Description Change the URL, events or description of a webhook. Setting active to true re-enables a disabled endpoint.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param webhook body models.UpdateWebhookRequest true "Webhook changes"
+// @Success 200 {object} models.Webhook
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /webhooks/{id} [put]
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	webhook, ok := wc.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+
+	if req.URL != "" {
+		if err := utils.ValidateWebhookURL(req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		set["url"] = req.URL
+	}
+
+	if req.Events != nil {
+		var schema models.Schema
+		if err := config.DB.Collection("schemas").FindOne(context.TODO(), bson.M{"_id": webhook.SchemaID}).Decode(&schema); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find schema"})
+			return
+		}
+		events, err := validateWebhookEvents(&schema, req.Events)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		set["events"] = events
+	}
+
+	if req.Description != nil {
+		set["description"] = *req.Description
+	}
+
+	if req.Active != nil {
+		set["active"] = *req.Active
+		if *req.Active {
+			// Re-enabling starts a fresh failure count
+			set["consecutive_failures"] = 0
+			unset["disabled_at"] = ""
+			unset["disabled_reason"] = ""
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var updated models.Webhook
+	err := config.DB.Collection("webhooks").FindOneAndUpdate(context.TODO(), bson.M{"_id": webhook.ID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary Delete webhook
+// @Description Delete a webhook and its delivery log
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 "Success"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /webhooks/{id} [delete]
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	webhook, ok := wc.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Collection("webhooks").DeleteOne(context.TODO(), bson.M{"_id": webhook.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	if _, err := config.DB.Collection("webhook_deliveries").DeleteMany(context.TODO(), bson.M{"webhook_id": webhook.ID}); err != nil {
+		fmt.Printf("Warning: Failed to delete deliveries of webhook %s: %v\n", webhook.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// @Summary Get webhook deliveries
+// @Description Get the delivery log of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Filter by status (pending, in_progress, succeeded, failed)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 "Success"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /webhooks/{id}/deliveries [get]
+func (wc *WebhookController) GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := wc.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{"webhook_id": webhook.ID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := config.DB.Collection("webhook_deliveries").Find(context.TODO(), filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(context.TODO(), &deliveries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode deliveries"})
+		return
+	}
+
+	total, err := config.DB.Collection("webhook_deliveries").CountDocuments(context.TODO(), filter)
+	if err != nil {
+		total = 0
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// @Summary Replay webhook delivery
+// @Description Queue a delivery again with its original payload
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (wc *WebhookController) ReplayWebhookDelivery(c *gin.Context) {
+	webhook, ok := wc.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	if !webhook.Active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook is disabled, re-enable it before replaying deliveries"})
+		return
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	var original models.WebhookDelivery
+	err = config.DB.Collection("webhook_deliveries").FindOne(context.TODO(), bson.M{"_id": deliveryID, "webhook_id": webhook.ID}).Decode(&original)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find delivery"})
+		}
+		return
+	}
+
+	// The payload keeps its original id so receivers can recognize the replayed event
+	now := time.Now()
+	replay := models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     webhook.ID,
+		UserID:        webhook.UserID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: now,
+		ReplayOf:      &original.ID,
+		CreatedAt:     now,
+	}
+
+	if _, err := config.DB.Collection("webhook_deliveries").InsertOne(context.TODO(), replay); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue delivery"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, replay)
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 
 	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/middleware"
 	"github.com/M-awais-rasool/SchemaCraft-go/routes"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
 	"github.com/joho/godotenv"
 )
 
@@ -43,6 +45,14 @@ func main() {
 	// Connect to MongoDB
 	config.ConnectMongoDB()
 
+	// Idempotency-Key replays rely on a unique index
+	if err := middleware.EnsureIdempotencyIndexes(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Deliver queued webhooks in the background
+	utils.StartWebhookWorker()
+
 	// Setup routes
 	router := routes.SetupRoutes()
 
@@ -13,7 +13,7 @@ import (
 	"github.com/M-awais-rasool/SchemaCraft-go/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -52,6 +52,10 @@ func AuthMiddleware() gin.HandlerFunc {
 func APIKeyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" && websocket.IsWebSocketUpgrade(c.Request) {
+			// Browsers cannot set headers on WebSocket connections
+			apiKey = c.Query("api_key")
+		}
 		if apiKey == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
 			c.Abort()
@@ -182,100 +186,93 @@ func DynamicAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		method := strings.ToLower(c.Request.Method)
-		requiresAuth := false
-
-		if schema.EndpointProtection != nil {
-			switch method {
-			case "get":
-				requiresAuth = schema.EndpointProtection.Get
-			case "post":
-				requiresAuth = schema.EndpointProtection.Post
-			case "put":
-				requiresAuth = schema.EndpointProtection.Put
-			case "delete":
-				requiresAuth = schema.EndpointProtection.Delete
-			}
-		}
+		operation := dynamicAccessOperation(c.Request.Method, c.FullPath())
+		requiresAuth := utils.DynamicEndpointRequiresAuth(&schema, operation)
 
-		if !requiresAuth {
-			c.Next()
-			return
+		// Functions decide about access themselves, so their routes have no rule. Owner-only
+		// operations need to know the caller.
+		source := ""
+		if !strings.Contains(c.FullPath(), "/fn/") {
+			source = utils.AccessRuleFor(&schema, operation)
+			requiresAuth = requiresAuth || utils.DynamicEndpointOwnerOnly(&schema, operation)
 		}
 
+		// Rules can use the signed-in user, so a token is also read when one is sent
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
-		}
-
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
-			c.Abort()
-			return
-		}
-
-		if schema.AuthConfig == nil || !schema.AuthConfig.Enabled {
-			var authSchema models.Schema
-			authFilter := bson.M{
-				"user_id":             userID,
-				"auth_config.enabled": true,
-				"is_active":           true,
-			}
-			err := config.DB.Collection("schemas").FindOne(context.TODO(), authFilter).Decode(&authSchema)
+		var auth interface{}
+		if requiresAuth || (source != "" && authHeader != "") {
+			claims, status, err := utils.ValidateDynamicAuthHeader(userID, &schema, authHeader)
 			if err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required but no auth system configured"})
+				c.JSON(status, gin.H{"error": err.Error()})
 				c.Abort()
 				return
 			}
 
-			schema.AuthConfig = authSchema.AuthConfig
-		}
-
-		jwtSecret := schema.AuthConfig.JWTSecret
-		if jwtSecret == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT secret not configured"})
-			c.Abort()
-			return
-		}
-
-		token, err := jwt.ParseWithClaims(tokenString, &models.DynamicAuthClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
+			if !utils.HasAnyRole(claims.Roles, utils.DynamicEndpointRoles(&schema, operation)) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+				c.Abort()
+				return
+			}
+			if schema.TenantField != "" && claims.Tenant == "" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Token has no tenant"})
+				c.Abort()
+				return
+			}
 
-		claims, ok := token.Claims.(*models.DynamicAuthClaims)
-		if !ok || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
+			c.Set("dynamic_auth_user_id", claims.SchemaUserID)
+			c.Set("dynamic_auth_schema_id", claims.SchemaID)
+			c.Set("dynamic_auth_collection", claims.Collection)
+			c.Set("dynamic_auth_roles", claims.Roles)
+			c.Set("dynamic_auth_tenant", claims.Tenant)
+			if !requiresAuth {
+				// Tokens only read for rules don't make the caller an editor
+				c.Set("dynamic_auth_optional", true)
+			}
+			auth = map[string]interface{}{"id": claims.SchemaUserID.Hex(), "collection": claims.Collection, "roles": utils.RuleValue(claims.Roles), "tenant": claims.Tenant}
 		}
 
-		if claims.UserID != userID {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token not valid for this user"})
-			c.Abort()
-			return
+		// Rules that only use the token are checked here, the controllers check the rest once
+		// the document is known
+		if source != "" {
+			rule, err := utils.ParseRule(source)
+			if err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access denied by " + operation + " rule"})
+				c.Abort()
+				return
+			}
+			if !rule.References("doc") && !rule.References("data") && !rule.ReferencesAuthProfile() && !rule.Evaluate(map[string]interface{}{"auth": auth}) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access denied by " + operation + " rule"})
+				c.Abort()
+				return
+			}
 		}
 
-		c.Set("dynamic_auth_user_id", claims.SchemaUserID)
-		c.Set("dynamic_auth_schema_id", claims.SchemaID)
-
 		c.Next()
 	}
 }
 
+// Helper function to map a dynamic API route to the operation guarding it. Endpoint protection
+// and access rules both use the operation, function calls other than GET count as creates.
+func dynamicAccessOperation(method, route string) string {
+	switch {
+	case method == http.MethodGet || method == http.MethodHead:
+		return models.AccessRead
+	case strings.Contains(route, "/fn/"):
+		return models.AccessCreate
+	case method == http.MethodPost && (strings.HasSuffix(route, "/:collection") || strings.HasSuffix(route, "/import")):
+		return models.AccessCreate
+	case method == http.MethodDelete && strings.HasSuffix(route, "/:collection/:id"):
+		return models.AccessDelete
+	}
+	// Updates, publishing, reverts and file changes all modify an existing document
+	return models.AccessUpdate
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
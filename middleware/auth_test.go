@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"github.com/M-awais-rasool/SchemaCraft-go/utils"
+)
+
+func TestDynamicAccessOperationTreatsDocumentChangesAsUpdates(t *testing.T) {
+	cases := []struct {
+		method string
+		route  string
+		want   string
+	}{
+		{"GET", "/api/:collection", models.AccessRead},
+		{"POST", "/api/:collection", models.AccessCreate},
+		{"PUT", "/api/:collection/:id", models.AccessUpdate},
+		{"DELETE", "/api/:collection/:id", models.AccessDelete},
+		{"GET", "/api/:collection/:id/files/:field", models.AccessRead},
+		{"POST", "/api/:collection/:id/files/:field", models.AccessUpdate},
+		{"DELETE", "/api/:collection/:id/files/:field", models.AccessUpdate},
+		{"POST", "/api/:collection/:id/publish", models.AccessUpdate},
+		{"POST", "/api/:collection/:id/unpublish", models.AccessUpdate},
+		{"GET", "/api/:collection/:id/history", models.AccessRead},
+		{"POST", "/api/:collection/:id/history/:version/revert", models.AccessUpdate},
+		{"POST", "/api/:collection/import", models.AccessCreate},
+		{"GET", "/api/:collection/export", models.AccessRead},
+		{"GET", "/api/:collection/fn/:name", models.AccessRead},
+		{"POST", "/api/:collection/fn/:name", models.AccessCreate},
+	}
+	for _, tc := range cases {
+		if got := dynamicAccessOperation(tc.method, tc.route); got != tc.want {
+			t.Errorf("%s %s is protected as %s, want %s", tc.method, tc.route, got, tc.want)
+		}
+	}
+}
+
+func TestUpdateProtectionGuardsDocumentChanges(t *testing.T) {
+	schema := &models.Schema{EndpointProtection: &models.EndpointProtection{Put: true}}
+	routes := map[string]bool{
+		"/api/:collection":                             false,
+		"/api/:collection/:id/files/:field":            true,
+		"/api/:collection/:id/publish":                 true,
+		"/api/:collection/:id/unpublish":               true,
+		"/api/:collection/:id/history/:version/revert": true,
+	}
+	for route, want := range routes {
+		if got := utils.DynamicEndpointRequiresAuth(schema, dynamicAccessOperation("POST", route)); got != want {
+			t.Errorf("POST %s requires auth = %v, want %v", route, got, want)
+		}
+	}
+}
+
+func TestUpdateRolesGuardDocumentChanges(t *testing.T) {
+	schema := &models.Schema{EndpointProtection: &models.EndpointProtection{Roles: models.EndpointRoles{Put: []string{"editor"}}}}
+	for _, route := range []string{"/api/:collection/:id/files/:field", "/api/:collection/:id/publish", "/api/:collection/:id/history/:version/revert"} {
+		roles := utils.DynamicEndpointRoles(schema, dynamicAccessOperation("POST", route))
+		if len(roles) != 1 || roles[0] != "editor" {
+			t.Errorf("POST %s is limited to %v, want [editor]", route, roles)
+		}
+	}
+	if roles := utils.DynamicEndpointRoles(schema, dynamicAccessOperation("POST", "/api/:collection")); roles != nil {
+		t.Errorf("POST /api/:collection is limited to %v, want no roles", roles)
+	}
+}
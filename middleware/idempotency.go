@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// idempotencyKeyTTL is how long a stored response can be replayed
+	idempotencyKeyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength bounds the size of client supplied keys
+	maxIdempotencyKeyLength = 255
+	// idempotencyProcessingTimeout frees keys whose request never finished, e.g. after a crash
+	idempotencyProcessingTimeout = time.Minute
+)
+
+// idempotencyResponseWriter keeps a copy of the response so it can be stored for replays
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// EnsureIdempotencyIndexes creates the unique and TTL indexes of the idempotency_keys collection.
+// Without the unique index concurrent retries could both run, so the server must not start without it.
+func EnsureIdempotencyIndexes() error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "auth_user_id", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := config.DB.Collection("idempotency_keys").Indexes().CreateMany(context.TODO(), indexes); err != nil {
+		return fmt.Errorf("failed to create idempotency key indexes: %w", err)
+	}
+	return nil
+}
+
+// Helper function to get the dynamic auth user a key is scoped to, nil for anonymous callers
+func idempotencyAuthUserID(c *gin.Context) *primitive.ObjectID {
+	value, exists := c.Get("dynamic_auth_user_id")
+	if !exists {
+		return nil
+	}
+	authUserID, ok := value.(primitive.ObjectID)
+	if !ok {
+		return nil
+	}
+	return &authUserID
+}
+
+// Helper function to build the filter of the stored record for a key. A missing auth_user_id
+// matches null, so anonymous callers share one scope per API user.
+func idempotencyRecordFilter(userID primitive.ObjectID, authUserID *primitive.ObjectID, key string) bson.M {
+	filter := bson.M{"user_id": userID, "auth_user_id": nil, "key": key}
+	if authUserID != nil {
+		filter["auth_user_id"] = *authUserID
+	}
+	return filter
+}
+
+// IdempotencyMiddleware replays the stored response when a POST is retried with the same
+// Idempotency-Key header. It must run after APIKeyMiddleware and DynamicAuthMiddleware, keys
+// are scoped per API user and dynamic auth user.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)})
+			c.Abort()
+			return
+		}
+
+		apiUserID, exists := c.Get("api_user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		userID := apiUserID.(primitive.ObjectID)
+		authUserID := idempotencyAuthUserID(c)
+
+		// Fingerprint the request so a reused key with a different payload can be detected
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		collection := config.DB.Collection("idempotency_keys")
+		now := time.Now()
+		record := models.IdempotencyRecord{
+			ID:          primitive.NewObjectID(),
+			UserID:      userID,
+			AuthUserID:  authUserID,
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: requestHash,
+			Status:      models.IdempotencyStatusProcessing,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyKeyTTL),
+		}
+
+		_, err = collection.InsertOne(context.TODO(), record)
+		if mongo.IsDuplicateKeyError(err) {
+			var existing models.IdempotencyRecord
+			filter := idempotencyRecordFilter(userID, authUserID, key)
+			if findErr := collection.FindOne(context.TODO(), filter).Decode(&existing); findErr != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load idempotency key"})
+				c.Abort()
+				return
+			}
+
+			// The TTL monitor only runs periodically, so expired keys may still be present
+			abandoned := existing.Status == models.IdempotencyStatusProcessing && existing.CreatedAt.Add(idempotencyProcessingTimeout).Before(now)
+			if existing.ExpiresAt.Before(now) || abandoned {
+				collection.DeleteOne(context.TODO(), bson.M{"_id": existing.ID})
+				_, err = collection.InsertOne(context.TODO(), record)
+			} else {
+				replayIdempotentResponse(c, existing, requestHash)
+				return
+			}
+		}
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store idempotency key"})
+			}
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// Release the key unless the response was stored, also when the handler panics
+		stored := false
+		defer func() {
+			if !stored {
+				collection.DeleteOne(context.TODO(), bson.M{"_id": record.ID})
+			}
+		}()
+
+		c.Next()
+
+		// Only successful responses are stored, failed requests release the key so the client
+		// can fix the request or retry with the same key
+		status := writer.Status()
+		if !idempotencyResponseStored(status) {
+			return
+		}
+
+		update := bson.M{"$set": bson.M{
+			"status":          models.IdempotencyStatusCompleted,
+			"response_status": status,
+			"response_body":   writer.body.Bytes(),
+			"content_type":    writer.Header().Get("Content-Type"),
+		}}
+		if _, err := collection.UpdateOne(context.TODO(), bson.M{"_id": record.ID}, update); err != nil {
+			fmt.Printf("Warning: Failed to store response for idempotency key %s: %v\n", key, err)
+			return
+		}
+		stored = true
+	}
+}
+
+// Helper function to check whether a response status is kept for replays
+func idempotencyResponseStored(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
+
+// Helper function to answer a retried request from its stored record
+func replayIdempotentResponse(c *gin.Context, record models.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+		c.Abort()
+		return
+	}
+
+	if record.Status != models.IdempotencyStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+		c.Abort()
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.ResponseStatus, record.ContentType, record.ResponseBody)
+	c.Abort()
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIdempotencyKeysAreScopedToDynamicAuthUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := primitive.NewObjectID()
+
+	anonymous, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if authUserID := idempotencyAuthUserID(anonymous); authUserID != nil {
+		t.Fatalf("anonymous caller scoped to %s", authUserID.Hex())
+	}
+
+	alice, _ := gin.CreateTestContext(httptest.NewRecorder())
+	alice.Set("dynamic_auth_user_id", primitive.NewObjectID())
+	bob, _ := gin.CreateTestContext(httptest.NewRecorder())
+	bob.Set("dynamic_auth_user_id", primitive.NewObjectID())
+
+	aliceFilter := idempotencyRecordFilter(userID, idempotencyAuthUserID(alice), "key-1")
+	bobFilter := idempotencyRecordFilter(userID, idempotencyAuthUserID(bob), "key-1")
+	anonymousFilter := idempotencyRecordFilter(userID, idempotencyAuthUserID(anonymous), "key-1")
+
+	if reflect.DeepEqual(aliceFilter, bobFilter) {
+		t.Error("two dynamic auth users share the same idempotency record")
+	}
+	if reflect.DeepEqual(aliceFilter, anonymousFilter) {
+		t.Error("a dynamic auth user shares the anonymous idempotency record")
+	}
+	if anonymousFilter["auth_user_id"] != nil {
+		t.Errorf("anonymous filter matches auth_user_id %v, want null", anonymousFilter["auth_user_id"])
+	}
+}
+
+func TestIdempotencyStoresOnlySuccessfulResponses(t *testing.T) {
+	cases := map[int]bool{
+		http.StatusOK:                  true,
+		http.StatusCreated:             true,
+		http.StatusNoContent:           true,
+		http.StatusBadRequest:          false,
+		http.StatusConflict:            false,
+		http.StatusUnprocessableEntity: false,
+		http.StatusInternalServerError: false,
+	}
+	for status, want := range cases {
+		if got := idempotencyResponseStored(status); got != want {
+			t.Errorf("status %d stored = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+// BatchSubRequest is one API call inside a batch. Strings in Path and Body may reference
+// earlier responses with {{<id or index>.<field path>}}, e.g. {{order.id}} or {{0.data.0.id}}.
+type BatchSubRequest struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method" binding:"required"`
+	Path    string            `json:"path" binding:"required"`
+	Body    interface{}       `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type BatchRequest struct {
+	Requests []BatchSubRequest `json:"requests" binding:"required,min=1,dive"`
+}
+
+type BatchSubResponse struct {
+	ID     string      `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Body   interface{} `json:"body,omitempty"`
+}
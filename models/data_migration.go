@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Operations a migration can apply to the stored documents of a collection
+const (
+	MigrationOpRenameField     = "rename_field"
+	MigrationOpConvertType     = "convert_type"
+	MigrationOpBackfillDefault = "backfill_default"
+	MigrationOpDropField       = "drop_field"
+	MigrationOpSplitField      = "split_field"
+	MigrationOpMergeFields     = "merge_fields"
+)
+
+const (
+	MigrationStatusPending    = "pending"
+	MigrationStatusRunning    = "running"
+	MigrationStatusCompleted  = "completed"
+	MigrationStatusFailed     = "failed"
+	MigrationStatusRolledBack = "rolled_back"
+)
+
+// MigrationOperation is one step of a migration. Which options apply depends on the type:
+// rename_field moves field to to, convert_type converts field to field_type, backfill_default
+// sets field to value where it is missing, drop_field removes field, split_field splits the
+// string in field into fields and merge_fields joins fields into to. Split and merge use
+// separator, a single space by default, and keep their source fields.
+type MigrationOperation struct {
+	Type      string      `json:"type" bson:"type"`
+	Field     string      `json:"field,omitempty" bson:"field,omitempty"`
+	To        string      `json:"to,omitempty" bson:"to,omitempty"`
+	FieldType string      `json:"field_type,omitempty" bson:"field_type,omitempty"`
+	Value     interface{} `json:"value,omitempty" bson:"value"`
+	Fields    []string    `json:"fields,omitempty" bson:"fields,omitempty"`
+	Separator string      `json:"separator,omitempty" bson:"separator,omitempty"`
+}
+
+// CreateMigrationRequest starts a migration. Without operations they are derived from the
+// diff between two schema versions, by default the latest version and the one before it.
+type CreateMigrationRequest struct {
+	Operations  []MigrationOperation `json:"operations,omitempty"`
+	FromVersion int                  `json:"from_version,omitempty"`
+	ToVersion   int                  `json:"to_version,omitempty"`
+	DryRun      bool                 `json:"dry_run"`
+}
+
+type MigrationDocumentError struct {
+	DocumentID primitive.ObjectID `json:"document_id" bson:"document_id"`
+	Error      string             `json:"error" bson:"error"`
+}
+
+// MigrationSample shows the data of a document before and after a dry run
+type MigrationSample struct {
+	DocumentID primitive.ObjectID     `json:"document_id" bson:"document_id"`
+	Before     map[string]interface{} `json:"before" bson:"before"`
+	After      map[string]interface{} `json:"after" bson:"after"`
+}
+
+// MigrationJob tracks a migration or its rollback running in the background, stored in the
+// migration_jobs collection. Documents changed by a migration are backed up in the
+// <collection>__migrations collection of the user's database until it is rolled back.
+type MigrationJob struct {
+	ID                 primitive.ObjectID       `json:"id" bson:"_id,omitempty"`
+	UserID             primitive.ObjectID       `json:"user_id" bson:"user_id"`
+	SchemaID           primitive.ObjectID       `json:"schema_id" bson:"schema_id"`
+	Collection         string                   `json:"collection" bson:"collection"`
+	Operations         []MigrationOperation     `json:"operations" bson:"operations"`
+	FromVersion        int                      `json:"from_version,omitempty" bson:"from_version,omitempty"`
+	ToVersion          int                      `json:"to_version,omitempty" bson:"to_version,omitempty"`
+	DryRun             bool                     `json:"dry_run" bson:"dry_run"`
+	RollbackOf         *primitive.ObjectID      `json:"rollback_of,omitempty" bson:"rollback_of,omitempty"`
+	RolledBackBy       *primitive.ObjectID      `json:"rolled_back_by,omitempty" bson:"rolled_back_by,omitempty"`
+	Status             string                   `json:"status" bson:"status"`
+	TotalDocuments     int64                    `json:"total_documents" bson:"total_documents"`
+	ProcessedDocuments int64                    `json:"processed_documents" bson:"processed_documents"`
+	ModifiedDocuments  int64                    `json:"modified_documents" bson:"modified_documents"`
+	FailedDocuments    int64                    `json:"failed_documents" bson:"failed_documents"`
+	Errors             []MigrationDocumentError `json:"errors" bson:"errors"`
+	ErrorsTruncated    bool                     `json:"errors_truncated" bson:"errors_truncated"`
+	Samples            []MigrationSample        `json:"samples,omitempty" bson:"samples,omitempty"`
+	Error              string                   `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt          time.Time                `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time                `json:"updated_at" bson:"updated_at"`
+	CompletedAt        *time.Time               `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
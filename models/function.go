@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Triggers a function can run on. Endpoint functions are called through /api/{collection}/fn/{name}.
+const (
+	FunctionTriggerBeforeCreate = "beforeCreate"
+	FunctionTriggerAfterCreate  = "afterCreate"
+	FunctionTriggerBeforeUpdate = "beforeUpdate"
+	FunctionTriggerAfterUpdate  = "afterUpdate"
+	FunctionTriggerBeforeDelete = "beforeDelete"
+	FunctionTriggerAfterDelete  = "afterDelete"
+	FunctionTriggerEndpoint     = "endpoint"
+)
+
+// SchemaFunction is a JavaScript function attached to a schema. Code is the body of a function
+// receiving event, db and console; before triggers can return an object to replace the written data.
+type SchemaFunction struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id" bson:"user_id"`
+	SchemaID       primitive.ObjectID `json:"schema_id" bson:"schema_id"`
+	CollectionName string             `json:"collection_name" bson:"collection_name"`
+	Name           string             `json:"name" bson:"name"`
+	Trigger        string             `json:"trigger" bson:"trigger"`
+	Code           string             `json:"code" bson:"code"`
+	TimeoutMs      int                `json:"timeout_ms" bson:"timeout_ms"`
+	Active         bool               `json:"active" bson:"active"`
+	LastRunAt      *time.Time         `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastError      string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateFunctionRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Trigger   string `json:"trigger" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+	TimeoutMs int    `json:"timeout_ms"`
+}
+
+type UpdateFunctionRequest struct {
+	Trigger   string  `json:"trigger"`
+	Code      *string `json:"code"`
+	TimeoutMs *int    `json:"timeout_ms"`
+	Active    *bool   `json:"active"`
+}
+
+// TestFunctionRequest runs a function once with the given event
+type TestFunctionRequest struct {
+	Event map[string]interface{} `json:"event"`
+}
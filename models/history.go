@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HistoryAction string
+
+const (
+	HistoryActionCreate HistoryAction = "create"
+	HistoryActionUpdate HistoryAction = "update"
+	HistoryActionDelete HistoryAction = "delete"
+	HistoryActionRevert HistoryAction = "revert"
+)
+
+// DocumentHistory is one entry of a document's change log, stored in <collection>__history
+type DocumentHistory struct {
+	ID         primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	DocumentID primitive.ObjectID     `json:"document_id" bson:"document_id"`
+	Version    int                    `json:"version" bson:"version"`
+	Action     HistoryAction          `json:"action" bson:"action"`
+	Snapshot   map[string]interface{} `json:"snapshot" bson:"snapshot"`
+	Diff       []FieldChange          `json:"diff" bson:"diff"`
+	Actor      HistoryActor           `json:"actor" bson:"actor"`
+	UserID     primitive.ObjectID     `json:"-" bson:"user_id"`
+	CreatedAt  time.Time              `json:"created_at" bson:"created_at"`
+}
+
+type FieldChange struct {
+	Field    string      `json:"field" bson:"field"`
+	OldValue interface{} `json:"old_value,omitempty" bson:"old_value,omitempty"`
+	NewValue interface{} `json:"new_value,omitempty" bson:"new_value,omitempty"`
+}
+
+// HistoryActor identifies who made a change: the API key owner or a dynamic auth user
+type HistoryActor struct {
+	Type  string `json:"type" bson:"type"`
+	ID    string `json:"id" bson:"id"`
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+}
+
+const (
+	HistoryActorAPIKey = "api_key"
+	HistoryActorUser   = "user"
+)
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyRecord stores the first response for an Idempotency-Key of an API user and dynamic auth user
+type IdempotencyRecord struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	AuthUserID     *primitive.ObjectID `json:"auth_user_id,omitempty" bson:"auth_user_id,omitempty"` // Dynamic auth user that sent the request
+	Key            string              `json:"key" bson:"key"`
+	Method         string              `json:"method" bson:"method"`
+	Path           string              `json:"path" bson:"path"`
+	RequestHash    string              `json:"request_hash" bson:"request_hash"`
+	Status         string              `json:"status" bson:"status"`
+	ResponseStatus int                 `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ResponseBody   []byte              `json:"-" bson:"response_body,omitempty"`
+	ContentType    string              `json:"content_type,omitempty" bson:"content_type,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	ExpiresAt      time.Time           `json:"expires_at" bson:"expires_at"`
+}
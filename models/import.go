@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportRowError struct {
+	Row   int    `json:"row" bson:"row"`
+	Error string `json:"error" bson:"error"`
+}
+
+// ImportJob tracks an asynchronous bulk import, stored in the import_jobs collection
+type ImportJob struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID          primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Collection      string             `json:"collection" bson:"collection"`
+	Format          string             `json:"format" bson:"format"`
+	Status          string             `json:"status" bson:"status"`
+	TotalBytes      int64              `json:"total_bytes" bson:"total_bytes"`
+	ProcessedBytes  int64              `json:"processed_bytes" bson:"processed_bytes"`
+	ProcessedRows   int                `json:"processed_rows" bson:"processed_rows"`
+	ImportedRows    int                `json:"imported_rows" bson:"imported_rows"`
+	FailedRows      int                `json:"failed_rows" bson:"failed_rows"`
+	Errors          []ImportRowError   `json:"errors" bson:"errors"`
+	ErrorsTruncated bool               `json:"errors_truncated" bson:"errors_truncated"`
+	Error           string             `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`
+	CompletedAt     *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// Message types sent by realtime WebSocket clients
+const (
+	RealtimeMessageAuth        = "auth"
+	RealtimeMessageSubscribe   = "subscribe"
+	RealtimeMessageUnsubscribe = "unsubscribe"
+	RealtimeMessagePing        = "ping"
+)
+
+// RealtimeClientMessage is a message sent by a realtime WebSocket client.
+// Subscribe messages watch a collection, or a single document when DocumentID is set.
+// Query uses the list endpoint parameters, e.g. "within=52.3,13.1,52.7,13.8&locale=de".
+type RealtimeClientMessage struct {
+	Type       string `json:"type"`
+	ID         string `json:"id,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	DocumentID string `json:"document_id,omitempty"`
+	Query      string `json:"query,omitempty"`
+	Token      string `json:"token,omitempty"`
+}
@@ -14,18 +14,115 @@ type Schema struct {
 	Fields             []SchemaField       `json:"fields" bson:"fields"`
 	AuthConfig         *AuthConfig         `json:"auth_config,omitempty" bson:"auth_config,omitempty"`
 	EndpointProtection *EndpointProtection `json:"endpoint_protection,omitempty" bson:"endpoint_protection,omitempty"`
+	Expiry             *ExpiryConfig       `json:"expiry,omitempty" bson:"expiry,omitempty"`
+	Localization       *LocalizationConfig `json:"localization,omitempty" bson:"localization,omitempty"`
+	Publishable        bool                `json:"publishable" bson:"publishable"`
+	History            bool                `json:"history" bson:"history"`
+	BeforeWrite        *BeforeWriteHook    `json:"before_write,omitempty" bson:"before_write,omitempty"`
+	Rules              *AccessRules        `json:"rules,omitempty" bson:"rules,omitempty"`
+	TenantField        string              `json:"tenant_field,omitempty" bson:"tenant_field,omitempty"` // String field stamped with the caller's tenant claim
 	CreatedAt          time.Time           `json:"created_at" bson:"created_at"`
 	UpdatedAt          time.Time           `json:"updated_at" bson:"updated_at"`
 	IsActive           bool                `json:"is_active" bson:"is_active"`
 }
 
 type EndpointProtection struct {
+	Get       bool             `json:"get" bson:"get"`
+	Post      bool             `json:"post" bson:"post"`
+	Put       bool             `json:"put" bson:"put"`
+	Delete    bool             `json:"delete" bson:"delete"`
+	OwnerOnly OwnerOnlyMethods `json:"owner_only" bson:"owner_only"`
+	Roles     EndpointRoles    `json:"roles" bson:"roles"`
+}
+
+// EndpointRoles limits methods to dynamic auth users holding at least one of the listed roles.
+// A method with roles requires a token, an empty list lets any signed-in user through.
+type EndpointRoles struct {
+	Get    []string `json:"get,omitempty" bson:"get,omitempty"`
+	Post   []string `json:"post,omitempty" bson:"post,omitempty"`
+	Put    []string `json:"put,omitempty" bson:"put,omitempty"`
+	Delete []string `json:"delete,omitempty" bson:"delete,omitempty"`
+}
+
+// OwnerOnlyMethods limits methods to documents created by the calling dynamic auth user.
+// Owner-only methods require a token. Put also covers publishing, file changes and reverts.
+type OwnerOnlyMethods struct {
 	Get    bool `json:"get" bson:"get"`
-	Post   bool `json:"post" bson:"post"`
 	Put    bool `json:"put" bson:"put"`
 	Delete bool `json:"delete" bson:"delete"`
 }
 
+// AccessRules are expressions deciding who may read and write documents, for example
+// `auth != null && doc.owner == auth.id`. Rules can use auth (the signed-in user or null),
+// doc (the stored document) and data (the document as it will be after the write). An empty
+// rule allows the operation. Read rules are turned into query filters for lists.
+type AccessRules struct {
+	Read   string `json:"read,omitempty" bson:"read,omitempty"`
+	Create string `json:"create,omitempty" bson:"create,omitempty"`
+	Update string `json:"update,omitempty" bson:"update,omitempty"`
+	Delete string `json:"delete,omitempty" bson:"delete,omitempty"`
+}
+
+// Operations an access rule can guard
+const (
+	AccessRead   = "read"
+	AccessCreate = "create"
+	AccessUpdate = "update"
+	AccessDelete = "delete"
+)
+
+// ExpiryConfig makes documents expire a fixed time after creation or at the time stored in a date field.
+// Expired documents are removed by a MongoDB TTL index, which runs about once a minute.
+type ExpiryConfig struct {
+	TTLSeconds int64  `json:"ttl_seconds,omitempty" bson:"ttl_seconds,omitempty"`
+	Field      string `json:"field,omitempty" bson:"field,omitempty"`
+}
+
+// LocalizationConfig lists the locales used by localized string fields.
+// Reads fall back to the fallback locales in order, then to the default locale.
+type LocalizationConfig struct {
+	DefaultLocale string   `json:"default_locale" bson:"default_locale"`
+	Locales       []string `json:"locales,omitempty" bson:"locales,omitempty"`
+	Fallback      []string `json:"fallback,omitempty" bson:"fallback,omitempty"`
+}
+
+// BeforeWriteHook is called synchronously before documents are created, updated or deleted.
+// The hook can reject the write or return a changed payload. When the hook fails or times out
+// the write is rejected unless FailOpen is set.
+type BeforeWriteHook struct {
+	URL        string   `json:"url" bson:"url"`
+	Operations []string `json:"operations,omitempty" bson:"operations,omitempty"` // create, update, delete; empty means all
+	TimeoutMs  int      `json:"timeout_ms,omitempty" bson:"timeout_ms,omitempty"`
+	FailOpen   bool     `json:"fail_open" bson:"fail_open"`
+	Secret     string   `json:"secret,omitempty" bson:"secret"` // Signs hook requests, generated when empty
+}
+
+// Operations a before-write hook can intercept
+const (
+	BeforeWriteCreate = "create"
+	BeforeWriteUpdate = "update"
+	BeforeWriteDelete = "delete"
+)
+
+// BeforeWriteRequest is the JSON body posted to a before-write hook
+type BeforeWriteRequest struct {
+	ID         string                 `json:"id"`
+	Operation  string                 `json:"operation"`
+	Collection string                 `json:"collection"`
+	DocumentID string                 `json:"document_id"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Current    map[string]interface{} `json:"current,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+}
+
+// BeforeWriteResponse is the answer of a before-write hook. An empty response allows the write,
+// Data replaces the proposed payload of creates and updates.
+type BeforeWriteResponse struct {
+	Allow   *bool                  `json:"allow,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
 type AuthConfig struct {
 	Enabled                  bool            `json:"enabled" bson:"enabled"`
 	UserCollection           string          `json:"user_collection" bson:"user_collection"`
@@ -36,6 +133,23 @@ type AuthConfig struct {
 	RequireEmailVerification bool            `json:"require_email_verification" bson:"require_email_verification"`
 	AllowSignup              bool            `json:"allow_signup" bson:"allow_signup"`
 	JWTSecret                string          `json:"-" bson:"jwt_secret"`
+	Roles                    []string        `json:"roles,omitempty" bson:"roles,omitempty"`
+	DefaultRole              string          `json:"default_role,omitempty" bson:"default_role,omitempty"`
+	TenantField              string          `json:"tenant_field,omitempty" bson:"tenant_field,omitempty"` // User field holding the tenant put in tokens
+}
+
+// AuthRolesField is the field of auth user documents holding their roles. Roles are
+// assigned by the API owner, signup only grants the default role.
+const AuthRolesField = "roles"
+
+// SetAuthUserRolesRequest replaces the roles of an auth user
+type SetAuthUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// SetAuthUserTenantRequest moves an auth user to a tenant, an empty tenant removes it
+type SetAuthUserTenantRequest struct {
+	Tenant string `json:"tenant"`
 }
 
 type AuthFieldConfig struct {
@@ -44,14 +158,34 @@ type AuthFieldConfig struct {
 	AllowBoth     bool   `json:"allow_both" bson:"allow_both"`
 }
 
+// Field policies decide who may read or write a field. Role policies are written as
+// "role:<name>", server-only fields are only available to functions.
+const (
+	FieldPolicyPublic        = "public"
+	FieldPolicyAuthenticated = "authenticated"
+	FieldPolicyOwner         = "owner"
+	FieldPolicyRolePrefix    = "role:"
+	FieldPolicyServerOnly    = "server-only"
+
+	// Private fields without a read policy are never returned, not even to functions
+	FieldPolicyPrivate = "private"
+)
+
 type SchemaField struct {
 	Name        string      `json:"name" bson:"name" binding:"required"`
 	Type        string      `json:"type" bson:"type" binding:"required"`
 	Visibility  string      `json:"visibility" bson:"visibility"`
+	ReadPolicy  string      `json:"read_policy,omitempty" bson:"read_policy,omitempty"`   // Who may read the field, derived from visibility when empty
+	WritePolicy string      `json:"write_policy,omitempty" bson:"write_policy,omitempty"` // Who may set the field, anyone when empty
 	Required    bool        `json:"required" bson:"required"`
 	Default     interface{} `json:"default,omitempty" bson:"default,omitempty"`
 	Description string      `json:"description,omitempty" bson:"description,omitempty"`
-	Target      string      `json:"target,omitempty" bson:"target,omitempty"` // For relation fields, specifies target collection
+	Target      string      `json:"target,omitempty" bson:"target,omitempty"`       // For relation fields, specifies target collection
+	Localized   bool        `json:"localized,omitempty" bson:"localized,omitempty"` // For string fields, stores one value per locale
+
+	// For file fields, limits applied to uploads
+	MaxSize      int64    `json:"max_size,omitempty" bson:"max_size,omitempty"`           // Maximum size in bytes
+	AllowedTypes []string `json:"allowed_types,omitempty" bson:"allowed_types,omitempty"` // Allowed MIME types, e.g. "image/*"
 }
 
 type CreateSchemaRequest struct {
@@ -59,6 +193,13 @@ type CreateSchemaRequest struct {
 	Fields             []SchemaField       `json:"fields" binding:"required,min=1"`
 	AuthConfig         *AuthConfig         `json:"auth_config,omitempty"`
 	EndpointProtection *EndpointProtection `json:"endpoint_protection,omitempty"`
+	Expiry             *ExpiryConfig       `json:"expiry,omitempty"`
+	Localization       *LocalizationConfig `json:"localization,omitempty"`
+	Publishable        bool                `json:"publishable"`
+	History            bool                `json:"history"`
+	BeforeWrite        *BeforeWriteHook    `json:"before_write,omitempty"`
+	Rules              *AccessRules        `json:"rules,omitempty"`
+	TenantField        string              `json:"tenant_field,omitempty"`
 }
 
 type DynamicData struct {
@@ -67,6 +208,35 @@ type DynamicData struct {
 	CreatedAt time.Time              `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at" bson:"updated_at"`
 	UserID    primitive.ObjectID     `json:"user_id" bson:"user_id"`
+
+	// Dynamic auth users that created and last changed the document, unset for API key writes
+	CreatedBy *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+
+	// Draft/publish workflow for publishable schemas. Data holds the working draft and
+	// PublishedData the snapshot visible to anonymous readers from PublishedAt onwards.
+	Status        string                 `json:"status,omitempty" bson:"status,omitempty"`
+	PublishedData map[string]interface{} `json:"published_data,omitempty" bson:"published_data,omitempty"`
+	PublishedAt   *time.Time             `json:"published_at,omitempty" bson:"published_at,omitempty"`
+}
+
+const (
+	DocumentStatusDraft     = "draft"
+	DocumentStatusPublished = "published"
+	DocumentStatusScheduled = "scheduled"
+)
+
+type PublishRequest struct {
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+// FileMetadata is stored in a document for file fields, the content itself lives in GridFS
+type FileMetadata struct {
+	FileID      primitive.ObjectID `json:"file_id" bson:"file_id"`
+	Filename    string             `json:"filename" bson:"filename"`
+	ContentType string             `json:"content_type" bson:"content_type"`
+	Size        int64              `json:"size" bson:"size"`
+	UploadedAt  time.Time          `json:"uploaded_at" bson:"uploaded_at"`
 }
 
 type DynamicAuthLoginRequest struct {
@@ -89,5 +259,7 @@ type DynamicAuthClaims struct {
 	SchemaUserID primitive.ObjectID `json:"schema_user_id"`
 	Collection   string             `json:"collection"`
 	SchemaID     primitive.ObjectID `json:"schema_id"`
+	Roles        []string           `json:"roles,omitempty"`
+	Tenant       string             `json:"tenant,omitempty"`
 	jwt.RegisteredClaims
 }
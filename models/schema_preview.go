@@ -0,0 +1,52 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SchemaPreview reports what a schema update would change without saving it
+type SchemaPreview struct {
+	Diff      SchemaDiff               `json:"diff"`
+	Documents SchemaPreviewDocuments   `json:"documents"`
+	Relations []SchemaPreviewReference `json:"relations"`
+	Auth      SchemaPreviewAuth        `json:"auth"`
+	Indexes   SchemaPreviewIndexes     `json:"indexes"`
+	Migration []MigrationOperation     `json:"suggested_migration"`
+	Warnings  []string                 `json:"warnings"`
+}
+
+// SchemaPreviewDocuments counts the stored documents that would fail the new validation
+type SchemaPreviewDocuments struct {
+	Collection       string                    `json:"collection"`
+	TotalDocuments   int64                     `json:"total_documents"`
+	FailingDocuments int64                     `json:"failing_documents"`
+	Fields           []SchemaPreviewFieldCheck `json:"fields"`
+}
+
+// SchemaPreviewFieldCheck counts the documents failing the checks of one field
+type SchemaPreviewFieldCheck struct {
+	Field           string `json:"field"`
+	MissingRequired int64  `json:"missing_required"`
+	WrongType       int64  `json:"wrong_type"`
+}
+
+// SchemaPreviewReference is a relation field or access rule of another schema that the update breaks
+type SchemaPreviewReference struct {
+	SchemaID       primitive.ObjectID `json:"schema_id"`
+	CollectionName string             `json:"collection_name"`
+	Field          string             `json:"field,omitempty"`     // Relation field pointing at the schema
+	Operation      string             `json:"operation,omitempty"` // Operation of the access rule reading the auth profile
+	Reason         string             `json:"reason"`
+}
+
+// SchemaPreviewAuth describes what happens to the authentication of the schema
+type SchemaPreviewAuth struct {
+	EnabledBefore bool     `json:"enabled_before"`
+	EnabledAfter  bool     `json:"enabled_after"`
+	Effects       []string `json:"effects"`
+}
+
+// SchemaPreviewIndexes lists the managed indexes the update would create and drop
+type SchemaPreviewIndexes struct {
+	Created   []string `json:"created"`
+	Dropped   []string `json:"dropped"`
+	Unchanged []string `json:"unchanged"`
+}
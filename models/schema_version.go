@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SchemaVersionAction string
+
+const (
+	SchemaVersionActionCreate   SchemaVersionAction = "create"
+	SchemaVersionActionUpdate   SchemaVersionAction = "update"
+	SchemaVersionActionRollback SchemaVersionAction = "rollback"
+)
+
+// SchemaDefinition is the part of a schema recorded by schema versions
+type SchemaDefinition struct {
+	CollectionName     string              `json:"collection_name" bson:"collection_name"`
+	Fields             []SchemaField       `json:"fields" bson:"fields"`
+	AuthConfig         *AuthConfig         `json:"auth_config,omitempty" bson:"auth_config,omitempty"`
+	EndpointProtection *EndpointProtection `json:"endpoint_protection,omitempty" bson:"endpoint_protection,omitempty"`
+	Expiry             *ExpiryConfig       `json:"expiry,omitempty" bson:"expiry,omitempty"`
+	Localization       *LocalizationConfig `json:"localization,omitempty" bson:"localization,omitempty"`
+	Publishable        bool                `json:"publishable" bson:"publishable"`
+	History            bool                `json:"history" bson:"history"`
+	BeforeWrite        *BeforeWriteHook    `json:"before_write,omitempty" bson:"before_write,omitempty"`
+	Rules              *AccessRules        `json:"rules,omitempty" bson:"rules,omitempty"`
+	TenantField        string              `json:"tenant_field,omitempty" bson:"tenant_field,omitempty"`
+}
+
+// SchemaVersion is one numbered definition of a schema, stored in schema_versions
+type SchemaVersion struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	SchemaID   primitive.ObjectID  `json:"schema_id" bson:"schema_id"`
+	UserID     primitive.ObjectID  `json:"-" bson:"user_id"`
+	Version    int                 `json:"version" bson:"version"`
+	Action     SchemaVersionAction `json:"action" bson:"action"`
+	Definition SchemaDefinition    `json:"definition" bson:"definition"`
+	// Author is unset for the definition a schema had before versions were recorded
+	Author         *SchemaVersionAuthor `json:"author,omitempty" bson:"author,omitempty"`
+	RolledBackFrom int                  `json:"rolled_back_from,omitempty" bson:"rolled_back_from,omitempty"` // Version restored by a rollback
+	CreatedAt      time.Time            `json:"created_at" bson:"created_at"`
+}
+
+type SchemaVersionAuthor struct {
+	ID    primitive.ObjectID `json:"id" bson:"id"`
+	Email string             `json:"email,omitempty" bson:"email,omitempty"`
+}
+
+// SchemaDiff lists what changed between two schema versions
+type SchemaDiff struct {
+	FromVersion   int               `json:"from_version"`
+	ToVersion     int               `json:"to_version"`
+	AddedFields   []SchemaField     `json:"added_fields"`
+	RemovedFields []SchemaField     `json:"removed_fields"`
+	ChangedFields []SchemaFieldDiff `json:"changed_fields"`
+	Settings      []FieldChange     `json:"settings"` // Changed schema options such as rules or auth_config
+}
+
+// SchemaFieldDiff lists the changed properties of a field present in both versions
+type SchemaFieldDiff struct {
+	Name    string        `json:"name"`
+	Changes []FieldChange `json:"changes"`
+}
@@ -0,0 +1,20 @@
+package models
+
+const (
+	TransactionActionCreate = "create"
+	TransactionActionUpdate = "update"
+	TransactionActionDelete = "delete"
+)
+
+// TransactionOperation is a single write inside a transaction. Create operations may pass an
+// ID so that later operations in the same transaction can reference the new document.
+type TransactionOperation struct {
+	Action     string                 `json:"action" binding:"required"`
+	Collection string                 `json:"collection" binding:"required"`
+	ID         string                 `json:"id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+type TransactionRequest struct {
+	Operations []TransactionOperation `json:"operations" binding:"required,min=1,dive"`
+}
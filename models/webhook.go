@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Events a webhook can subscribe to
+const (
+	WebhookEventCreate = "create"
+	WebhookEventUpdate = "update"
+	WebhookEventDelete = "delete"
+	WebhookEventSignup = "signup"
+)
+
+// Delivery states of the webhook queue
+const (
+	WebhookDeliveryPending    = "pending"
+	WebhookDeliveryInProgress = "in_progress"
+	WebhookDeliverySucceeded  = "succeeded"
+	WebhookDeliveryFailed     = "failed"
+)
+
+// Webhook is an endpoint notified about document lifecycle events of one schema.
+// Endpoints that keep failing are disabled automatically.
+type Webhook struct {
+	ID                  primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID              primitive.ObjectID `json:"user_id" bson:"user_id"`
+	SchemaID            primitive.ObjectID `json:"schema_id" bson:"schema_id"`
+	CollectionName      string             `json:"collection_name" bson:"collection_name"`
+	URL                 string             `json:"url" bson:"url"`
+	Events              []string           `json:"events" bson:"events"`
+	Description         string             `json:"description,omitempty" bson:"description,omitempty"`
+	Secret              string             `json:"-" bson:"secret"`
+	Active              bool               `json:"active" bson:"active"`
+	ConsecutiveFailures int                `json:"consecutive_failures" bson:"consecutive_failures"`
+	DisabledAt          *time.Time         `json:"disabled_at,omitempty" bson:"disabled_at,omitempty"`
+	DisabledReason      string             `json:"disabled_reason,omitempty" bson:"disabled_reason,omitempty"`
+	CreatedAt           time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookPayload is the JSON body posted to webhook endpoints
+type WebhookPayload struct {
+	ID         string                 `json:"id"`
+	Event      string                 `json:"event"`
+	Collection string                 `json:"collection"`
+	DocumentID string                 `json:"document_id"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+}
+
+// WebhookDelivery is one queued or attempted webhook call. The queue lives in MongoDB
+// so pending deliveries survive restarts.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	WebhookID      primitive.ObjectID  `json:"webhook_id" bson:"webhook_id"`
+	UserID         primitive.ObjectID  `json:"-" bson:"user_id"`
+	Event          string              `json:"event" bson:"event"`
+	Payload        string              `json:"payload" bson:"payload"`
+	Status         string              `json:"status" bson:"status"`
+	Attempts       int                 `json:"attempts" bson:"attempts"`
+	NextAttemptAt  time.Time           `json:"next_attempt_at" bson:"next_attempt_at"`
+	LockedUntil    *time.Time          `json:"-" bson:"locked_until,omitempty"`
+	LastAttemptAt  *time.Time          `json:"last_attempt_at,omitempty" bson:"last_attempt_at,omitempty"`
+	ResponseStatus int                 `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ResponseBody   string              `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	Error          string              `json:"error,omitempty" bson:"error,omitempty"`
+	ReplayOf       *primitive.ObjectID `json:"replay_of,omitempty" bson:"replay_of,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	CompletedAt    *time.Time          `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
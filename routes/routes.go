@@ -23,6 +23,9 @@ func SetupRoutes() *gin.Engine {
 	dynamicAuthController := controllers.NewDynamicAuthController()
 	notificationController := controllers.NewNotificationController()
 	activityController := controllers.NewActivityController()
+	webhookController := controllers.NewWebhookController()
+	functionController := controllers.NewFunctionController()
+	batchController := controllers.NewBatchController(r)
 
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -76,7 +79,32 @@ func SetupRoutes() *gin.Engine {
 		protectedGroup.GET("/schemas", schemaController.GetSchemas)
 		protectedGroup.GET("/schemas/:id", schemaController.GetSchemaByID)
 		protectedGroup.PUT("/schemas/:id", schemaController.UpdateSchema)
+		protectedGroup.POST("/schemas/:id/preview", schemaController.PreviewSchemaUpdate)
 		protectedGroup.DELETE("/schemas/:id", schemaController.DeleteSchema)
+		protectedGroup.POST("/schemas/:id/seed", schemaController.SeedSchema)
+		protectedGroup.GET("/schemas/:id/versions", schemaController.GetSchemaVersions)
+		protectedGroup.GET("/schemas/:id/versions/diff", schemaController.DiffSchemaVersions)
+		protectedGroup.GET("/schemas/:id/versions/:version", schemaController.GetSchemaVersion)
+		protectedGroup.POST("/schemas/:id/versions/:version/rollback", schemaController.RollbackSchema)
+		protectedGroup.POST("/schemas/:id/migrations", schemaController.CreateMigration)
+		protectedGroup.GET("/schemas/:id/migrations", schemaController.GetMigrations)
+		protectedGroup.GET("/schemas/:id/migrations/:job_id", schemaController.GetMigration)
+		protectedGroup.POST("/schemas/:id/migrations/:job_id/rollback", schemaController.RollbackMigration)
+		protectedGroup.PUT("/schemas/:id/users/:user_id/roles", dynamicAuthController.SetUserRoles)
+		protectedGroup.PUT("/schemas/:id/users/:user_id/tenant", dynamicAuthController.SetUserTenant)
+
+		protectedGroup.POST("/schemas/:id/webhooks", webhookController.CreateWebhook)
+		protectedGroup.GET("/schemas/:id/webhooks", webhookController.GetWebhooks)
+		protectedGroup.PUT("/webhooks/:id", webhookController.UpdateWebhook)
+		protectedGroup.DELETE("/webhooks/:id", webhookController.DeleteWebhook)
+		protectedGroup.GET("/webhooks/:id/deliveries", webhookController.GetWebhookDeliveries)
+		protectedGroup.POST("/webhooks/:id/deliveries/:delivery_id/replay", webhookController.ReplayWebhookDelivery)
+
+		protectedGroup.POST("/schemas/:id/functions", functionController.CreateFunction)
+		protectedGroup.GET("/schemas/:id/functions", functionController.GetFunctions)
+		protectedGroup.PUT("/functions/:id", functionController.UpdateFunction)
+		protectedGroup.DELETE("/functions/:id", functionController.DeleteFunction)
+		protectedGroup.POST("/functions/:id/test", functionController.TestFunction)
 	}
 
 	adminGroup := r.Group("/admin")
@@ -97,19 +125,40 @@ func SetupRoutes() *gin.Engine {
 	apiGroup := r.Group("/api")
 	apiGroup.Use(middleware.APIKeyMiddleware())
 	{
-		apiGroup.POST("/:collection/auth/signup", dynamicAuthController.Signup)
+		apiGroup.POST("/:collection/auth/signup", middleware.IdempotencyMiddleware(), dynamicAuthController.Signup)
 		apiGroup.POST("/:collection/auth/login", dynamicAuthController.Login)
 		apiGroup.GET("/:collection/auth/validate", dynamicAuthController.ValidateToken)
 		apiGroup.GET("/:collection/auth/users", dynamicAuthController.GetAllUsers)
 
+		apiGroup.POST("/transactions", dynamicAPIController.RunTransaction)
+		// Sub-requests of a batch go through the API key middleware again individually
+		apiGroup.POST("/batch", batchController.RunBatch)
+		apiGroup.GET("/ws", dynamicAPIController.Realtime)
+
 		protectedAPIGroup := apiGroup.Group("")
 		protectedAPIGroup.Use(middleware.DynamicAuthMiddleware())
 		{
-			protectedAPIGroup.POST("/:collection", dynamicAPIController.CreateDocument)
+			protectedAPIGroup.POST("/:collection", middleware.IdempotencyMiddleware(), dynamicAPIController.CreateDocument)
 			protectedAPIGroup.GET("/:collection", dynamicAPIController.GetDocuments)
+			protectedAPIGroup.GET("/:collection/export", dynamicAPIController.ExportDocuments)
+			protectedAPIGroup.GET("/:collection/stream", dynamicAPIController.StreamDocuments)
+			protectedAPIGroup.POST("/:collection/import", dynamicAPIController.ImportDocuments)
+			protectedAPIGroup.GET("/:collection/import/:job_id", dynamicAPIController.GetImportJob)
+			protectedAPIGroup.GET("/:collection/fn/:name", dynamicAPIController.CallFunction)
+			protectedAPIGroup.POST("/:collection/fn/:name", dynamicAPIController.CallFunction)
 			protectedAPIGroup.GET("/:collection/:id", dynamicAPIController.GetDocumentByID)
 			protectedAPIGroup.PUT("/:collection/:id", dynamicAPIController.UpdateDocument)
 			protectedAPIGroup.DELETE("/:collection/:id", dynamicAPIController.DeleteDocument)
+
+			protectedAPIGroup.POST("/:collection/:id/files/:field", dynamicAPIController.UploadFile)
+			protectedAPIGroup.GET("/:collection/:id/files/:field", dynamicAPIController.DownloadFile)
+			protectedAPIGroup.DELETE("/:collection/:id/files/:field", dynamicAPIController.DeleteFile)
+
+			protectedAPIGroup.POST("/:collection/:id/publish", dynamicAPIController.PublishDocument)
+			protectedAPIGroup.POST("/:collection/:id/unpublish", dynamicAPIController.UnpublishDocument)
+
+			protectedAPIGroup.GET("/:collection/:id/history", dynamicAPIController.GetDocumentHistory)
+			protectedAPIGroup.POST("/:collection/:id/history/:version/revert", dynamicAPIController.RevertDocument)
 		}
 	}
 
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// DefaultBeforeWriteTimeout is used when a hook does not set timeout_ms
+	DefaultBeforeWriteTimeout = 3000
+	// MaxBeforeWriteTimeout keeps slow hooks from holding API requests open
+	MaxBeforeWriteTimeout = 10000
+
+	beforeWriteResponseLimit = 1 << 20
+)
+
+// BeforeWriteHookRunsFor reports whether a hook intercepts an operation
+func BeforeWriteHookRunsFor(hook *models.BeforeWriteHook, operation string) bool {
+	if hook == nil || hook.URL == "" {
+		return false
+	}
+	if len(hook.Operations) == 0 {
+		return true
+	}
+	for _, op := range hook.Operations {
+		if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// CallBeforeWriteHook posts a proposed write to a before-write hook and returns its answer.
+// Errors mean the hook could not be reached or answered with an invalid response.
+func CallBeforeWriteHook(ctx context.Context, hook *models.BeforeWriteHook, operation, collectionName string, documentID primitive.ObjectID, data, current map[string]interface{}) (*models.BeforeWriteResponse, error) {
+	timeout := hook.TimeoutMs
+	if timeout <= 0 {
+		timeout = DefaultBeforeWriteTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+	defer cancel()
+
+	requestID := primitive.NewObjectID().Hex()
+	body, err := json.Marshal(models.BeforeWriteRequest{
+		ID:         requestID,
+		Operation:  operation,
+		Collection: collectionName,
+		DocumentID: documentID.Hex(),
+		Data:       data,
+		Current:    current,
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "SchemaCraft-Webhooks/1.0")
+	request.Header.Set("X-SchemaCraft-Event", "before_"+operation)
+	request.Header.Set("X-SchemaCraft-Delivery", requestID)
+	request.Header.Set("X-SchemaCraft-Timestamp", strconv.FormatInt(timestamp, 10))
+	request.Header.Set("X-SchemaCraft-Signature", SignWebhookPayload(hook.Secret, timestamp, body))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("hook did not respond within %dms", timeout)
+		}
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("hook responded with status %d", response.StatusCode)
+	}
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, beforeWriteResponseLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.BeforeWriteResponse
+	if len(bytes.TrimSpace(responseBody)) > 0 {
+		if err := json.Unmarshal(responseBody, &result); err != nil {
+			return nil, errors.New("hook returned an invalid JSON response")
+		}
+	}
+	return &result, nil
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dateLayouts lists the string formats accepted for date values
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseDate converts a stored or client supplied date value into a time.Time
+func ParseDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case primitive.DateTime:
+		return v.Time(), nil
+	case string:
+		trimmed := strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, trimmed); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, errors.New("date must be in RFC 3339 format, e.g. 2024-01-02T15:04:05Z")
+	default:
+		return time.Time{}, errors.New("date must be a string in RFC 3339 format")
+	}
+}
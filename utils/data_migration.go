@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// migrationTypes lists the field types convert_type can produce
+var migrationTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"boolean": true,
+	"date":    true,
+}
+
+// Helper function to check a field name used by a migration, names become keys below data
+func validateMigrationFieldName(name string) error {
+	if name == "" {
+		return errors.New("field name is required")
+	}
+	if strings.Contains(name, ".") || strings.HasPrefix(name, "$") {
+		return errors.New("invalid field name '" + name + "'")
+	}
+	return nil
+}
+
+// ValidateMigrationOperation checks the options of a migration operation
+func ValidateMigrationOperation(op models.MigrationOperation) error {
+	switch op.Type {
+	case models.MigrationOpRenameField:
+		if err := validateMigrationFieldName(op.Field); err != nil {
+			return err
+		}
+		if err := validateMigrationFieldName(op.To); err != nil {
+			return err
+		}
+		if op.Field == op.To {
+			return errors.New("rename_field needs a new name in to")
+		}
+	case models.MigrationOpConvertType:
+		if err := validateMigrationFieldName(op.Field); err != nil {
+			return err
+		}
+		if !migrationTypes[op.FieldType] {
+			return errors.New("convert_type supports field_type string, number, boolean or date")
+		}
+	case models.MigrationOpBackfillDefault:
+		if err := validateMigrationFieldName(op.Field); err != nil {
+			return err
+		}
+		if op.Value == nil {
+			return errors.New("backfill_default needs a value")
+		}
+	case models.MigrationOpDropField:
+		return validateMigrationFieldName(op.Field)
+	case models.MigrationOpSplitField:
+		if err := validateMigrationFieldName(op.Field); err != nil {
+			return err
+		}
+		if len(op.Fields) < 2 {
+			return errors.New("split_field needs at least two target fields")
+		}
+		for _, name := range op.Fields {
+			if err := validateMigrationFieldName(name); err != nil {
+				return err
+			}
+		}
+	case models.MigrationOpMergeFields:
+		if len(op.Fields) < 2 {
+			return errors.New("merge_fields needs at least two source fields")
+		}
+		for _, name := range op.Fields {
+			if err := validateMigrationFieldName(name); err != nil {
+				return err
+			}
+		}
+		if err := validateMigrationFieldName(op.To); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported operation '" + op.Type + "'")
+	}
+	return nil
+}
+
+// Helper function to get the separator of a split or merge
+func migrationSeparator(op models.MigrationOperation) string {
+	if op.Separator == "" {
+		return " "
+	}
+	return op.Separator
+}
+
+// ApplyMigration runs migration operations on a copy of document data and reports whether
+// anything changed. The data is left alone when an operation fails.
+func ApplyMigration(data map[string]interface{}, operations []models.MigrationOperation) (map[string]interface{}, bool, error) {
+	result := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		result[key] = value
+	}
+
+	changed := false
+	for _, op := range operations {
+		switch op.Type {
+		case models.MigrationOpRenameField:
+			value, ok := result[op.Field]
+			if !ok {
+				continue
+			}
+			if existing, taken := result[op.To]; taken && existing != nil {
+				return data, false, errors.New("cannot rename '" + op.Field + "', '" + op.To + "' already has a value")
+			}
+			result[op.To] = value
+			delete(result, op.Field)
+			changed = true
+
+		case models.MigrationOpConvertType:
+			value, ok := result[op.Field]
+			if !ok || value == nil {
+				continue
+			}
+			converted, err := ConvertMigrationValue(value, op.FieldType)
+			if err != nil {
+				return data, false, fmt.Errorf("cannot convert '%s' to %s: %v", op.Field, op.FieldType, err)
+			}
+			original := value
+			if dateTime, ok := value.(primitive.DateTime); ok {
+				original = dateTime.Time()
+			}
+			if !reflect.DeepEqual(converted, original) {
+				result[op.Field] = converted
+				changed = true
+			}
+
+		case models.MigrationOpBackfillDefault:
+			if value, ok := result[op.Field]; ok && value != nil {
+				continue
+			}
+			result[op.Field] = op.Value
+			changed = true
+
+		case models.MigrationOpDropField:
+			if _, ok := result[op.Field]; ok {
+				delete(result, op.Field)
+				changed = true
+			}
+
+		case models.MigrationOpSplitField:
+			value, ok := result[op.Field]
+			if !ok || value == nil {
+				continue
+			}
+			text, isString := value.(string)
+			if !isString {
+				return data, false, errors.New("cannot split '" + op.Field + "', it is not a string")
+			}
+			parts := strings.SplitN(text, migrationSeparator(op), len(op.Fields))
+			for i, part := range parts {
+				result[op.Fields[i]] = strings.TrimSpace(part)
+			}
+			changed = true
+
+		case models.MigrationOpMergeFields:
+			var parts []string
+			for _, name := range op.Fields {
+				value, ok := result[name]
+				if !ok || value == nil {
+					continue
+				}
+				text, err := ConvertMigrationValue(value, "string")
+				if err != nil {
+					return data, false, fmt.Errorf("cannot merge '%s': %v", name, err)
+				}
+				if text != "" {
+					parts = append(parts, text.(string))
+				}
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			result[op.To] = strings.Join(parts, migrationSeparator(op))
+			changed = true
+		}
+	}
+	return result, changed, nil
+}
+
+// ConvertMigrationValue converts a stored value to a string, number, boolean or date
+func ConvertMigrationValue(value interface{}, fieldType string) (interface{}, error) {
+	if dateTime, ok := value.(primitive.DateTime); ok {
+		value = dateTime.Time()
+	}
+
+	switch fieldType {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case time.Time:
+			return v.UTC().Format(time.RFC3339), nil
+		case primitive.ObjectID:
+			return v.Hex(), nil
+		}
+		if number, err := toFloat(value); err == nil {
+			return strconv.FormatFloat(number, 'f', -1, 64), nil
+		}
+		return nil, errors.New("value is not a plain value")
+
+	case "number":
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		case time.Time:
+			return nil, errors.New("dates cannot be converted to numbers")
+		}
+		number, err := toFloat(value)
+		if err != nil {
+			return nil, errors.New("value is not a number")
+		}
+		return number, nil
+
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "1":
+				return true, nil
+			case "false", "no", "0", "":
+				return false, nil
+			}
+			return nil, errors.New("'" + v + "' is not a boolean")
+		}
+		if number, err := toFloat(value); err == nil {
+			return number != 0, nil
+		}
+		return nil, errors.New("value is not a boolean")
+
+	case "date":
+		// Numbers are read as Unix timestamps in milliseconds
+		if _, isString := value.(string); !isString {
+			if number, err := toFloat(value); err == nil {
+				return time.UnixMilli(int64(number)).UTC(), nil
+			}
+		}
+		date, err := ParseDate(value)
+		if err != nil {
+			return nil, err
+		}
+		return date, nil
+	}
+	return nil, errors.New("unsupported field type " + fieldType)
+}
+
+// PlanMigration derives migration operations from a schema diff. A removed field is treated
+// as renamed when it is the only removed and the added field the only added field of its
+// type. Other removed fields are dropped, type changes are converted where possible and
+// fields with a new default are backfilled.
+func PlanMigration(diff models.SchemaDiff) []models.MigrationOperation {
+	removedByType := map[string][]models.SchemaField{}
+	for _, field := range diff.RemovedFields {
+		removedByType[field.Type] = append(removedByType[field.Type], field)
+	}
+	addedByType := map[string][]models.SchemaField{}
+	for _, field := range diff.AddedFields {
+		addedByType[field.Type] = append(addedByType[field.Type], field)
+	}
+
+	operations := []models.MigrationOperation{}
+	renamed := map[string]bool{}
+	for _, field := range diff.RemovedFields {
+		if len(removedByType[field.Type]) == 1 && len(addedByType[field.Type]) == 1 {
+			target := addedByType[field.Type][0]
+			renamed[field.Name] = true
+			renamed[target.Name] = true
+			operations = append(operations, models.MigrationOperation{Type: models.MigrationOpRenameField, Field: field.Name, To: target.Name})
+		}
+	}
+
+	for _, field := range diff.ChangedFields {
+		for _, change := range field.Changes {
+			switch change.Field {
+			case "type":
+				if fieldType, ok := change.NewValue.(string); ok && migrationTypes[fieldType] {
+					operations = append(operations, models.MigrationOperation{Type: models.MigrationOpConvertType, Field: field.Name, FieldType: fieldType})
+				}
+			case "default":
+				if change.NewValue != nil {
+					operations = append(operations, models.MigrationOperation{Type: models.MigrationOpBackfillDefault, Field: field.Name, Value: change.NewValue})
+				}
+			}
+		}
+	}
+
+	for _, field := range diff.AddedFields {
+		if !renamed[field.Name] && field.Default != nil {
+			operations = append(operations, models.MigrationOperation{Type: models.MigrationOpBackfillDefault, Field: field.Name, Value: field.Default})
+		}
+	}
+
+	for _, field := range diff.RemovedFields {
+		if !renamed[field.Name] {
+			operations = append(operations, models.MigrationOperation{Type: models.MigrationOpDropField, Field: field.Name})
+		}
+	}
+	return operations
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+func TestApplyMigrationRunsOperationsInOrder(t *testing.T) {
+	data := map[string]interface{}{"name": "Ada Lovelace", "age": "36"}
+	operations := []models.MigrationOperation{
+		{Type: models.MigrationOpSplitField, Field: "name", Fields: []string{"first", "last"}},
+		{Type: models.MigrationOpDropField, Field: "name"},
+		{Type: models.MigrationOpConvertType, Field: "age", FieldType: "number"},
+		{Type: models.MigrationOpBackfillDefault, Field: "active", Value: true},
+	}
+
+	result, changed, err := ApplyMigration(data, operations)
+	if err != nil || !changed {
+		t.Fatalf("migration failed: changed=%v, err=%v", changed, err)
+	}
+	if result["first"] != "Ada" || result["last"] != "Lovelace" {
+		t.Errorf("split gave %v %v, want Ada Lovelace", result["first"], result["last"])
+	}
+	if _, ok := result["name"]; ok {
+		t.Error("dropped field is still present")
+	}
+	if result["age"] != float64(36) {
+		t.Errorf("age = %#v, want 36", result["age"])
+	}
+	if result["active"] != true {
+		t.Errorf("active = %v, want backfilled true", result["active"])
+	}
+	if data["name"] != "Ada Lovelace" {
+		t.Error("the original data was modified")
+	}
+}
+
+func TestApplyMigrationLeavesDataAloneOnFailure(t *testing.T) {
+	data := map[string]interface{}{"title": "Draft", "headline": "Taken"}
+	operations := []models.MigrationOperation{
+		{Type: models.MigrationOpBackfillDefault, Field: "slug", Value: "draft"},
+		{Type: models.MigrationOpRenameField, Field: "headline", To: "title"},
+	}
+
+	result, changed, err := ApplyMigration(data, operations)
+	if err == nil {
+		t.Fatal("rename onto a field with a value was allowed")
+	}
+	if changed || len(result) != 2 || result["title"] != "Draft" {
+		t.Errorf("failed migration returned %v, changed=%v, want the original data", result, changed)
+	}
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// roleNamePattern limits role names to short identifiers
+var roleNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+// ValidateRoleName checks that a role name is a short identifier
+func ValidateRoleName(role string) error {
+	if !roleNamePattern.MatchString(role) {
+		return errors.New("invalid role '" + role + "': roles must start with a letter and contain only letters, digits, '_' and '-' (max 64 characters)")
+	}
+	return nil
+}
+
+// ValidateAuthRoles checks the roles offered by an auth schema. The default role must be one
+// of them when roles are listed.
+func ValidateAuthRoles(authConfig *models.AuthConfig) error {
+	for _, role := range authConfig.Roles {
+		if err := ValidateRoleName(role); err != nil {
+			return err
+		}
+	}
+	if authConfig.DefaultRole == "" {
+		return nil
+	}
+	if err := ValidateRoleName(authConfig.DefaultRole); err != nil {
+		return err
+	}
+	if len(authConfig.Roles) > 0 && !HasAnyRole([]string{authConfig.DefaultRole}, authConfig.Roles) {
+		return errors.New("default_role '" + authConfig.DefaultRole + "' is not one of the listed roles")
+	}
+	return nil
+}
+
+// DynamicEndpointRequiresAuth reports whether a schema protects the given access operation.
+// Reads are guarded by the GET protection, creates by POST, updates by PUT and deletes by DELETE.
+// Tenant-scoped collections protect every operation, the tenant comes from the token.
+func DynamicEndpointRequiresAuth(schema *models.Schema, operation string) bool {
+	if schema.TenantField != "" {
+		return true
+	}
+	if schema.EndpointProtection == nil {
+		return false
+	}
+
+	if len(DynamicEndpointRoles(schema, operation)) > 0 {
+		return true
+	}
+
+	switch operation {
+	case models.AccessRead:
+		return schema.EndpointProtection.Get
+	case models.AccessCreate:
+		return schema.EndpointProtection.Post
+	case models.AccessUpdate:
+		return schema.EndpointProtection.Put
+	case models.AccessDelete:
+		return schema.EndpointProtection.Delete
+	}
+	return false
+}
+
+// DynamicEndpointRoles returns the roles allowed to use the given access operation, nil when any
+// caller passing authentication may use it. Like protection, roles of PUT also cover publishing,
+// file changes and reverts.
+func DynamicEndpointRoles(schema *models.Schema, operation string) []string {
+	if schema.EndpointProtection == nil {
+		return nil
+	}
+
+	roles := schema.EndpointProtection.Roles
+	switch operation {
+	case models.AccessRead:
+		return roles.Get
+	case models.AccessCreate:
+		return roles.Post
+	case models.AccessUpdate:
+		return roles.Put
+	case models.AccessDelete:
+		return roles.Delete
+	}
+	return nil
+}
+
+// HasAnyRole reports whether the user roles include one of the allowed roles. No allowed
+// roles means the method is not limited by role.
+func HasAnyRole(userRoles, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, role := range userRoles {
+		for _, allowedRole := range allowed {
+			if role == allowedRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DynamicEndpointOwnerOnly reports whether a schema limits an operation (read, update or delete)
+// to the documents the caller created
+func DynamicEndpointOwnerOnly(schema *models.Schema, operation string) bool {
+	if schema.EndpointProtection == nil {
+		return false
+	}
+
+	ownerOnly := schema.EndpointProtection.OwnerOnly
+	switch operation {
+	case models.AccessRead:
+		return ownerOnly.Get
+	case models.AccessUpdate:
+		return ownerOnly.Put
+	case models.AccessDelete:
+		return ownerOnly.Delete
+	}
+	return false
+}
+
+// ValidateDynamicAuthHeader validates a "Bearer <token>" header issued by the user's dynamic
+// auth system for the given schema. On failure it returns the HTTP status to respond with.
+func ValidateDynamicAuthHeader(userID primitive.ObjectID, schema *models.Schema, authHeader string) (*models.DynamicAuthClaims, int, error) {
+	if authHeader == "" {
+		return nil, http.StatusUnauthorized, errors.New("Authorization header required")
+	}
+
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return nil, http.StatusUnauthorized, errors.New("Bearer token required")
+	}
+
+	// Collections without their own auth system use the user's authentication schema
+	authConfig := schema.AuthConfig
+	if authConfig == nil || !authConfig.Enabled {
+		var authSchema models.Schema
+		authFilter := bson.M{
+			"user_id":             userID,
+			"auth_config.enabled": true,
+			"is_active":           true,
+		}
+		err := config.DB.Collection("schemas").FindOne(context.TODO(), authFilter).Decode(&authSchema)
+		if err != nil {
+			return nil, http.StatusUnauthorized, errors.New("Authentication required but no auth system configured")
+		}
+
+		authConfig = authSchema.AuthConfig
+	}
+
+	jwtSecret := authConfig.JWTSecret
+	if jwtSecret == "" {
+		return nil, http.StatusInternalServerError, errors.New("JWT secret not configured")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &models.DynamicAuthClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	})
+	if err != nil {
+		return nil, http.StatusUnauthorized, errors.New("Invalid token")
+	}
+
+	claims, ok := token.Claims.(*models.DynamicAuthClaims)
+	if !ok || !token.Valid {
+		return nil, http.StatusUnauthorized, errors.New("Invalid token claims")
+	}
+
+	if claims.UserID != userID {
+		return nil, http.StatusUnauthorized, errors.New("Token not valid for this user")
+	}
+
+	return claims, http.StatusOK, nil
+}
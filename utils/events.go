@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Document event types published by the dynamic API write paths
+const (
+	DocumentEventInsert = "insert"
+	DocumentEventUpdate = "update"
+	DocumentEventDelete = "delete"
+)
+
+// documentEventBuffer is the number of events a slow subscriber can fall behind before events are dropped
+const documentEventBuffer = 256
+
+// DocumentEvent describes a change made to a document through the dynamic API
+type DocumentEvent struct {
+	UserID     primitive.ObjectID
+	Collection string
+	Type       string
+	DocumentID primitive.ObjectID
+}
+
+// EventHub fans document events out to in-process subscribers. Events only reach subscribers
+// of the same server instance.
+type EventHub struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]*eventSubscriber
+}
+
+type eventSubscriber struct {
+	userID primitive.ObjectID
+	events chan DocumentEvent
+}
+
+// DocumentEvents is the hub used by the dynamic API
+var DocumentEvents = NewEventHub()
+
+// NewEventHub creates an empty event hub
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[int]*eventSubscriber)}
+}
+
+// Subscribe returns a channel receiving the events of one API user and a function that ends the subscription
+func (h *EventHub) Subscribe(userID primitive.ObjectID) (<-chan DocumentEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextID
+	h.nextID++
+	subscriber := &eventSubscriber{userID: userID, events: make(chan DocumentEvent, documentEventBuffer)}
+	h.subscribers[id] = subscriber
+
+	var once sync.Once
+	return subscriber.events, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, id)
+			h.mu.Unlock()
+			close(subscriber.events)
+		})
+	}
+}
+
+// Publish delivers an event to the subscribers of its user without blocking the caller
+func (h *EventHub) Publish(event DocumentEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, subscriber := range h.subscribers {
+		if subscriber.userID != event.UserID {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			// The subscriber is not keeping up, drop the event rather than stall the write path
+		}
+	}
+}
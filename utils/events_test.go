@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEventHubOnlyDeliversEventsOfTheSubscribedUser(t *testing.T) {
+	hub := NewEventHub()
+	userID := primitive.NewObjectID()
+
+	events, unsubscribe := hub.Subscribe(userID)
+	hub.Publish(DocumentEvent{UserID: primitive.NewObjectID(), Collection: "posts", Type: DocumentEventInsert})
+	hub.Publish(DocumentEvent{UserID: userID, Collection: "posts", Type: DocumentEventDelete})
+
+	event := <-events
+	if event.UserID != userID || event.Type != DocumentEventDelete {
+		t.Errorf("received %+v, want the delete of the subscribed user", event)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, open := <-events; open {
+		t.Error("channel is still open after unsubscribing")
+	}
+	hub.Publish(DocumentEvent{UserID: userID, Collection: "posts", Type: DocumentEventUpdate})
+}
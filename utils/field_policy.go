@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+// ValidateFieldPolicy checks a field read or write policy. An empty policy is allowed and
+// means the default.
+func ValidateFieldPolicy(policy string) error {
+	switch policy {
+	case "", models.FieldPolicyPublic, models.FieldPolicyAuthenticated, models.FieldPolicyOwner, models.FieldPolicyServerOnly:
+		return nil
+	}
+	if role, ok := strings.CutPrefix(policy, models.FieldPolicyRolePrefix); ok {
+		return ValidateRoleName(role)
+	}
+	return errors.New("unsupported policy '" + policy + "', use public, authenticated, owner, role:<name> or server-only")
+}
+
+// FieldReadPolicy returns who may read a field. Fields without a read policy follow their
+// visibility, private fields are never returned.
+func FieldReadPolicy(field *models.SchemaField) string {
+	if field.ReadPolicy != "" {
+		return field.ReadPolicy
+	}
+	if field.Visibility == "public" {
+		return models.FieldPolicyPublic
+	}
+	return models.FieldPolicyPrivate
+}
+
+// FieldWritePolicy returns who may set a field, anyone who may write the document by default
+func FieldWritePolicy(field *models.SchemaField) string {
+	if field.WritePolicy != "" {
+		return field.WritePolicy
+	}
+	return models.FieldPolicyPublic
+}
+
+// FieldPolicyAllows reports whether an API caller passes a field policy. Callers are
+// authenticated when they sent a dynamic auth token, owner tells whether they created the
+// document. Server-only and private fields are never available to API callers.
+func FieldPolicyAllows(policy string, authenticated bool, roles []string, owner bool) bool {
+	switch policy {
+	case models.FieldPolicyPublic:
+		return true
+	case models.FieldPolicyAuthenticated:
+		return authenticated
+	case models.FieldPolicyOwner:
+		return authenticated && owner
+	case models.FieldPolicyServerOnly:
+		return false
+	}
+	if role, ok := strings.CutPrefix(policy, models.FieldPolicyRolePrefix); ok {
+		return authenticated && HasAnyRole(roles, []string{role})
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	// DefaultFunctionTimeout is used when a function does not set timeout_ms
+	DefaultFunctionTimeout = 1000
+	// MaxFunctionTimeout is the longest a function may run, including its database calls
+	MaxFunctionTimeout = 5000
+	// MaxFunctionCodeSize limits the size of function source code in bytes
+	MaxFunctionCodeSize = 64 * 1024
+
+	functionMaxCallStack = 256
+	functionMaxLogs      = 100
+	functionMaxLogLength = 1000
+)
+
+var (
+	// ErrFunctionTimeout is returned when a function runs longer than its time limit
+	ErrFunctionTimeout = errors.New("function exceeded its time limit")
+	// ErrFunctionBusy is returned when no execution slot frees up before the time limit
+	ErrFunctionBusy = errors.New("too many functions are running, try again later")
+)
+
+// functionSlots caps the number of functions running at once so they can't take over every CPU
+var functionSlots = make(chan struct{}, runtime.NumCPU())
+
+// FunctionError is an error thrown by function code
+type FunctionError struct {
+	Message string
+}
+
+func (e *FunctionError) Error() string {
+	return e.Message
+}
+
+// Helper function to wrap function code, the code is the body of a function receiving event, db and console
+func wrapFunctionCode(code string) string {
+	return "(function (event, db, console) {\n" + code + "\n})"
+}
+
+// CompileFunction checks that function code is valid JavaScript
+func CompileFunction(name, code string) error {
+	if len(code) > MaxFunctionCodeSize {
+		return fmt.Errorf("function code must be at most %d bytes", MaxFunctionCodeSize)
+	}
+	_, err := goja.Compile(name, wrapFunctionCode(code), true)
+	return err
+}
+
+// RunFunction runs function code in a fresh interpreter. The interpreter has no network, file or
+// timer access; the only way out is the db object, whose methods are plain Go functions that
+// return an error as their last result to throw in JavaScript. The returned value is exported to
+// Go types, undefined becomes nil. Console output is returned as log lines.
+func RunFunction(ctx context.Context, name, code string, timeoutMs int, event interface{}, db map[string]interface{}) (interface{}, []string, error) {
+	if timeoutMs <= 0 {
+		timeoutMs = DefaultFunctionTimeout
+	}
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case functionSlots <- struct{}{}:
+		defer func() { <-functionSlots }()
+	case <-ctx.Done():
+		return nil, nil, ErrFunctionBusy
+	}
+
+	program, err := goja.Compile(name, wrapFunctionCode(code), true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	vm.SetMaxCallStackSize(functionMaxCallStack)
+
+	// Stop the interpreter when the time limit is reached, checked between instructions
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			vm.Interrupt(ErrFunctionTimeout)
+		case <-done:
+		}
+	}()
+
+	var logs []string
+	console := vm.NewObject()
+	logger := func(level string) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			if len(logs) >= functionMaxLogs {
+				return goja.Undefined()
+			}
+			parts := make([]string, len(call.Arguments))
+			for i, argument := range call.Arguments {
+				parts[i] = argument.String()
+			}
+			line := level + ": " + strings.Join(parts, " ")
+			if len(line) > functionMaxLogLength {
+				line = line[:functionMaxLogLength]
+			}
+			logs = append(logs, line)
+			return goja.Undefined()
+		}
+	}
+	console.Set("log", logger("log"))
+	console.Set("info", logger("info"))
+	console.Set("warn", logger("warn"))
+	console.Set("error", logger("error"))
+
+	wrapper, err := vm.RunProgram(program)
+	if err != nil {
+		return nil, logs, functionRunError(err)
+	}
+	function, ok := goja.AssertFunction(wrapper)
+	if !ok {
+		return nil, logs, errors.New("function code did not compile to a function")
+	}
+
+	result, err := function(goja.Undefined(), vm.ToValue(event), vm.ToValue(db), console)
+	if err != nil {
+		return nil, logs, functionRunError(err)
+	}
+	if result == nil || goja.IsUndefined(result) || goja.IsNull(result) {
+		return nil, logs, nil
+	}
+	return result.Export(), logs, nil
+}
+
+// Helper function to turn interpreter errors into timeout errors or the message thrown by the code
+func functionRunError(err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if cause, ok := interrupted.Value().(error); ok {
+			return cause
+		}
+		return ErrFunctionTimeout
+	}
+
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		if object, ok := exception.Value().(*goja.Object); ok {
+			if message := object.Get("message"); message != nil && !goja.IsUndefined(message) {
+				return &FunctionError{Message: message.String()}
+			}
+		}
+		return &FunctionError{Message: exception.Value().String()}
+	}
+
+	var stackOverflow *goja.StackOverflowError
+	if errors.As(err, &stackOverflow) {
+		return &FunctionError{Message: "Maximum call stack size exceeded"}
+	}
+	return err
+}
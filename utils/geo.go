@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EarthRadiusMeters is used to convert distances to radians for $centerSphere queries
+const EarthRadiusMeters = 6378100.0
+
+// ParseGeoPoint converts a client supplied location into a GeoJSON point.
+// Accepted formats are {"lat": 1, "lng": 2}, {"type": "Point", "coordinates": [lng, lat]},
+// [lng, lat] and the string "lat,lng".
+func ParseGeoPoint(value interface{}) (bson.M, error) {
+	var lat, lng float64
+	var err error
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if coordinates, ok := v["coordinates"]; ok {
+			if pointType, ok := v["type"].(string); ok && pointType != "Point" {
+				return nil, errors.New("only GeoJSON Point is supported")
+			}
+			lng, lat, err = parseCoordinatePair(coordinates)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			latValue, latOK := v["lat"]
+			if !latOK {
+				latValue, latOK = v["latitude"]
+			}
+			lngValue, lngOK := v["lng"]
+			if !lngOK {
+				lngValue, lngOK = v["longitude"]
+			}
+			if !latOK || !lngOK {
+				return nil, errors.New("location must contain lat and lng")
+			}
+			if lat, err = toFloat(latValue); err != nil {
+				return nil, errors.New("lat must be a number")
+			}
+			if lng, err = toFloat(lngValue); err != nil {
+				return nil, errors.New("lng must be a number")
+			}
+		}
+	case bson.M:
+		return ParseGeoPoint(map[string]interface{}(v))
+	case []interface{}:
+		lng, lat, err = parseCoordinatePair(v)
+		if err != nil {
+			return nil, err
+		}
+	case string:
+		lat, lng, err = ParseLatLng(v)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported location format")
+	}
+
+	if err := validateLatLng(lat, lng); err != nil {
+		return nil, err
+	}
+
+	return bson.M{
+		"type":        "Point",
+		"coordinates": []float64{lng, lat},
+	}, nil
+}
+
+// ParseLatLng parses a "lat,lng" string as used by the near query parameter
+func ParseLatLng(value string) (float64, float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("expected format lat,lng")
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid latitude")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid longitude")
+	}
+
+	if err := validateLatLng(lat, lng); err != nil {
+		return 0, 0, err
+	}
+
+	return lat, lng, nil
+}
+
+// ParseWithinPolygon parses the within query parameter into a GeoJSON polygon.
+// A bounding box is given as "minLat,minLng,maxLat,maxLng" and a polygon as
+// "lat,lng;lat,lng;lat,lng". Polygons are closed automatically.
+func ParseWithinPolygon(value string) (bson.M, error) {
+	var ring [][]float64
+
+	if !strings.Contains(value, ";") {
+		parts := strings.Split(value, ",")
+		if len(parts) != 4 {
+			return nil, errors.New("bounding box must be minLat,minLng,maxLat,maxLng")
+		}
+
+		bounds := make([]float64, 4)
+		for i, part := range parts {
+			number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bounding box value: %s", part)
+			}
+			bounds[i] = number
+		}
+
+		minLat, minLng, maxLat, maxLng := bounds[0], bounds[1], bounds[2], bounds[3]
+		if err := validateLatLng(minLat, minLng); err != nil {
+			return nil, err
+		}
+		if err := validateLatLng(maxLat, maxLng); err != nil {
+			return nil, err
+		}
+		if minLat >= maxLat || minLng >= maxLng {
+			return nil, errors.New("bounding box minimums must be smaller than maximums")
+		}
+
+		ring = [][]float64{
+			{minLng, minLat},
+			{maxLng, minLat},
+			{maxLng, maxLat},
+			{minLng, maxLat},
+		}
+	} else {
+		for _, point := range strings.Split(value, ";") {
+			if strings.TrimSpace(point) == "" {
+				continue
+			}
+			lat, lng, err := ParseLatLng(point)
+			if err != nil {
+				return nil, fmt.Errorf("invalid polygon point %q: %v", point, err)
+			}
+			ring = append(ring, []float64{lng, lat})
+		}
+		if len(ring) < 3 {
+			return nil, errors.New("polygon requires at least 3 points")
+		}
+	}
+
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, first)
+	}
+
+	return bson.M{
+		"type":        "Polygon",
+		"coordinates": [][][]float64{ring},
+	}, nil
+}
+
+func parseCoordinatePair(value interface{}) (float64, float64, error) {
+	coordinates, ok := value.([]interface{})
+	if !ok {
+		if typed, ok := value.([]float64); ok && len(typed) == 2 {
+			return typed[0], typed[1], nil
+		}
+		return 0, 0, errors.New("coordinates must be [lng, lat]")
+	}
+	if len(coordinates) != 2 {
+		return 0, 0, errors.New("coordinates must be [lng, lat]")
+	}
+
+	lng, err := toFloat(coordinates[0])
+	if err != nil {
+		return 0, 0, errors.New("longitude must be a number")
+	}
+	lat, err := toFloat(coordinates[1])
+	if err != nil {
+		return 0, 0, errors.New("latitude must be a number")
+	}
+
+	return lng, lat, nil
+}
+
+func validateLatLng(lat, lng float64) error {
+	if lat < -90 || lat > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if lng < -180 || lng > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, errors.New("not a number")
+	}
+}
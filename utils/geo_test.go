@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGeoPointAcceptsEveryFormat(t *testing.T) {
+	inputs := []interface{}{
+		map[string]interface{}{"lat": 52.5, "lng": 13.4},
+		map[string]interface{}{"latitude": 52.5, "longitude": "13.4"},
+		map[string]interface{}{"type": "Point", "coordinates": []interface{}{13.4, 52.5}},
+		[]interface{}{13.4, 52.5},
+		"52.5, 13.4",
+	}
+	for _, input := range inputs {
+		point, err := ParseGeoPoint(input)
+		if err != nil {
+			t.Errorf("%v was refused: %v", input, err)
+			continue
+		}
+		if coordinates := point["coordinates"].([]float64); coordinates[0] != 13.4 || coordinates[1] != 52.5 {
+			t.Errorf("%v was stored as %v, want [lng, lat] = [13.4, 52.5]", input, coordinates)
+		}
+	}
+}
+
+func TestParseGeoPointRefusesInvalidLocations(t *testing.T) {
+	inputs := []interface{}{
+		map[string]interface{}{"lat": 91.0, "lng": 0.0},
+		map[string]interface{}{"lat": 0.0},
+		map[string]interface{}{"type": "LineString", "coordinates": []interface{}{0.0, 0.0}},
+		[]interface{}{181.0, 0.0},
+		"north",
+		42,
+	}
+	for _, input := range inputs {
+		if _, err := ParseGeoPoint(input); err == nil {
+			t.Errorf("%v was accepted", input)
+		}
+	}
+}
+
+func TestParseWithinPolygonClosesRings(t *testing.T) {
+	box, err := ParseWithinPolygon("10,20,11,21")
+	if err != nil {
+		t.Fatalf("bounding box was refused: %v", err)
+	}
+	want := [][][]float64{{{20, 10}, {21, 10}, {21, 11}, {20, 11}, {20, 10}}}
+	if !reflect.DeepEqual(box["coordinates"], want) {
+		t.Errorf("bounding box became %v, want %v", box["coordinates"], want)
+	}
+
+	polygon, err := ParseWithinPolygon("0,0;0,1;1,1")
+	if err != nil {
+		t.Fatalf("polygon was refused: %v", err)
+	}
+	ring := polygon["coordinates"].([][][]float64)[0]
+	if len(ring) != 4 || !reflect.DeepEqual(ring[0], ring[3]) {
+		t.Errorf("polygon ring %v was not closed", ring)
+	}
+
+	for _, value := range []string{"11,20,10,21", "0,0;1,1", "1,2,3"} {
+		if _, err := ParseWithinPolygon(value); err == nil {
+			t.Errorf("%s was accepted", value)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NormalizeLocale lower-cases a locale code and uses "-" as separator, e.g. "pt_BR" becomes "pt-br"
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header ordered by preference
+func ParseAcceptLanguage(header string) []string {
+	type weightedLocale struct {
+		locale string
+		weight float64
+		index  int
+	}
+
+	var weighted []weightedLocale
+	for i, part := range strings.Split(header, ",") {
+		pieces := strings.Split(strings.TrimSpace(part), ";")
+		locale := NormalizeLocale(pieces[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range pieces[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					weight = q
+				}
+			}
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		weighted = append(weighted, weightedLocale{locale: locale, weight: weight, index: i})
+	}
+
+	sort.SliceStable(weighted, func(a, b int) bool {
+		return weighted[a].weight > weighted[b].weight
+	})
+
+	locales := make([]string, 0, len(weighted))
+	for _, w := range weighted {
+		locales = append(locales, w.locale)
+	}
+	return locales
+}
+
+// LocaleChain builds the order in which locales are tried: the requested locales (each followed by
+// its base language), then the configured fallback locales and finally the default locale
+func LocaleChain(requested, fallback []string, defaultLocale string) []string {
+	seen := make(map[string]bool)
+	var chain []string
+
+	add := func(locale string) {
+		locale = NormalizeLocale(locale)
+		if locale != "" && !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+
+	for _, locale := range requested {
+		add(locale)
+		if base, _, found := strings.Cut(NormalizeLocale(locale), "-"); found {
+			add(base)
+		}
+	}
+	for _, locale := range fallback {
+		add(locale)
+	}
+	add(defaultLocale)
+
+	return chain
+}
+
+// ResolveLocalizedValue picks the first available translation following the locale chain.
+// It returns the value and the locale it was found in.
+func ResolveLocalizedValue(values map[string]interface{}, chain []string) (interface{}, string, bool) {
+	for _, locale := range chain {
+		if value, ok := values[locale]; ok && value != nil && value != "" {
+			return value, locale, true
+		}
+	}
+	return nil, "", false
+}
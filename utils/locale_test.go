@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguageOrdersByWeight(t *testing.T) {
+	got := ParseAcceptLanguage("fr;q=0.5, pt_BR, en;q=0.8, *;q=0.1, de;q=0")
+	want := []string{"pt-br", "en", "fr"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAcceptLanguage = %v, want %v", got, want)
+	}
+}
+
+func TestLocaleChainFallsBackToBaseLanguage(t *testing.T) {
+	chain := LocaleChain([]string{"pt-BR"}, []string{"es", "pt"}, "en")
+	want := []string{"pt-br", "pt", "es", "en"}
+	if !reflect.DeepEqual(chain, want) {
+		t.Fatalf("LocaleChain = %v, want %v", chain, want)
+	}
+
+	value, locale, ok := ResolveLocalizedValue(map[string]interface{}{"pt-br": "", "es": "Hola", "en": "Hello"}, chain)
+	if !ok || value != "Hola" || locale != "es" {
+		t.Errorf("ResolveLocalizedValue = %v, %s, %v, want Hola from es", value, locale, ok)
+	}
+}
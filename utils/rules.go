@@ -0,0 +1,760 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// MaxRuleLength limits the size of a single access rule
+	MaxRuleLength = 2000
+
+	ruleMaxDepth = 32
+)
+
+// ruleVariables are the names a rule can start a path with
+var ruleVariables = map[string]bool{"auth": true, "doc": true, "data": true}
+
+// ruleAuthClaims are the auth fields read from the token rather than the user's profile
+var ruleAuthClaims = map[string]bool{"id": true, "collection": true, "roles": true, "tenant": true}
+
+// ruleComparisons are the binary operators that compare two values
+var ruleComparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "in": true}
+
+// Rule is a parsed access rule. Rules are boolean expressions over the variables auth, doc and data
+// using ||, &&, !, the comparisons == != < <= > >= and in, literals (strings, numbers, true, false,
+// null and [lists]) and parentheses. Paths into missing values evaluate to null.
+type Rule struct {
+	root ruleNode
+}
+
+type ruleNode interface{}
+
+type ruleLiteral struct {
+	value interface{}
+}
+
+type ruleList struct {
+	items []ruleNode
+}
+
+type rulePath struct {
+	variable string
+	fields   []string
+}
+
+type ruleNot struct {
+	operand ruleNode
+}
+
+type ruleBinary struct {
+	op          string
+	left, right ruleNode
+}
+
+// RuleFieldMapper maps a path below doc to the stored key it is queried on and converts
+// compared values to the stored type. The convert function may be nil.
+type RuleFieldMapper func(fields []string) (key string, convert func(interface{}) interface{})
+
+// AccessRuleFor returns the rule guarding an operation, empty when the operation is open
+func AccessRuleFor(schema *models.Schema, operation string) string {
+	if schema.Rules == nil {
+		return ""
+	}
+	var source string
+	switch operation {
+	case models.AccessRead:
+		source = schema.Rules.Read
+	case models.AccessCreate:
+		source = schema.Rules.Create
+	case models.AccessUpdate:
+		source = schema.Rules.Update
+	case models.AccessDelete:
+		source = schema.Rules.Delete
+	}
+	return strings.TrimSpace(source)
+}
+
+// ValidateAccessRules parses every rule of a schema and checks it only uses the variables
+// available to its operation
+func ValidateAccessRules(rules *models.AccessRules) error {
+	if rules == nil {
+		return nil
+	}
+
+	checks := []struct {
+		operation string
+		source    string
+		variables []string
+	}{
+		{models.AccessRead, rules.Read, []string{"auth", "doc"}},
+		{models.AccessCreate, rules.Create, []string{"auth", "data"}},
+		{models.AccessUpdate, rules.Update, []string{"auth", "doc", "data"}},
+		{models.AccessDelete, rules.Delete, []string{"auth", "doc"}},
+	}
+
+	for _, check := range checks {
+		if strings.TrimSpace(check.source) == "" {
+			continue
+		}
+		rule, err := ParseRule(check.source)
+		if err != nil {
+			return fmt.Errorf("%s rule: %v", check.operation, err)
+		}
+		for _, variable := range []string{"auth", "doc", "data"} {
+			if rule.References(variable) && !containsString(check.variables, variable) {
+				return fmt.Errorf("%s rule cannot use %s", check.operation, variable)
+			}
+		}
+		if check.operation == models.AccessRead {
+			mapper := func(fields []string) (string, func(interface{}) interface{}) {
+				return "data." + strings.Join(fields, "."), nil
+			}
+			if _, err := rule.QueryFilter(map[string]interface{}{"auth": nil}, mapper); err != nil {
+				return fmt.Errorf("read rule: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Helper function to check whether a list contains a string
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleValue converts stored values to the plain JSON types rules work with: IDs become hex
+// strings, dates RFC 3339 strings and all numbers float64
+func RuleValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// ParseRule parses an access rule
+func ParseRule(source string) (*Rule, error) {
+	if len(source) > MaxRuleLength {
+		return nil, fmt.Errorf("rule must be at most %d characters", MaxRuleLength)
+	}
+
+	tokens, err := tokenizeRule(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &ruleParser{tokens: tokens}
+	root, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != ruleTokenEnd {
+		return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.pos)
+	}
+	return &Rule{root: root}, nil
+}
+
+// References reports whether the rule uses a variable
+func (r *Rule) References(variable string) bool {
+	return ruleReferences(r.root, func(path *rulePath) bool { return path.variable == variable })
+}
+
+// ReferencesAuthProfile reports whether the rule reads fields of the signed-in user besides
+// the id, collection, roles and tenant carried by the token
+func (r *Rule) ReferencesAuthProfile() bool {
+	return ruleReferences(r.root, func(path *rulePath) bool {
+		return path.variable == "auth" && len(path.fields) > 0 && !ruleAuthClaims[path.fields[0]]
+	})
+}
+
+// ReferencesAuthField reports whether the rule reads a field of the signed-in user's profile.
+// Fields named like the values carried by the token are read from the token instead.
+func (r *Rule) ReferencesAuthField(field string) bool {
+	if ruleAuthClaims[field] {
+		return false
+	}
+	return ruleReferences(r.root, func(path *rulePath) bool {
+		return path.variable == "auth" && len(path.fields) > 0 && path.fields[0] == field
+	})
+}
+
+// Helper function to look for a path matching a condition
+func ruleReferences(node ruleNode, match func(*rulePath) bool) bool {
+	switch n := node.(type) {
+	case *rulePath:
+		return match(n)
+	case *ruleList:
+		for _, item := range n.items {
+			if ruleReferences(item, match) {
+				return true
+			}
+		}
+	case *ruleNot:
+		return ruleReferences(n.operand, match)
+	case *ruleBinary:
+		return ruleReferences(n.left, match) || ruleReferences(n.right, match)
+	}
+	return false
+}
+
+// Evaluate runs the rule against the variables. Values should be converted with RuleValue.
+// Anything but a true result denies access.
+func (r *Rule) Evaluate(variables map[string]interface{}) bool {
+	return evaluateRuleNode(r.root, variables) == true
+}
+
+// Helper function to evaluate a node to a value
+func evaluateRuleNode(node ruleNode, variables map[string]interface{}) interface{} {
+	switch n := node.(type) {
+	case *ruleLiteral:
+		return n.value
+	case *ruleList:
+		items := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			items[i] = evaluateRuleNode(item, variables)
+		}
+		return items
+	case *rulePath:
+		value := variables[n.variable]
+		for _, field := range n.fields {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = object[field]
+		}
+		return value
+	case *ruleNot:
+		return evaluateRuleNode(n.operand, variables) != true
+	case *ruleBinary:
+		switch n.op {
+		case "&&":
+			return evaluateRuleNode(n.left, variables) == true && evaluateRuleNode(n.right, variables) == true
+		case "||":
+			return evaluateRuleNode(n.left, variables) == true || evaluateRuleNode(n.right, variables) == true
+		}
+		return compareRuleValues(n.op, evaluateRuleNode(n.left, variables), evaluateRuleNode(n.right, variables))
+	}
+	return nil
+}
+
+// Helper function to apply a comparison operator. Ordering only works between two numbers or
+// two strings, in checks list membership.
+func compareRuleValues(op string, left, right interface{}) bool {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	case "in":
+		items, ok := right.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if reflect.DeepEqual(left, item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var order int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case l < r:
+			order = -1
+		case l > r:
+			order = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		order = strings.Compare(l, r)
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+	return false
+}
+
+// QueryFilter turns a read rule into a MongoDB filter. Everything but doc is known up front, so
+// those parts are evaluated and the comparisons against doc fields become query conditions.
+func (r *Rule) QueryFilter(variables map[string]interface{}, mapper RuleFieldMapper) (bson.M, error) {
+	builder := &ruleQueryBuilder{variables: variables, mapper: mapper}
+	return builder.filter(r.root)
+}
+
+type ruleQueryBuilder struct {
+	variables map[string]interface{}
+	mapper    RuleFieldMapper
+}
+
+// Helper function to report whether a node reads the stored document
+func ruleReadsDocument(node ruleNode) bool {
+	return ruleReferences(node, func(path *rulePath) bool { return path.variable == "doc" })
+}
+
+// Helper function to turn a constant result into a filter
+func ruleConstantFilter(result bool) bson.M {
+	if result {
+		return bson.M{}
+	}
+	return bson.M{"_id": bson.M{"$exists": false}}
+}
+
+// Helper function to check whether a filter is the constant match-all or match-nothing filter
+func ruleFilterIs(filter bson.M, result bool) bool {
+	if result {
+		return len(filter) == 0
+	}
+	return reflect.DeepEqual(filter, ruleConstantFilter(false))
+}
+
+func (b *ruleQueryBuilder) filter(node ruleNode) (bson.M, error) {
+	switch n := node.(type) {
+	case *ruleNot:
+		operand, err := b.filter(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case ruleFilterIs(operand, true):
+			return ruleConstantFilter(false), nil
+		case ruleFilterIs(operand, false):
+			return ruleConstantFilter(true), nil
+		}
+		return bson.M{"$nor": bson.A{operand}}, nil
+
+	case *ruleBinary:
+		if n.op == "&&" || n.op == "||" {
+			// Both sides are translated so unsupported expressions always surface
+			left, err := b.filter(n.left)
+			if err != nil {
+				return nil, err
+			}
+			right, err := b.filter(n.right)
+			if err != nil {
+				return nil, err
+			}
+
+			absorbing := n.op == "||"
+			switch {
+			case ruleFilterIs(left, absorbing) || ruleFilterIs(right, absorbing):
+				return ruleConstantFilter(absorbing), nil
+			case ruleFilterIs(left, !absorbing):
+				return right, nil
+			case ruleFilterIs(right, !absorbing):
+				return left, nil
+			}
+			if n.op == "&&" {
+				return bson.M{"$and": bson.A{left, right}}, nil
+			}
+			return bson.M{"$or": bson.A{left, right}}, nil
+		}
+		return b.comparison(n)
+
+	case *rulePath:
+		if n.variable == "doc" {
+			key, _, err := b.field(n)
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{key: true}, nil
+		}
+	}
+
+	if ruleReadsDocument(node) {
+		return nil, errors.New("doc fields can only be used in comparisons in read rules")
+	}
+	return ruleConstantFilter(evaluateRuleNode(node, b.variables) == true), nil
+}
+
+// Helper function to map a doc path to its stored key
+func (b *ruleQueryBuilder) field(path *rulePath) (string, func(interface{}) interface{}, error) {
+	if len(path.fields) == 0 {
+		return "", nil, errors.New("doc must be followed by a field name in read rules")
+	}
+	key, convert := b.mapper(path.fields)
+	if convert == nil {
+		convert = func(value interface{}) interface{} { return value }
+	}
+	return key, convert, nil
+}
+
+// mirroredComparisons swaps the sides of a comparison
+var mirroredComparisons = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+// ruleQueryOperators maps comparisons to MongoDB operators
+var ruleQueryOperators = map[string]string{"==": "$eq", "!=": "$ne", "<": "$lt", "<=": "$lte", ">": "$gt", ">=": "$gte"}
+
+func (b *ruleQueryBuilder) comparison(n *ruleBinary) (bson.M, error) {
+	leftPath, leftIsDoc := n.left.(*rulePath)
+	leftIsDoc = leftIsDoc && leftPath.variable == "doc"
+	rightPath, rightIsDoc := n.right.(*rulePath)
+	rightIsDoc = rightIsDoc && rightPath.variable == "doc"
+
+	switch {
+	case leftIsDoc && rightIsDoc:
+		if n.op == "in" {
+			return nil, errors.New("in cannot compare two doc fields")
+		}
+		leftKey, _, err := b.field(leftPath)
+		if err != nil {
+			return nil, err
+		}
+		rightKey, _, err := b.field(rightPath)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$expr": bson.M{ruleQueryOperators[n.op]: bson.A{"$" + leftKey, "$" + rightKey}}}, nil
+
+	case leftIsDoc && !ruleReadsDocument(n.right):
+		key, convert, err := b.field(leftPath)
+		if err != nil {
+			return nil, err
+		}
+		value := evaluateRuleNode(n.right, b.variables)
+		if n.op == "in" {
+			items, ok := value.([]interface{})
+			if !ok {
+				return ruleConstantFilter(false), nil
+			}
+			converted := make(bson.A, len(items))
+			for i, item := range items {
+				converted[i] = convert(item)
+			}
+			return bson.M{key: bson.M{"$in": converted}}, nil
+		}
+		return ruleFieldCondition(key, n.op, convert(value)), nil
+
+	case rightIsDoc && !ruleReadsDocument(n.left):
+		key, convert, err := b.field(rightPath)
+		if err != nil {
+			return nil, err
+		}
+		value := evaluateRuleNode(n.left, b.variables)
+		if n.op == "in" {
+			// A value in a doc field matches arrays containing it. Null would also match
+			// missing fields, which are not lists.
+			if value == nil {
+				return ruleConstantFilter(false), nil
+			}
+			return bson.M{key: convert(value)}, nil
+		}
+		return ruleFieldCondition(key, mirroredComparisons[n.op], convert(value)), nil
+
+	case !ruleReadsDocument(n):
+		return ruleConstantFilter(evaluateRuleNode(n, b.variables) == true), nil
+	}
+
+	return nil, errors.New("doc fields can only be compared with values in read rules")
+}
+
+// Helper function to build the condition for a doc field compared with a value
+func ruleFieldCondition(key, op string, value interface{}) bson.M {
+	switch op {
+	case "==":
+		return bson.M{key: value}
+	case "!=":
+		return bson.M{key: bson.M{"$ne": value}}
+	}
+	// Nothing is ordered relative to null
+	if value == nil {
+		return ruleConstantFilter(false)
+	}
+	return bson.M{key: bson.M{ruleQueryOperators[op]: value}}
+}
+
+// Rule tokenizer and parser
+
+const (
+	ruleTokenEnd = iota
+	ruleTokenIdent
+	ruleTokenNumber
+	ruleTokenString
+	ruleTokenSymbol
+)
+
+type ruleToken struct {
+	kind  int
+	text  string
+	value interface{}
+	pos   int
+}
+
+// ruleSymbols lists the operators and punctuation, longest first
+var ruleSymbols = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+// Helper function to split a rule into tokens
+func tokenizeRule(source string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenIdent, text: string(runes[start:i]), pos: start})
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			number, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", string(runes[start:i]), start)
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenNumber, text: string(runes[start:i]), value: number, pos: start})
+
+		case r == '"' || r == '\'':
+			start := i
+			var value strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if runes[i] == r {
+					i++
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenString, text: string(runes[start:i]), value: value.String(), pos: start})
+
+		default:
+			matched := false
+			for _, symbol := range ruleSymbols {
+				if strings.HasPrefix(string(runes[i:]), symbol) {
+					tokens = append(tokens, ruleToken{kind: ruleTokenSymbol, text: symbol, pos: i})
+					i += len([]rune(symbol))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", string(r), i)
+			}
+		}
+	}
+
+	return append(tokens, ruleToken{kind: ruleTokenEnd, text: "end of rule", pos: len(runes)}), nil
+}
+
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+}
+
+func (p *ruleParser) peek() ruleToken {
+	return p.tokens[p.pos]
+}
+
+func (p *ruleParser) next() ruleToken {
+	token := p.tokens[p.pos]
+	if token.kind != ruleTokenEnd {
+		p.pos++
+	}
+	return token
+}
+
+// Helper function to consume a symbol when it is next
+func (p *ruleParser) accept(symbol string) bool {
+	token := p.peek()
+	if (token.kind == ruleTokenSymbol || token.kind == ruleTokenIdent) && token.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) expect(symbol string) error {
+	if !p.accept(symbol) {
+		token := p.peek()
+		return fmt.Errorf("expected %q but found %q at position %d", symbol, token.text, token.pos)
+	}
+	return nil
+}
+
+func (p *ruleParser) parseOr(depth int) (ruleNode, error) {
+	if depth > ruleMaxDepth {
+		return nil, errors.New("rule is nested too deeply")
+	}
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &ruleBinary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd(depth int) (ruleNode, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &ruleBinary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseNot(depth int) (ruleNode, error) {
+	if p.accept("!") {
+		if depth > ruleMaxDepth {
+			return nil, errors.New("rule is nested too deeply")
+		}
+		operand, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &ruleNot{operand: operand}, nil
+	}
+	return p.parseComparison(depth)
+}
+
+func (p *ruleParser) parseComparison(depth int) (ruleNode, error) {
+	left, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	token := p.peek()
+	if (token.kind == ruleTokenSymbol || token.kind == ruleTokenIdent) && ruleComparisons[token.text] {
+		p.next()
+		right, err := p.parsePrimary(depth)
+		if err != nil {
+			return nil, err
+		}
+		return &ruleBinary{op: token.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parsePrimary(depth int) (ruleNode, error) {
+	if depth > ruleMaxDepth {
+		return nil, errors.New("rule is nested too deeply")
+	}
+	token := p.next()
+	switch token.kind {
+	case ruleTokenNumber, ruleTokenString:
+		return &ruleLiteral{value: token.value}, nil
+
+	case ruleTokenIdent:
+		switch token.text {
+		case "true":
+			return &ruleLiteral{value: true}, nil
+		case "false":
+			return &ruleLiteral{value: false}, nil
+		case "null":
+			return &ruleLiteral{value: nil}, nil
+		}
+		if !ruleVariables[token.text] {
+			return nil, fmt.Errorf("unknown name %q at position %d, rules can use auth, doc and data", token.text, token.pos)
+		}
+		path := &rulePath{variable: token.text}
+		for p.accept(".") {
+			field := p.next()
+			if field.kind != ruleTokenIdent {
+				return nil, fmt.Errorf("expected a field name but found %q at position %d", field.text, field.pos)
+			}
+			path.fields = append(path.fields, field.text)
+		}
+		return path, nil
+
+	case ruleTokenSymbol:
+		switch token.text {
+		case "(":
+			node, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			list := &ruleList{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parsePrimary(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.pos)
+}
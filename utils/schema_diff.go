@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+// DiffSchemas compares two schema definitions. Fields are matched by name, so a renamed field
+// shows up as removed and added. Values are compared in their JSON form.
+func DiffSchemas(from, to *models.SchemaDefinition) models.SchemaDiff {
+	diff := models.SchemaDiff{
+		AddedFields:   []models.SchemaField{},
+		RemovedFields: []models.SchemaField{},
+		ChangedFields: []models.SchemaFieldDiff{},
+	}
+
+	previous := make(map[string]models.SchemaField, len(from.Fields))
+	for _, field := range from.Fields {
+		previous[field.Name] = field
+	}
+	current := make(map[string]bool, len(to.Fields))
+	for _, field := range to.Fields {
+		current[field.Name] = true
+		old, ok := previous[field.Name]
+		if !ok {
+			diff.AddedFields = append(diff.AddedFields, field)
+			continue
+		}
+		if changes := diffJSONObjects(old, field); len(changes) > 0 {
+			diff.ChangedFields = append(diff.ChangedFields, models.SchemaFieldDiff{Name: field.Name, Changes: changes})
+		}
+	}
+	for _, field := range from.Fields {
+		if !current[field.Name] {
+			diff.RemovedFields = append(diff.RemovedFields, field)
+		}
+	}
+
+	// Everything but the fields is compared as schema options
+	fromOptions, toOptions := *from, *to
+	fromOptions.Fields, toOptions.Fields = nil, nil
+	diff.Settings = diffJSONObjects(fromOptions, toOptions)
+	return diff
+}
+
+// Helper function to list the top level keys that differ between the JSON forms of two values
+func diffJSONObjects(from, to interface{}) []models.FieldChange {
+	fromMap, toMap := jsonObject(from), jsonObject(to)
+
+	keys := make(map[string]bool, len(fromMap)+len(toMap))
+	for key := range fromMap {
+		keys[key] = true
+	}
+	for key := range toMap {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	changes := []models.FieldChange{}
+	for _, key := range sorted {
+		if !reflect.DeepEqual(fromMap[key], toMap[key]) {
+			changes = append(changes, models.FieldChange{Field: key, OldValue: fromMap[key], NewValue: toMap[key]})
+		}
+	}
+	return changes
+}
+
+// Helper function to turn a struct into its JSON object form
+func jsonObject(value interface{}) map[string]interface{} {
+	object := map[string]interface{}{}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return object
+	}
+	json.Unmarshal(data, &object)
+	return object
+}
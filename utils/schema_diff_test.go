@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+func TestDiffSchemasMatchesFieldsByName(t *testing.T) {
+	from := &models.SchemaDefinition{
+		CollectionName: "products",
+		Fields: []models.SchemaField{
+			{Name: "title", Type: "string", Required: true},
+			{Name: "price", Type: "number"},
+		},
+	}
+	to := &models.SchemaDefinition{
+		CollectionName: "products",
+		Fields: []models.SchemaField{
+			{Name: "title", Type: "string"},
+			{Name: "sku", Type: "string"},
+		},
+		History: true,
+	}
+
+	diff := DiffSchemas(from, to)
+	if len(diff.AddedFields) != 1 || diff.AddedFields[0].Name != "sku" {
+		t.Errorf("added fields = %v, want sku", diff.AddedFields)
+	}
+	if len(diff.RemovedFields) != 1 || diff.RemovedFields[0].Name != "price" {
+		t.Errorf("removed fields = %v, want price", diff.RemovedFields)
+	}
+	if len(diff.ChangedFields) != 1 || diff.ChangedFields[0].Name != "title" {
+		t.Fatalf("changed fields = %v, want title", diff.ChangedFields)
+	}
+	if changes := diff.ChangedFields[0].Changes; len(changes) != 1 || changes[0].Field != "required" {
+		t.Errorf("title changes = %v, want required", changes)
+	}
+	if len(diff.Settings) != 1 || diff.Settings[0].Field != "history" {
+		t.Errorf("settings = %v, want history", diff.Settings)
+	}
+}
+
+func TestDiffSchemasOfEqualDefinitionsIsEmpty(t *testing.T) {
+	definition := &models.SchemaDefinition{
+		CollectionName: "products",
+		Fields:         []models.SchemaField{{Name: "title", Type: "string"}},
+	}
+	diff := DiffSchemas(definition, definition)
+	if len(diff.AddedFields)+len(diff.RemovedFields)+len(diff.ChangedFields)+len(diff.Settings) != 0 {
+		t.Errorf("diff of equal definitions = %+v, want no changes", diff)
+	}
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	seedFirstNames = []string{"Olivia", "Liam", "Emma", "Noah", "Ava", "Elijah", "Sophia", "Lucas", "Mia", "Mateo", "Amelia", "Omar", "Aisha", "Hiro", "Yuki", "Ravi", "Priya", "Carlos", "Lucia", "Ivan"}
+	seedLastNames  = []string{"Smith", "Johnson", "Garcia", "Brown", "Khan", "Tanaka", "Rossi", "Müller", "Silva", "Nguyen", "Kowalski", "Dubois", "Patel", "Ali", "Kim", "Lopez", "Wilson", "Ahmed", "Novak", "Costa"}
+	seedCities     = []string{"London", "Lahore", "Berlin", "Tokyo", "Toronto", "Madrid", "Lisbon", "Nairobi", "Sydney", "Austin", "Seoul", "Dubai", "Oslo", "Lima", "Prague"}
+	seedCountries  = []string{"United Kingdom", "Pakistan", "Germany", "Japan", "Canada", "Spain", "Portugal", "Kenya", "Australia", "United States", "South Korea", "Norway", "Peru"}
+	seedStreets    = []string{"Main Street", "Park Avenue", "Oak Lane", "Maple Road", "Station Road", "High Street", "Church Lane", "Mill Road"}
+	seedDomains    = []string{"example.com", "example.org", "mail.test", "demo.dev"}
+	seedCompanies  = []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Stark Industries", "Wayne Enterprises", "Soylent"}
+	seedWords      = []string{"alpha", "bright", "cloud", "delta", "ember", "forest", "granite", "harbor", "island", "jade", "kinetic", "lunar", "meadow", "nova", "orbit", "prism", "quartz", "river", "summit", "timber", "ultra", "vivid", "willow", "zenith"}
+	seedStatuses   = []string{"active", "pending", "archived", "draft"}
+)
+
+// seedDateStart and seedDateRange bound generated dates, fixed so seeded data is reproducible
+var (
+	seedDateStart = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	seedDateRange = int64(6 * 365 * 24 * time.Hour)
+)
+
+// seedNameTokens splits a field name like "firstName" or "zip_code" into lower-case words
+func seedNameTokens(name string) map[string]bool {
+	tokens := make(map[string]bool)
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens[strings.ToLower(string(current))] = true
+			current = nil
+		}
+	}
+	for _, r := range name {
+		switch {
+		case unicode.IsUpper(r) && len(current) > 0 && unicode.IsLower(current[len(current)-1]):
+			flush()
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Helper function to check whether a field name contains any of the given words
+func seedNameHas(tokens map[string]bool, words ...string) bool {
+	for _, word := range words {
+		if tokens[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// Seeder generates fake field values. Seeders created with the same seed produce the same values.
+type Seeder struct {
+	rand *rand.Rand
+}
+
+// NewSeeder creates a seeder using a deterministic random source
+func NewSeeder(seed int64) *Seeder {
+	return &Seeder{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Intn returns a random number in [0, n)
+func (s *Seeder) Intn(n int) int {
+	return s.rand.Intn(n)
+}
+
+// Chance returns true with the given probability
+func (s *Seeder) Chance(probability float64) bool {
+	return s.rand.Float64() < probability
+}
+
+func (s *Seeder) pick(values []string) string {
+	return values[s.rand.Intn(len(values))]
+}
+
+func (s *Seeder) words(count int) string {
+	parts := make([]string, count)
+	for i := range parts {
+		parts[i] = s.pick(seedWords)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Seeder) sentence() string {
+	text := s.words(6 + s.rand.Intn(8))
+	return strings.ToUpper(text[:1]) + text[1:] + "."
+}
+
+// String returns a realistic string for a field, guessed from its name
+func (s *Seeder) String(name string) string {
+	key := seedNameTokens(name)
+	first, last := s.pick(seedFirstNames), s.pick(seedLastNames)
+
+	switch {
+	case seedNameHas(key, "email", "mail"):
+		return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), s.rand.Intn(1000), s.pick(seedDomains))
+	case seedNameHas(key, "username", "handle", "login"):
+		return fmt.Sprintf("%s_%s%d", strings.ToLower(first), s.pick(seedWords), s.rand.Intn(100))
+	case seedNameHas(key, "first", "firstname", "given"):
+		return first
+	case seedNameHas(key, "last", "lastname", "surname", "family"):
+		return last
+	case seedNameHas(key, "company", "organization", "organisation", "employer", "brand"):
+		return s.pick(seedCompanies)
+	case seedNameHas(key, "name", "fullname", "author", "owner", "contact"):
+		return first + " " + last
+	case seedNameHas(key, "phone", "mobile", "tel", "telephone"):
+		return fmt.Sprintf("+1-%03d-%03d-%04d", 200+s.rand.Intn(800), s.rand.Intn(1000), s.rand.Intn(10000))
+	case seedNameHas(key, "city", "town"):
+		return s.pick(seedCities)
+	case seedNameHas(key, "country"):
+		return s.pick(seedCountries)
+	case seedNameHas(key, "address", "street"):
+		return fmt.Sprintf("%d %s, %s", 1+s.rand.Intn(999), s.pick(seedStreets), s.pick(seedCities))
+	case seedNameHas(key, "zip", "postal", "postcode", "zipcode"):
+		return fmt.Sprintf("%05d", s.rand.Intn(100000))
+	case seedNameHas(key, "url", "website", "link", "homepage"):
+		return fmt.Sprintf("https://%s/%s", s.pick(seedDomains), s.pick(seedWords))
+	case seedNameHas(key, "color", "colour"):
+		return fmt.Sprintf("#%06x", s.rand.Intn(0x1000000))
+	case seedNameHas(key, "status", "state"):
+		return s.pick(seedStatuses)
+	case seedNameHas(key, "slug"):
+		return strings.ReplaceAll(s.words(3), " ", "-")
+	case seedNameHas(key, "title", "subject", "label", "headline"):
+		text := s.words(2 + s.rand.Intn(3))
+		return strings.ToUpper(text[:1]) + text[1:]
+	case seedNameHas(key, "description", "bio", "content", "body", "comment", "note", "notes", "summary", "text", "message"):
+		return s.sentence() + " " + s.sentence()
+	}
+
+	return s.words(2)
+}
+
+// Number returns a realistic number for a field, guessed from its name
+func (s *Seeder) Number(name string) float64 {
+	key := seedNameTokens(name)
+
+	switch {
+	case seedNameHas(key, "price", "amount", "cost", "total", "salary", "balance", "fee"):
+		return math.Round((1+s.rand.Float64()*999)*100) / 100
+	case seedNameHas(key, "age"):
+		return float64(18 + s.rand.Intn(62))
+	case seedNameHas(key, "rating", "score", "stars"):
+		return float64(1 + s.rand.Intn(5))
+	case seedNameHas(key, "percent", "percentage"):
+		return float64(s.rand.Intn(101))
+	case seedNameHas(key, "year"):
+		return float64(1990 + s.rand.Intn(36))
+	case seedNameHas(key, "lat", "latitude"):
+		return math.Round((s.rand.Float64()*180-90)*1e6) / 1e6
+	case seedNameHas(key, "lng", "lon", "longitude"):
+		return math.Round((s.rand.Float64()*360-180)*1e6) / 1e6
+	case seedNameHas(key, "quantity", "qty", "count", "stock"):
+		return float64(s.rand.Intn(100))
+	}
+
+	return float64(s.rand.Intn(1000))
+}
+
+// Date returns a date between 2020 and 2025, formatted like client supplied dates
+func (s *Seeder) Date() string {
+	offset := time.Duration(s.rand.Int63n(seedDateRange))
+	return seedDateStart.Add(offset).Truncate(time.Second).Format(time.RFC3339)
+}
+
+// GeoPoint returns a random GeoJSON point
+func (s *Seeder) GeoPoint() bson.M {
+	lat := math.Round((s.rand.Float64()*170-85)*1e6) / 1e6
+	lng := math.Round((s.rand.Float64()*360-180)*1e6) / 1e6
+	return bson.M{"type": "Point", "coordinates": []float64{lng, lat}}
+}
+
+// FieldValue returns a fake value matching the field type. Relation and file fields are not
+// handled here because their values depend on the database.
+func (s *Seeder) FieldValue(field models.SchemaField, localization *models.LocalizationConfig) interface{} {
+	switch field.Type {
+	case "string":
+		if field.Localized && localization != nil {
+			translations := map[string]interface{}{localization.DefaultLocale: s.String(field.Name)}
+			for _, locale := range localization.Locales {
+				translations[locale] = s.String(field.Name)
+			}
+			return translations
+		}
+		return s.String(field.Name)
+	case "number":
+		return s.Number(field.Name)
+	case "boolean":
+		return s.rand.Intn(2) == 1
+	case "date":
+		return s.Date()
+	case "geopoint":
+		return s.GeoPoint()
+	case "object":
+		return map[string]interface{}{
+			"label": s.words(2),
+			"value": s.rand.Intn(100),
+		}
+	case "array":
+		items := make([]interface{}, 1+s.rand.Intn(4))
+		for i := range items {
+			items[i] = s.pick(seedWords)
+		}
+		return items
+	}
+	return nil
+}
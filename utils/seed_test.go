@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+func TestSeederIsReproducible(t *testing.T) {
+	fields := []models.SchemaField{
+		{Name: "email", Type: "string"},
+		{Name: "price", Type: "number"},
+		{Name: "published", Type: "boolean"},
+		{Name: "location", Type: "geopoint"},
+		{Name: "tags", Type: "array"},
+	}
+
+	first, second := NewSeeder(42), NewSeeder(42)
+	for _, field := range fields {
+		a, b := first.FieldValue(field, nil), second.FieldValue(field, nil)
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%s differs for the same seed: %v and %v", field.Name, a, b)
+		}
+	}
+}
+
+func TestSeederGuessesValuesFromFieldNames(t *testing.T) {
+	seeder := NewSeeder(7)
+	if email := seeder.String("contactEmail"); !strings.Contains(email, "@") {
+		t.Errorf("email field got %q", email)
+	}
+	if age := seeder.Number("age"); age < 18 || age >= 80 {
+		t.Errorf("age field got %v", age)
+	}
+
+	localization := &models.LocalizationConfig{DefaultLocale: "en", Locales: []string{"de"}}
+	value := seeder.FieldValue(models.SchemaField{Name: "title", Type: "string", Localized: true}, localization)
+	translations, ok := value.(map[string]interface{})
+	if !ok || translations["en"] == nil || translations["de"] == nil {
+		t.Errorf("localized field got %v", value)
+	}
+}
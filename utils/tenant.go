@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+)
+
+// MaxTenantLength limits the tenant identifiers assigned to auth users
+const MaxTenantLength = 200
+
+// TenantKey returns the document key holding the tenant of a collection: the data field of
+// tenant-scoped collections or the user field of auth collections with tenants. An empty key
+// means the collection is shared by all tenants.
+func TenantKey(schema *models.Schema) string {
+	if schema.AuthConfig != nil && schema.AuthConfig.Enabled {
+		return schema.AuthConfig.TenantField
+	}
+	if schema.TenantField != "" {
+		return "data." + schema.TenantField
+	}
+	return ""
+}
+
+// ValidateTenantField checks the tenant field of a schema. Data collections need a plain string
+// field, auth collections keep the tenant on their users instead.
+func ValidateTenantField(req *models.CreateSchemaRequest) error {
+	if req.TenantField == "" {
+		return nil
+	}
+	if req.AuthConfig != nil && req.AuthConfig.Enabled {
+		return errors.New("authentication schemas keep tenants in auth_config.tenant_field")
+	}
+
+	for _, field := range req.Fields {
+		if field.Name != req.TenantField {
+			continue
+		}
+		if field.Type != "string" || field.Localized {
+			return errors.New("tenant field '" + field.Name + "' must be a string field that is not localized")
+		}
+		if field.Required || field.Default != nil {
+			return errors.New("tenant field '" + field.Name + "' is set from the token, it can't be required or have a default")
+		}
+		return nil
+	}
+	return errors.New("tenant field '" + req.TenantField + "' not found in schema")
+}
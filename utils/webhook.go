@@ -0,0 +1,366 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/M-awais-rasool/SchemaCraft-go/config"
+	"github.com/M-awais-rasool/SchemaCraft-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// WebhookMaxAttempts is the number of delivery attempts before a delivery is marked as failed
+	WebhookMaxAttempts = 8
+	// WebhookDisableThreshold is the number of consecutive failed attempts that disables an endpoint
+	WebhookDisableThreshold = 20
+
+	webhookBaseBackoff    = 30 * time.Second
+	webhookMaxBackoff     = 6 * time.Hour
+	webhookTimeout        = 10 * time.Second
+	webhookLockDuration   = time.Minute
+	webhookPollInterval   = 2 * time.Second
+	webhookWorkers        = 4
+	webhookResponseLimit  = 2048
+	webhookSignatureLabel = "sha256="
+)
+
+// webhookClient connects directly so the dial check sees the real destination, and does not
+// follow redirects, a redirect counts as a failed delivery
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: webhookDialControl}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Helper function to check whether webhooks may call hosts on private networks, e.g. during local development
+func webhookAllowPrivateNetworks() bool {
+	allow, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"))
+	return allow
+}
+
+// Helper function to reject addresses that would let webhooks reach internal services
+func isPrivateWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// webhookDialControl checks the resolved address, so DNS names pointing to private networks are rejected too
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	if webhookAllowPrivateNetworks() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateWebhookIP(ip) {
+		return errors.New("webhook address is on a private network")
+	}
+	return nil
+}
+
+// ValidateWebhookURL checks that a webhook URL is an absolute http(s) URL of a public host
+func ValidateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return errors.New("Webhook URL must be an absolute URL")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return errors.New("Webhook URL must use http or https")
+	}
+	if webhookAllowPrivateNetworks() {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errors.New("Webhook URL must point to a public host")
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateWebhookIP(ip) {
+		return errors.New("Webhook URL must point to a public host")
+	}
+	return nil
+}
+
+// GenerateWebhookSecret creates the secret used to sign deliveries
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// SignWebhookPayload returns the signature sent in the X-SchemaCraft-Signature header.
+// Receivers compute HMAC-SHA256 over "<timestamp>.<body>" with the webhook secret.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignatureLabel + hex.EncodeToString(mac.Sum(nil))
+}
+
+// FindWebhooks returns the active webhooks of a collection subscribed to an event
+func FindWebhooks(userID primitive.ObjectID, collectionName, event string) ([]models.Webhook, error) {
+	filter := bson.M{
+		"user_id":         userID,
+		"collection_name": collectionName,
+		"active":          true,
+		"events":          event,
+	}
+	cursor, err := config.DB.Collection("webhooks").Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var webhooks []models.Webhook
+	if err := cursor.All(context.TODO(), &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// EnqueueWebhookDeliveries stores one pending delivery per webhook in the durable queue
+func EnqueueWebhookDeliveries(webhooks []models.Webhook, event, collectionName string, documentID primitive.ObjectID, data map[string]interface{}) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	deliveries := make([]interface{}, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveryID := primitive.NewObjectID()
+		payload, err := json.Marshal(models.WebhookPayload{
+			ID:         deliveryID.Hex(),
+			Event:      event,
+			Collection: collectionName,
+			DocumentID: documentID.Hex(),
+			Data:       data,
+			Timestamp:  now,
+		})
+		if err != nil {
+			return err
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:            deliveryID,
+			WebhookID:     webhook.ID,
+			UserID:        webhook.UserID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	_, err := config.DB.Collection("webhook_deliveries").InsertMany(context.TODO(), deliveries)
+	return err
+}
+
+// Helper function to compute the wait before the next attempt, doubling with every failure
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
+
+// StartWebhookWorker starts the background workers that deliver queued webhooks.
+// Deliveries are claimed with a lock so several server instances can share the queue.
+func StartWebhookWorker() {
+	deliveries := config.DB.Collection("webhook_deliveries")
+	_, err := deliveries.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to create webhook delivery indexes: %v\n", err)
+	}
+	_, err = config.DB.Collection("webhooks").Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "collection_name", Value: 1}},
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to create webhook indexes: %v\n", err)
+	}
+
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for {
+				delivery, err := claimWebhookDelivery()
+				if err != nil {
+					if err != mongo.ErrNoDocuments {
+						fmt.Printf("Warning: Failed to claim webhook delivery: %v\n", err)
+					}
+					time.Sleep(webhookPollInterval)
+					continue
+				}
+				processWebhookDelivery(delivery)
+			}
+		}()
+	}
+}
+
+// Helper function to lock the next due delivery. Deliveries left in progress by a crashed worker
+// are picked up again once their lock expires.
+func claimWebhookDelivery() (*models.WebhookDelivery, error) {
+	now := time.Now()
+	lockedUntil := now.Add(webhookLockDuration)
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+			{"status": models.WebhookDeliveryInProgress, "locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"status": models.WebhookDeliveryInProgress, "locked_until": lockedUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	if err := config.DB.Collection("webhook_deliveries").FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Helper function to send one delivery and record the outcome
+func processWebhookDelivery(delivery *models.WebhookDelivery) {
+	deliveries := config.DB.Collection("webhook_deliveries")
+
+	var webhook models.Webhook
+	err := config.DB.Collection("webhooks").FindOne(context.TODO(), bson.M{"_id": delivery.WebhookID}).Decode(&webhook)
+	if err != nil || !webhook.Active {
+		reason := "Webhook was deleted"
+		if err == nil {
+			reason = "Webhook is disabled"
+		}
+		now := time.Now()
+		deliveries.UpdateOne(context.TODO(), bson.M{"_id": delivery.ID}, bson.M{
+			"$set":   bson.M{"status": models.WebhookDeliveryFailed, "error": reason, "completed_at": now},
+			"$unset": bson.M{"locked_until": ""},
+		})
+		return
+	}
+
+	status, body, sendErr := sendWebhook(&webhook, delivery)
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+	set := bson.M{
+		"attempts":        attempts,
+		"last_attempt_at": now,
+		"response_status": status,
+		"response_body":   body,
+	}
+
+	if sendErr == nil {
+		set["status"] = models.WebhookDeliverySucceeded
+		set["completed_at"] = now
+		set["error"] = ""
+		deliveries.UpdateOne(context.TODO(), bson.M{"_id": delivery.ID}, bson.M{"$set": set, "$unset": bson.M{"locked_until": ""}})
+		config.DB.Collection("webhooks").UpdateOne(context.TODO(), bson.M{"_id": webhook.ID}, bson.M{"$set": bson.M{"consecutive_failures": 0}})
+		return
+	}
+
+	set["error"] = sendErr.Error()
+	if attempts >= WebhookMaxAttempts {
+		set["status"] = models.WebhookDeliveryFailed
+		set["completed_at"] = now
+	} else {
+		set["status"] = models.WebhookDeliveryPending
+		set["next_attempt_at"] = now.Add(webhookBackoff(attempts))
+	}
+	deliveries.UpdateOne(context.TODO(), bson.M{"_id": delivery.ID}, bson.M{"$set": set, "$unset": bson.M{"locked_until": ""}})
+
+	recordWebhookFailure(&webhook)
+}
+
+// Helper function to count a failed attempt and disable the endpoint once it keeps failing
+func recordWebhookFailure(webhook *models.Webhook) {
+	webhooks := config.DB.Collection("webhooks")
+
+	var updated models.Webhook
+	err := webhooks.FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": webhook.ID},
+		bson.M{"$inc": bson.M{"consecutive_failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil || updated.ConsecutiveFailures < WebhookDisableThreshold {
+		return
+	}
+
+	now := time.Now()
+	reason := fmt.Sprintf("Disabled after %d consecutive failed deliveries", updated.ConsecutiveFailures)
+	result, err := webhooks.UpdateOne(context.TODO(),
+		bson.M{"_id": webhook.ID, "active": true},
+		bson.M{"$set": bson.M{"active": false, "disabled_at": now, "disabled_reason": reason, "updated_at": now}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return
+	}
+
+	notificationService := NewNotificationService()
+	notificationService.CreateNotification(webhook.UserID, "Webhook Disabled",
+		fmt.Sprintf("Your webhook %s for \"%s\" was disabled after %d consecutive failed deliveries. Fix the endpoint and re-enable it to resume deliveries.", webhook.URL, webhook.CollectionName, updated.ConsecutiveFailures),
+		models.NotificationTypeWarning)
+}
+
+// Helper function to post a delivery to its endpoint. Any 2xx response counts as success.
+func sendWebhook(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "SchemaCraft-Webhooks/1.0")
+	request.Header.Set("X-SchemaCraft-Event", delivery.Event)
+	request.Header.Set("X-SchemaCraft-Delivery", delivery.ID.Hex())
+	request.Header.Set("X-SchemaCraft-Timestamp", strconv.FormatInt(timestamp, 10))
+	request.Header.Set("X-SchemaCraft-Signature", SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, webhookResponseLimit))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, string(responseBody), fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+	return response.StatusCode, string(responseBody), nil
+}
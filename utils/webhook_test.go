@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestValidateWebhookURLRefusesPrivateHosts(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "")
+
+	for _, rawURL := range []string{"https://hooks.example.com/schemacraft", "http://93.184.216.34/hook"} {
+		if err := ValidateWebhookURL(rawURL); err != nil {
+			t.Errorf("%s was refused: %v", rawURL, err)
+		}
+	}
+	for _, rawURL := range []string{"ftp://example.com", "/relative", "http://localhost:8080", "http://api.localhost", "http://127.0.0.1", "http://10.0.0.5", "http://[::1]/hook", "http://169.254.169.254/latest"} {
+		if err := ValidateWebhookURL(rawURL); err == nil {
+			t.Errorf("%s was accepted", rawURL)
+		}
+	}
+}
+
+func TestSignWebhookPayloadSignsTimestampAndBody(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000.{\"event\":\"create\"}"))
+	want := webhookSignatureLabel + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhookPayload("whsec_test", 1700000000, []byte(`{"event":"create"}`)); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookBackoffDoublesUpToTheMaximum(t *testing.T) {
+	if webhookBackoff(2) != 2*webhookBaseBackoff {
+		t.Errorf("second attempt waits %v, want %v", webhookBackoff(2), 2*webhookBaseBackoff)
+	}
+	if webhookBackoff(100) != webhookMaxBackoff {
+		t.Errorf("backoff grew to %v, want at most %v", webhookBackoff(100), webhookMaxBackoff)
+	}
+}